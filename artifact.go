@@ -0,0 +1,137 @@
+package bsubio
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Names of the entries written by StreamJobArtifact
+const (
+	ArtifactJobEntry    = "job.json"
+	ArtifactOutputEntry = "output"
+	ArtifactLogsEntry   = "logs.txt"
+)
+
+// StreamJobArtifact writes a tar archive with the job's metadata (job.json),
+// output and logs (logs.txt) to w.
+//
+// Entries are streamed straight from the API when the server reports their size;
+// otherwise they are buffered in memory, since tar headers need the size up front.
+// The output entry is only written for finished jobs and logs are skipped when
+// the server has none.
+func (c *BsubClient) StreamJobArtifact(ctx context.Context, jobID JobId, w io.Writer) error {
+	jobResp, err := c.GetJobWithResponse(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if jobResp.StatusCode() != http.StatusOK {
+		return c.apiError("get job", jobResp.HTTPResponse, jobResp.Body)
+	}
+
+	if jobResp.JSON200 == nil || jobResp.JSON200.Data == nil {
+		return fmt.Errorf("unexpected response format")
+	}
+
+	job := jobResp.JSON200.Data
+
+	modTime := time.Now()
+	if job.UpdatedAt != nil {
+		modTime = *job.UpdatedAt
+	}
+
+	jobJSON, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+
+	if err := c.writeTarEntry(tw, ArtifactJobEntry, int64(len(jobJSON)), modTime, bytes.NewReader(jobJSON)); err != nil {
+		return err
+	}
+
+	if job.Status != nil && *job.Status == JobStatusFinished {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		outputResp, err := c.getJobOutput(ctx, jobID, newCallOptions(nil))
+		if err != nil {
+			return fmt.Errorf("failed to get job output: %w", err)
+		}
+		defer outputResp.Body.Close()
+
+		if outputResp.StatusCode != http.StatusOK {
+			return c.apiError("get job output", outputResp, nil)
+		}
+
+		if err := c.writeTarResponse(tw, ArtifactOutputEntry, modTime, outputResp); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	logsResp, err := c.getJobLogs(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job logs: %w", err)
+	}
+	defer logsResp.Body.Close()
+
+	if logsResp.StatusCode == http.StatusOK {
+		if err := c.writeTarResponse(tw, ArtifactLogsEntry, modTime, logsResp); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return nil
+}
+
+// writeTarResponse adds the body of resp as a tar entry, buffering it only when
+// the server did not send a Content-Length
+func (c *BsubClient) writeTarResponse(tw *tar.Writer, name string, modTime time.Time, resp *http.Response) error {
+	if resp.ContentLength >= 0 {
+		return c.writeTarEntry(tw, name, resp.ContentLength, modTime, resp.Body)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return c.writeTarEntry(tw, name, int64(len(data)), modTime, bytes.NewReader(data))
+}
+
+// writeTarEntry writes a single regular file entry of the given size
+func (c *BsubClient) writeTarEntry(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s header: %w", name, err)
+	}
+
+	if _, err := c.copyData(tw, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
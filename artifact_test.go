@@ -0,0 +1,101 @@
+package bsubio
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamJobArtifact tests archiving a job's metadata, output and logs
+func TestStreamJobArtifact(t *testing.T) {
+	t.Run("finished job contains all entries", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx := context.Background()
+		data := bytes.NewReader([]byte("line1\nline2\nline3"))
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", data)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = client.StreamJobArtifact(ctx, *job.Id, &buf)
+		require.NoError(t, err)
+
+		entries := readTarEntries(t, &buf)
+		require.Contains(t, entries, ArtifactJobEntry)
+		require.Contains(t, entries, ArtifactOutputEntry)
+		require.Contains(t, entries, ArtifactLogsEntry)
+
+		var archived Job
+		require.NoError(t, json.Unmarshal(entries[ArtifactJobEntry], &archived))
+		assert.Equal(t, *job.Id, *archived.Id)
+		assert.Equal(t, JobStatusFinished, *archived.Status)
+
+		result, err := client.GetJobResult(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, result.Output, entries[ArtifactOutputEntry])
+		assert.NotEmpty(t, entries[ArtifactLogsEntry])
+	})
+
+	t.Run("unfinished job has no output entry", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Unfinished job test only supported in mock mode")
+		}
+
+		ctx := context.Background()
+		resp, err := client.CreateJobWithResponse(ctx, CreateJobJSONRequestBody{Type: "test/linecount"})
+		require.NoError(t, err)
+		require.NotNil(t, resp.JSON201)
+
+		var buf bytes.Buffer
+		err = client.StreamJobArtifact(ctx, *resp.JSON201.Data.Id, &buf)
+		require.NoError(t, err)
+
+		entries := readTarEntries(t, &buf)
+		assert.Contains(t, entries, ArtifactJobEntry)
+		assert.NotContains(t, entries, ArtifactOutputEntry)
+		assert.Contains(t, entries, ArtifactLogsEntry)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var buf bytes.Buffer
+		err := client.StreamJobArtifact(ctx, JobId{}, &buf)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// readTarEntries reads every entry of a tar archive into memory
+func readTarEntries(t *testing.T, r io.Reader) map[string][]byte {
+	t.Helper()
+
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries[hdr.Name] = data
+	}
+	return entries
+}
@@ -0,0 +1,89 @@
+package bsubio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Token is a bearer token handed out by a TokenSource
+type Token struct {
+	// AccessToken is sent in the Authorization header as "Bearer <AccessToken>"
+	AccessToken string
+	// Expiry is when the token stops being accepted; the zero time means never
+	Expiry time.Time
+}
+
+// tokenExpiryDelta is how long before its expiry a token is refreshed, so that it does
+// not expire while a request is in flight
+const tokenExpiryDelta = 10 * time.Second
+
+// Valid reports whether the token is set and not about to expire
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+// TokenSource supplies the bearer tokens requests are authenticated with, for deployments
+// that issue short-lived tokens instead of a permanent API key, see Config.TokenSource.
+// It mirrors oauth2.TokenSource, so one can be adapted in a few lines.
+type TokenSource interface {
+	// Token returns a token, fetching a new one if needed. It is only called when the
+	// last token returned is no longer Valid, so it need not cache.
+	Token() (*Token, error)
+}
+
+// staticTokenSource serves an API key as a token that never expires
+type staticTokenSource string
+
+func (s staticTokenSource) Token() (*Token, error) {
+	return &Token{AccessToken: string(s)}, nil
+}
+
+// cachingTokenSource reuses the token of src until it is about to expire. It is safe for
+// concurrent use; concurrent requests that find the token expired share one refresh.
+type cachingTokenSource struct {
+	src TokenSource
+
+	mu    sync.Mutex
+	token *Token
+}
+
+func (s *cachingTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	token, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("token source returned an empty token")
+	}
+
+	s.token = token
+	return token, nil
+}
+
+// bearerAuth returns the request editor that authenticates requests with the tokens of
+// src. It leaves an Authorization header that is already set alone, so custom auth
+// schemes and per-call credentials are never clobbered.
+func bearerAuth(src TokenSource) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		if req.Header.Get("Authorization") != "" {
+			return nil
+		}
+
+		token, err := src.Token()
+		if err != nil {
+			return fmt.Errorf("failed to get auth token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTokenSource hands out numbered tokens that live for ttl
+type countingTokenSource struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	err   error
+	calls int
+}
+
+func (s *countingTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.calls++
+	return &Token{AccessToken: fmt.Sprintf("token-%d", s.calls), Expiry: time.Now().Add(s.ttl)}, nil
+}
+
+// TestTokenSource tests authenticating with refreshed tokens instead of an API key
+func TestTokenSource(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	newClient := func(src TokenSource) *BsubClient {
+		client, err := NewBsubClientWithOptions(WithTokenSource(src), WithAPIBaseURL(mockServer.URL))
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("token reused until it expires", func(t *testing.T) {
+		src := &countingTokenSource{ttl: time.Hour}
+		client := newClient(src)
+
+		for range 3 {
+			require.NoError(t, client.Ping(ctx))
+			assert.Equal(t, "Bearer token-1", mockServer.LastRequest().Header.Get("Authorization"))
+		}
+		assert.Equal(t, 1, src.calls)
+	})
+
+	t.Run("expiring token refreshed", func(t *testing.T) {
+		src := &countingTokenSource{ttl: tokenExpiryDelta / 2}
+		client := newClient(src)
+
+		require.NoError(t, client.Ping(ctx))
+		assert.Equal(t, "Bearer token-1", mockServer.LastRequest().Header.Get("Authorization"))
+		require.NoError(t, client.Ping(ctx))
+		assert.Equal(t, "Bearer token-2", mockServer.LastRequest().Header.Get("Authorization"))
+	})
+
+	t.Run("source failure", func(t *testing.T) {
+		sourceErr := errors.New("SSO unavailable")
+		client := newClient(&countingTokenSource{err: sourceErr})
+
+		_, err := client.ListTypes(ctx)
+		assert.ErrorIs(t, err, sourceErr)
+		assert.Contains(t, err.Error(), "failed to get auth token")
+	})
+
+	t.Run("token source wins over the API key", func(t *testing.T) {
+		client, err := NewBsubClient(Config{
+			APIKey:      "not a valid key",
+			TokenSource: &countingTokenSource{ttl: time.Hour},
+			BaseURL:     mockServer.URL,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, client.Ping(ctx))
+		assert.Equal(t, "Bearer token-1", mockServer.LastRequest().Header.Get("Authorization"))
+	})
+}
@@ -0,0 +1,427 @@
+package bsubio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultBatchConcurrency is how many files ProcessBatch handles at once by default
+const defaultBatchConcurrency = 4
+
+// BatchOptions configures ProcessBatch
+type BatchOptions struct {
+	// Concurrency limits how many files are processed at once (defaults to 4)
+	Concurrency int
+	// Progress, when set, receives a JSON Lines stream with one BatchProgress record
+	// per submission, job status change and completion, as they happen
+	Progress io.Writer
+}
+
+// BatchResult is the outcome of processing one file of a batch
+type BatchResult struct {
+	// FilePath is the input file
+	FilePath string
+	// Result holds the job, output and logs; it may be set even when Err is not nil
+	Result *JobResult
+	// Err is the reason processing the file failed, if it did
+	Err error
+}
+
+// Events reported in BatchProgress records
+const (
+	BatchEventSubmitted = "submitted"
+	BatchEventStatus    = "status"
+	BatchEventCompleted = "completed"
+	BatchEventFailed    = "failed"
+)
+
+// BatchProgress is a single line of the ProcessBatch progress stream
+type BatchProgress struct {
+	Time     time.Time `json:"time"`
+	FilePath string    `json:"file_path"`
+	Event    string    `json:"event"`
+	JobID    string    `json:"job_id,omitempty"`
+	Status   JobStatus `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ProcessBatch processes every file in filePaths as a job of jobType, running up to
+// opts.Concurrency of them at once. The returned results are in the order of filePaths.
+//
+// The progress stream is independent of the results, so callers can watch it live
+// and still inspect the returned slice once the batch is done.
+//
+// callOpts are shared by every file, so options that describe a single job are
+// rejected with ErrUnsupportedOption in each result, see checkBatchOptions.
+func (c *BsubClient) ProcessBatch(ctx context.Context, jobType string, filePaths []string, opts BatchOptions, callOpts ...CallOption) []BatchResult {
+	if err := checkBatchOptions(callOpts); err != nil {
+		results := make([]BatchResult, len(filePaths))
+		for i, filePath := range filePaths {
+			results[i] = BatchResult{FilePath: filePath, Err: err}
+		}
+		return results
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	progress := &progressWriter{w: opts.Progress}
+	results := make([]BatchResult, len(filePaths))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, filePath := range filePaths {
+		wg.Add(1)
+		go func(i int, filePath string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = BatchResult{FilePath: filePath, Err: ctx.Err()}
+				progress.write(BatchProgress{FilePath: filePath, Event: BatchEventFailed, Error: ctx.Err().Error()})
+				return
+			}
+
+			result, err := c.processBatchFile(ctx, jobType, filePath, progress, callOpts)
+			results[i] = BatchResult{FilePath: filePath, Result: result, Err: err}
+		}(i, filePath)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// BatchInput is one input of a BatchProcessor run: a reader, or else a file
+type BatchInput struct {
+	// Name identifies the input in its result (defaults to FilePath)
+	Name string
+	// FilePath is the input file, used when Reader is nil
+	FilePath string
+	// Reader is the input data
+	Reader io.Reader
+}
+
+// FileInputs returns a BatchInput for each of filePaths
+func FileInputs(filePaths ...string) []BatchInput {
+	inputs := make([]BatchInput, len(filePaths))
+	for i, filePath := range filePaths {
+		inputs[i] = BatchInput{FilePath: filePath}
+	}
+	return inputs
+}
+
+// BatchItemResult is the outcome of processing one input of a BatchProcessor run
+type BatchItemResult struct {
+	// Index is the position of the input in the slice passed to Run
+	Index int
+	// Name is the name of the input
+	Name string
+	// Result holds the job, output and logs; it may be set even when Err is not nil
+	Result *JobResult
+	// Err is the reason processing the input failed, if it did
+	Err error
+}
+
+// BatchProcessor processes many inputs as jobs of one type with a pool of workers.
+// Set its fields before calling Run; a BatchProcessor can be run more than once, but
+// not concurrently.
+type BatchProcessor struct {
+	client  *BsubClient
+	jobType string
+
+	// Concurrency limits how many inputs are processed at once (defaults to 4)
+	Concurrency int
+	// DrainTimeout bounds how long jobs that were already started may keep running once
+	// the context of Run is done. Zero lets them run to completion.
+	DrainTimeout time.Duration
+	// OnResult, when set, is called with every result as soon as it is known, in
+	// completion order and one call at a time
+	OnResult func(BatchItemResult)
+	// CallOptions are passed to the Process or ProcessFile call of every input. Options
+	// that describe a single job, such as WithIdempotencyKey, fail every input with
+	// ErrUnsupportedOption.
+	CallOptions []CallOption
+}
+
+// NewBatchProcessor returns a BatchProcessor that runs jobs of jobType, up to
+// concurrency at once
+func (c *BsubClient) NewBatchProcessor(jobType string, concurrency int) *BatchProcessor {
+	return &BatchProcessor{client: c, jobType: jobType, Concurrency: concurrency}
+}
+
+// Run processes inputs and returns their results in the order of inputs.
+//
+// When ctx is done, inputs that have not been started are not processed and get the
+// context error as their result, while jobs in flight are drained: they keep running,
+// for up to DrainTimeout, and report their own outcome.
+func (p *BatchProcessor) Run(ctx context.Context, inputs []BatchInput) []BatchItemResult {
+	if err := checkBatchOptions(p.CallOptions); err != nil {
+		results := make([]BatchItemResult, len(inputs))
+		for i, input := range inputs {
+			results[i] = BatchItemResult{Index: i, Name: inputName(input), Err: err}
+		}
+		return results
+	}
+
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	stopDrain := context.AfterFunc(ctx, func() {
+		if p.DrainTimeout > 0 {
+			time.AfterFunc(p.DrainTimeout, cancelWork)
+		}
+	})
+	defer stopDrain()
+
+	results := make([]BatchItemResult, len(inputs))
+	var mu sync.Mutex
+	report := func(result BatchItemResult) {
+		results[result.Index] = result
+		if p.OnResult != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			p.OnResult(result)
+		}
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(inputs)) {
+		wg.Go(func() {
+			for i := range next {
+				report(p.process(workCtx, i, inputs[i]))
+			}
+		})
+	}
+
+	started := 0
+	for started < len(inputs) && ctx.Err() == nil {
+		select {
+		case next <- started:
+			started++
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	for i := started; i < len(inputs); i++ {
+		report(BatchItemResult{Index: i, Name: inputName(inputs[i]), Err: ctx.Err()})
+	}
+	return results
+}
+
+// process runs one input of a batch through the whole job lifecycle
+func (p *BatchProcessor) process(ctx context.Context, i int, input BatchInput) BatchItemResult {
+	var result *JobResult
+	var err error
+	switch {
+	case input.Reader != nil:
+		result, err = p.client.Process(ctx, p.jobType, input.Reader, p.CallOptions...)
+	case input.FilePath == "":
+		err = ErrNilInput
+	default:
+		result, err = p.client.ProcessFile(ctx, p.jobType, input.FilePath, p.CallOptions...)
+	}
+	return BatchItemResult{Index: i, Name: inputName(input), Result: result, Err: err}
+}
+
+// ProcessMany processes inputs as jobs of jobType, up to concurrency at once, and
+// returns their results and errors in slices aligned with inputs: the outcome of
+// inputs[i] is results[i] and errs[i]. A result may be set even when its error is not
+// nil, e.g. for a failed job. Cancellation and shared options work as in
+// BatchProcessor.Run.
+func (c *BsubClient) ProcessMany(ctx context.Context, jobType string, inputs []io.Reader, concurrency int, opts ...CallOption) (results []*JobResult, errs []error) {
+	batch := make([]BatchInput, len(inputs))
+	for i, input := range inputs {
+		batch[i] = BatchInput{Name: strconv.Itoa(i), Reader: input}
+	}
+
+	processor := c.NewBatchProcessor(jobType, concurrency)
+	processor.CallOptions = opts
+
+	results = make([]*JobResult, len(inputs))
+	errs = make([]error, len(inputs))
+	for _, item := range processor.Run(ctx, batch) {
+		results[item.Index], errs[item.Index] = item.Result, item.Err
+	}
+	return results, errs
+}
+
+// checkBatchOptions rejects the options that describe a single job, which every job of
+// a batch would share: one idempotency key hands each input the job of the first, and
+// the destinations of WithUploadChecksum, WithAssignedPriority and WithAssignedMetadata
+// would be written by all the jobs at once
+func checkBatchOptions(opts []CallOption) error {
+	options := newCallOptions(opts)
+	var option string
+	switch {
+	case options.idempotencyKey != "":
+		option = "WithIdempotencyKey"
+	case options.checksumDst != nil:
+		option = "WithUploadChecksum with a destination"
+	case options.priorityDst != nil:
+		option = "WithAssignedPriority"
+	case options.metadataDst != nil:
+		option = "WithAssignedMetadata"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: %s in batches", ErrUnsupportedOption, option)
+}
+
+// inputName returns the name of a batch input
+func inputName(input BatchInput) string {
+	if input.Name != "" {
+		return input.Name
+	}
+	return input.FilePath
+}
+
+// processBatchFile runs one file of a batch through the whole job lifecycle,
+// reporting each step to progress
+func (c *BsubClient) processBatchFile(ctx context.Context, jobType, filePath string, progress *progressWriter, opts []CallOption) (*JobResult, error) {
+	fail := func(jobID string, result *JobResult, err error) (*JobResult, error) {
+		progress.write(BatchProgress{FilePath: filePath, Event: BatchEventFailed, JobID: jobID, Error: err.Error()})
+		return result, err
+	}
+
+	job, err := c.CreateAndSubmitJobFromFile(ctx, jobType, filePath, opts...)
+	if err != nil {
+		return fail("", nil, err)
+	}
+
+	jobID := job.Id.String()
+	progress.write(BatchProgress{FilePath: filePath, Event: BatchEventSubmitted, JobID: jobID})
+
+	var lastStatus JobStatus
+	finishedJob, err := c.pollJob(ctx, *job.Id, func(job *Job) bool {
+		if job.Status != nil && *job.Status != lastStatus {
+			lastStatus = *job.Status
+			progress.write(BatchProgress{FilePath: filePath, Event: BatchEventStatus, JobID: jobID, Status: lastStatus})
+		}
+		return isTerminal(job.Status)
+	})
+	if err != nil {
+		return fail(jobID, lastPolledResult(finishedJob), fmt.Errorf("failed waiting for job: %w", err))
+	}
+
+	if err := jobEndError(finishedJob); err != nil {
+		result, _ := c.GetJobResult(ctx, *job.Id, opts...)
+		return fail(jobID, result, err)
+	}
+
+	result, err := c.GetJobResult(ctx, *job.Id, opts...)
+	if err != nil {
+		return fail(jobID, result, err)
+	}
+
+	progress.write(BatchProgress{FilePath: filePath, Event: BatchEventCompleted, JobID: jobID, Status: JobStatusFinished})
+	return result, nil
+}
+
+// ProcessFileMulti runs every job type in jobTypes over the same input file, concurrently.
+// The file is opened once and each job reads its own view of it, so it is not re-read
+// per type in user code. The API has no way to share an upload between jobs, so the
+// input is still uploaded once per type.
+//
+// Results are keyed by job type and include the partial results of failed jobs. The
+// error joins the failures of all job types, each prefixed with its type. As in
+// ProcessBatch, opts that describe a single job are rejected with ErrUnsupportedOption.
+func (c *BsubClient) ProcessFileMulti(ctx context.Context, filePath string, jobTypes []string, opts ...CallOption) (map[string]*JobResult, error) {
+	if filePath == "" {
+		return nil, ErrEmptyFilePath
+	}
+	if err := checkBatchOptions(opts); err != nil {
+		return nil, err
+	}
+	for _, jobType := range jobTypes {
+		if err := validateJobType(jobType); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]*JobResult, len(jobTypes))
+		errs    []error
+		started = make(map[string]bool, len(jobTypes))
+	)
+	for _, jobType := range jobTypes {
+		if started[jobType] {
+			continue
+		}
+		started[jobType] = true
+
+		wg.Add(1)
+		go func(jobType string) {
+			defer wg.Done()
+
+			result, err := c.processWithRetry(ctx, ProcessOptions{}, opts, func(ctx context.Context) (*Job, error) {
+				return c.CreateAndSubmitJobFromReaderAt(ctx, jobType, file, info.Size(), opts...)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[jobType] = result
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", jobType, err))
+			}
+		}(jobType)
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// progressWriter serializes BatchProgress records as JSON Lines to an optional writer
+type progressWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// write stamps and writes a record. Write errors are ignored so that a broken
+// progress consumer never fails the batch itself.
+func (p *progressWriter) write(record BatchProgress) {
+	if p.w == nil {
+		return
+	}
+
+	record.Time = time.Now().UTC()
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = p.w.Write(line)
+}
@@ -0,0 +1,297 @@
+package bsubio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProcessBatch tests processing several files and the JSON Lines progress stream
+func TestProcessBatch(t *testing.T) {
+	t.Run("results keep input order", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		dir := t.TempDir()
+		first := filepath.Join(dir, "first.txt")
+		second := filepath.Join(dir, "second.txt")
+		require.NoError(t, os.WriteFile(first, []byte("a\nb"), 0644))
+		require.NoError(t, os.WriteFile(second, []byte("a\nb\nc"), 0644))
+
+		results := client.ProcessBatch(context.Background(), "test/linecount", []string{first, second}, BatchOptions{})
+		require.Len(t, results, 2)
+
+		for i, path := range []string{first, second} {
+			assert.Equal(t, path, results[i].FilePath)
+			require.NoError(t, results[i].Err)
+			require.NotNil(t, results[i].Result)
+			assert.Equal(t, JobStatusFinished, *results[i].Result.Job.Status)
+		}
+	})
+
+	t.Run("progress stream", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusFinished)
+
+		dir := t.TempDir()
+		input := filepath.Join(dir, "input.txt")
+		missing := filepath.Join(dir, "missing.txt")
+		require.NoError(t, os.WriteFile(input, []byte("data"), 0644))
+
+		var progress bytes.Buffer
+		results := client.ProcessBatch(context.Background(), "test/slow", []string{input, missing}, BatchOptions{
+			Concurrency: 1,
+			Progress:    &progress,
+		})
+		require.Len(t, results, 2)
+		require.NoError(t, results[0].Err)
+		require.Error(t, results[1].Err)
+
+		events := make(map[string][]BatchProgress)
+		scanner := bufio.NewScanner(&progress)
+		for scanner.Scan() {
+			var record BatchProgress
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "line: %s", scanner.Text())
+			assert.False(t, record.Time.IsZero())
+			events[record.FilePath] = append(events[record.FilePath], record)
+		}
+		require.NoError(t, scanner.Err())
+
+		var kinds []string
+		var statuses []JobStatus
+		for _, record := range events[input] {
+			kinds = append(kinds, record.Event)
+			if record.Event == BatchEventStatus {
+				statuses = append(statuses, record.Status)
+			}
+			assert.Equal(t, results[0].Result.Job.Id.String(), record.JobID)
+		}
+		assert.Equal(t, BatchEventSubmitted, kinds[0])
+		assert.Equal(t, BatchEventCompleted, kinds[len(kinds)-1])
+		assert.Equal(t, []JobStatus{JobStatusClaimed, JobStatusProcessing, JobStatusFinished}, statuses)
+
+		require.Len(t, events[missing], 1)
+		assert.Equal(t, BatchEventFailed, events[missing][0].Event)
+		assert.NotEmpty(t, events[missing][0].Error)
+	})
+}
+
+// TestProcessFileMulti tests running several job types over one file
+func TestProcessFileMulti(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Custom job types only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/upper", JobStatusFinished)
+	mockServer.SetOutputFunc("test/upper", bytes.ToUpper)
+	mockServer.SetProgression("test/broken", JobStatusFailed)
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb"), 0644))
+
+	results, err := client.ProcessFileMulti(context.Background(), path, []string{"test/linecount", "test/upper", "test/broken", "test/upper"})
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrJobFailed)
+	assert.Contains(t, err.Error(), "test/broken: job failed")
+	assert.NotContains(t, err.Error(), "test/upper")
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "2", string(results["test/linecount"].Output))
+	assert.Equal(t, "A\nB", string(results["test/upper"].Output))
+	require.NotNil(t, results["test/broken"])
+	assert.Equal(t, JobStatusFailed, *results["test/broken"].Job.Status)
+
+	_, err = client.ProcessFileMulti(context.Background(), path, []string{"test/linecount", " "})
+	assert.ErrorIs(t, err, ErrInvalidJobType)
+}
+
+// TestBatchProcessor tests the worker pool, streamed results and draining on cancellation
+func TestBatchProcessor(t *testing.T) {
+	t.Run("files and readers", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		dir := t.TempDir()
+		file := filepath.Join(dir, "input.txt")
+		require.NoError(t, os.WriteFile(file, []byte("a\nb\nc"), 0644))
+
+		inputs := append(FileInputs(file, filepath.Join(dir, "missing.txt")),
+			BatchInput{Name: "inline", Reader: strings.NewReader("a\nb")})
+
+		var streamed []string
+		processor := client.NewBatchProcessor("test/linecount", 2)
+		processor.OnResult = func(result BatchItemResult) {
+			streamed = append(streamed, result.Name)
+		}
+		results := processor.Run(context.Background(), inputs)
+
+		require.Len(t, results, 3)
+		assert.ElementsMatch(t, []string{file, filepath.Join(dir, "missing.txt"), "inline"}, streamed)
+		for i, result := range results {
+			assert.Equal(t, i, result.Index)
+		}
+		require.NoError(t, results[0].Err)
+		assert.Equal(t, "3", strings.TrimSpace(string(results[0].Result.Output)))
+		assert.Error(t, results[1].Err)
+		require.NoError(t, results[2].Err)
+		assert.Equal(t, "inline", results[2].Name)
+		assert.Equal(t, "2", strings.TrimSpace(string(results[2].Result.Output)))
+	})
+
+	t.Run("cancellation drains jobs in flight", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+		mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusFinished)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processor := client.NewBatchProcessor("test/slow", 1)
+		processor.CallOptions = []CallOption{WithPollCallback(func(*Job) { cancel() })}
+		results := processor.Run(ctx, []BatchInput{
+			{Name: "first", Reader: strings.NewReader("a")},
+			{Name: "second", Reader: strings.NewReader("b")},
+			{Name: "third", Reader: strings.NewReader("c")},
+		})
+
+		require.Len(t, results, 3)
+		require.NoError(t, results[0].Err)
+		assert.Equal(t, JobStatusFinished, *results[0].Result.Job.Status)
+		for _, result := range results[1:] {
+			assert.ErrorIs(t, result.Err, context.Canceled, result.Name)
+			assert.Nil(t, result.Result)
+		}
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+		mockServer.SetProgression("test/stuck", JobStatusProcessing)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processor := client.NewBatchProcessor("test/stuck", 1)
+		processor.DrainTimeout = 20 * time.Millisecond
+		processor.CallOptions = []CallOption{WithPollCallback(func(*Job) { cancel() })}
+		results := processor.Run(ctx, []BatchInput{{Name: "stuck", Reader: strings.NewReader("a")}})
+
+		require.Len(t, results, 1)
+		assert.ErrorIs(t, results[0].Err, context.Canceled)
+	})
+}
+
+// TestProcessMany tests that results and errors line up with the inputs
+func TestProcessMany(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted outcomes only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	inputs := []io.Reader{
+		strings.NewReader("a"),
+		strings.NewReader("a\nb\nc"),
+		nil,
+		strings.NewReader("a\nb"),
+	}
+	results, errs := client.ProcessMany(context.Background(), "test/linecount", inputs, 2)
+
+	require.Len(t, results, len(inputs))
+	require.Len(t, errs, len(inputs))
+	for i, want := range map[int]string{0: "1", 1: "3", 3: "2"} {
+		require.NoError(t, errs[i])
+		assert.Equal(t, want, results[i].OutputString())
+	}
+	assert.ErrorIs(t, errs[2], ErrNilInput)
+	assert.Nil(t, results[2])
+
+	mockServer.SetJobOutcome("test/broken", JobStatusFailed, "invalid_input", "bad input")
+	results, errs = client.ProcessMany(context.Background(), "test/broken", []io.Reader{strings.NewReader("a")}, 1)
+	assert.ErrorIs(t, errs[0], ErrJobFailed)
+	require.NotNil(t, results[0])
+	assert.Equal(t, JobStatusFailed, *results[0].Job.Status)
+}
+
+// TestBatchSharedOptions tests that the fan-out helpers reject options that describe a
+// single job before creating any
+func TestBatchSharedOptions(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Request inspection only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb"), 0644))
+
+	var sum string
+	var priority JobPriority
+	var metadata map[string]string
+	for name, opt := range map[string]CallOption{
+		"WithIdempotencyKey":   WithIdempotencyKey("same-for-all"),
+		"WithUploadChecksum":   WithUploadChecksum(&sum),
+		"WithAssignedPriority": WithAssignedPriority(&priority),
+		"WithAssignedMetadata": WithAssignedMetadata(&metadata),
+	} {
+		t.Run(name, func(t *testing.T) {
+			batch := client.ProcessBatch(ctx, "test/linecount", []string{path, path}, BatchOptions{}, opt)
+			require.Len(t, batch, 2)
+			for _, result := range batch {
+				assert.ErrorIs(t, result.Err, ErrUnsupportedOption)
+			}
+
+			_, errs := client.ProcessMany(ctx, "test/linecount", []io.Reader{strings.NewReader("a")}, 1, opt)
+			assert.ErrorIs(t, errs[0], ErrUnsupportedOption)
+
+			_, err := client.ProcessFileMulti(ctx, path, []string{"test/linecount", "test/upper"}, opt)
+			assert.ErrorIs(t, err, ErrUnsupportedOption)
+			assert.Contains(t, err.Error(), name)
+		})
+	}
+	assert.Nil(t, mockServer.LastRequest())
+
+	t.Run("checksum without a destination", func(t *testing.T) {
+		client.pollInterval = testPollInterval
+		_, errs := client.ProcessMany(ctx, "test/linecount", []io.Reader{strings.NewReader("a")}, 1, WithUploadChecksum(nil))
+		assert.NoError(t, errs[0])
+	})
+}
@@ -2,121 +2,823 @@ package bsubio
 
 import (
 	"bytes"
+	"cmp"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // BsubClient wraps the generated API client with helper methods
 type BsubClient struct {
 	*ClientWithResponses
-	apiKey string
+	apiKey     string
+	httpClient *http.Client
+
+	// defaultParams holds the parameters registered with SetDefaultParams, by job type
+	mu            sync.RWMutex
+	defaultParams map[string]map[string]any
+
+	// pollInterval is the delay between job status checks while waiting
+	pollInterval time.Duration
+
+	// useLongPoll and longPollWait configure long-polling status requests; longPollUnsupported
+	// is set once the server has been seen answering them without waiting
+	useLongPoll         bool
+	longPollWait        time.Duration
+	longPollUnsupported atomic.Bool
+
+	// ioBufferSize is the buffer size for copies that stream job data
+	ioBufferSize int
+
+	// uploadChunkSize is how much ResumableUpload sends per request
+	uploadChunkSize int64
+
+	// compressUploads is Config.CompressUploads
+	compressUploads bool
+	// verifyChecksum is Config.VerifyChecksum
+	verifyChecksum bool
+	// inlineDataThreshold is Config.InlineDataThreshold, with the default applied
+	inlineDataThreshold int
+
+	// stats accumulates the counters reported by Stats
+	stats clientStats
+
+	// captureLimit is Config.CaptureBodiesOnError
+	captureLimit int
+
+	// maxRetries, retryBackoff and retryStatusCodes configure retryDoer
+	maxRetries       int
+	retryBackoff     time.Duration
+	retryStatusCodes map[int]bool
+
+	// rateLimitRetries configures rateLimitDoer
+	rateLimitRetries int
+
+	// requestTimeout configures timeoutDoer
+	requestTimeout time.Duration
+
+	// logger receives the client's log events
+	logger Logger
+
+	// requestInterceptors and responseInterceptors configure interceptorDoer
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
+
+	// limiter paces requests, nil unless Config.RequestsPerSecond is set
+	limiter *rate.Limiter
+
+	// tracer records spans, nil unless Config.TracerProvider is set
+	tracer trace.Tracer
+
+	// types caches the job type names for typeCacheTTL
+	types        typeCache
+	typeCacheTTL time.Duration
 }
 
+// defaultPollInterval is how often WaitForJob checks the job status
+const defaultPollInterval = 2 * time.Second
+
+// defaultLongPollWait is how long the server is asked to hold a long-poll status request
+const defaultLongPollWait = 30 * time.Second
+
+// DefaultInlineDataThreshold is the largest input CreateJobWithData sends inline by
+// default, see Config.InlineDataThreshold
+const DefaultInlineDataThreshold = 64 * 1024
+
+// DefaultRequestTimeout is the default Config.DefaultTimeout
+const DefaultRequestTimeout = 30 * time.Second
+
+// DefaultMaxIdleConnsPerHost is the default Config.MaxIdleConnsPerHost. It is above the
+// net/http default of 2, since every request of the client goes to the same host.
+const DefaultMaxIdleConnsPerHost = 16
+
+// Bounds for Config.IOBufferSize
+const (
+	DefaultIOBufferSize = 32 * 1024
+	MinIOBufferSize     = 4 * 1024
+)
+
 // Config holds configuration for the BSUB.IO client
 type Config struct {
 	// APIKey is your BSUB.IO API key
 	APIKey string
-	// BaseURL is the API server URL (defaults to production)
+	// TokenSource, when set, supplies short-lived bearer tokens in place of APIKey, which
+	// is then ignored. Its tokens are cached until shortly before they expire.
+	TokenSource TokenSource
+	// BaseURL is the API server URL. When empty, the BSUBIO_BASE_URL environment variable
+	// is used, and when that is unset too, DefaultBaseURL.
 	BaseURL string
-	// HTTPClient is optional custom HTTP client
+	// BasePath is prepended to the path of every API request, for servers that mount the
+	// API under a prefix, e.g. "/api/bsub" for a gateway serving it at /api/bsub/v1. It is
+	// joined to the path of BaseURL, if any.
+	BasePath string
+	// HTTPClient is optional custom HTTP client. Supplying it, or sharing the default one
+	// with ShareDefaultHTTPClient, overrides the connection pool settings below.
 	HTTPClient *http.Client
+	// MaxIdleConnsPerHost is how many idle connections to the server are kept for reuse
+	// (defaults to DefaultMaxIdleConnsPerHost). Raise it along with MaxConnsPerHost when
+	// many goroutines share the client, so requests do not open fresh connections.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the connections to the server, including those in use;
+	// requests beyond it wait for a free connection. Defaults to 0, no limit.
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept before it is closed
+	// (defaults to 90 seconds)
+	IdleConnTimeout time.Duration
+	// DefaultTimeout bounds each API request, so a hung server cannot block a helper
+	// whose context has no deadline (defaults to DefaultRequestTimeout). Requests that
+	// legitimately take long are exempt and rely on their context: uploads, output and
+	// log downloads, and the long polls of WaitForJob. Set it negative to disable it.
+	// It is not applied when HTTPClient is supplied; set its Timeout instead.
+	DefaultTimeout time.Duration
+	// UseLongPoll makes WaitForJob ask the server to hold each status request open until
+	// the job changes, instead of polling at a fixed interval. Servers that ignore the
+	// request are detected and the client falls back to interval polling.
+	UseLongPoll bool
+	// ShareDefaultHTTPClient makes the client use http.DefaultClient when HTTPClient is nil.
+	// By default a dedicated client with its own transport is created instead, so settings
+	// changed on http.DefaultClient elsewhere in the process do not affect the SDK.
+	ShareDefaultHTTPClient bool
+	// IOBufferSize is the size of the buffer used when streaming job data, such as copying
+	// the input into an upload or job output into an artifact. It defaults to
+	// DefaultIOBufferSize and must be at least MinIOBufferSize. Larger buffers mean fewer
+	// read and write calls, which helps large files on fast links, at the cost of memory
+	// for every copy in flight. Copies between readers and writers that can transfer data
+	// directly, such as files and sockets, do not use the buffer.
+	IOBufferSize int
+	// CaptureBodiesOnError, when positive, makes APIError carry up to this many bytes of
+	// the request and response bodies, plus the request headers with Authorization
+	// redacted. It is off by default, since bodies cost memory and may hold personal data.
+	CaptureBodiesOnError int
+	// MaxRetries is how many times a request that failed with a transient network error,
+	// such as a reset connection, or one of RetryStatusCodes is repeated. Only GET and HEAD
+	// requests and job creation are retried; client errors (4xx) never are. Defaults to 0,
+	// no retries.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for every further one
+	// (defaults to 500ms). Retries stop early rather than sleep past the context deadline.
+	RetryBackoff time.Duration
+	// RetryStatusCodes are the response status codes that are retried
+	// (defaults to DefaultRetryStatusCodes: 502, 503 and 504)
+	RetryStatusCodes []int
+	// MaxRateLimitRetries is how many times a request throttled with 429 Too Many Requests
+	// is repeated, after waiting as long as its Retry-After header asks or RetryBackoff
+	// when it has none. Defaults to 3; set it negative to return 429 responses as errors.
+	MaxRateLimitRetries int
+	// TypeCacheTTL is how long GetTypesCached, ValidateJobType and WithJobTypeValidation
+	// reuse the list of job types fetched from the server (defaults to 5 minutes). Set it
+	// negative to revalidate the list on every call.
+	TypeCacheTTL time.Duration
+	// UserAgent is sent as the User-Agent header of every request (defaults to
+	// DefaultUserAgent)
+	UserAgent string
+	// UserAgentSuffix is appended to UserAgent, separated by a space, to identify the
+	// application using the SDK, e.g. "my-app/1.2"
+	UserAgentSuffix string
+	// TracerProvider, when set, makes the client record OpenTelemetry spans: one per API
+	// request, and one around each Process and ProcessFile call covering the whole job.
+	// Without it no tracing code runs.
+	TracerProvider trace.TracerProvider
+	// Logger receives debug and error events about requests, retries and job status
+	// changes (defaults to NopLogger). Use NewSlogLogger to log with log/slog.
+	Logger Logger
+	// RequestInterceptors run in order on every API request before it is sent, after the
+	// Authorization and User-Agent headers are set, e.g. to add headers. An interceptor
+	// that reads the body must put an unread copy back.
+	RequestInterceptors []RequestInterceptor
+	// ResponseInterceptors run in order on every API response before the helpers see it.
+	// A response retried by the client is only seen once, after the last attempt.
+	ResponseInterceptors []ResponseInterceptor
+	// RequestsPerSecond, when positive, limits the rate of API requests sent by the client,
+	// across all goroutines using it. Every request, including retries, waits for its
+	// turn. Burst is how many requests may be sent at once after a quiet period
+	// (defaults to 1).
+	RequestsPerSecond float64
+	Burst             int
+	// CompressUploads gzips single-request uploads and sends them with Content-Encoding:
+	// gzip, which saves bandwidth on inputs such as large text files. Only enable it for
+	// servers that accept compressed uploads. Uploads that would not get smaller are sent
+	// as is; turn it off per call with WithUploadCompression for inputs that are already
+	// compressed, to save the CPU time.
+	CompressUploads bool
+	// VerifyChecksum computes the SHA-256 of single-request uploads in the same pass that
+	// sends them and passes it in ChecksumHeader, so the server can reject corrupted
+	// input. A rejection, or a checksum in the upload response that differs, is returned
+	// as *ChecksumMismatchError. Override it per call with WithVerifyChecksum.
+	VerifyChecksum bool
+	// InlineDataThreshold is the largest input, in bytes, that CreateJobWithData sends in
+	// the create request instead of uploading it separately (defaults to
+	// DefaultInlineDataThreshold). Set it negative to always upload.
+	InlineDataThreshold int
 }
 
-// configFile represents the structure of ~/.config/bsubio/config.json
+// DefaultBaseURL is the production API server
+const DefaultBaseURL = "https://app.bsub.io"
+
+// configFile represents the structure of ~/.config/bsubio/config.json. The top-level
+// fields are the default profile; Profiles holds named ones, e.g. for staging and
+// production accounts.
 type configFile struct {
-	APIKey  string `json:"api_key"`
-	BaseURL string `json:"base_url"`
+	APIKey   string                `json:"api_key"`
+	BaseURL  string                `json:"base_url"`
+	Profiles map[string]configFile `json:"profiles,omitempty"`
 }
 
 // LoadConfig loads configuration from ~/.config/bsubio/config.json or BSUBIO_API_KEY env var
 // Returns an empty Config{} if neither is found (no error)
+//
+// When the BSUBIO_PROFILE environment variable names a profile, the configuration is
+// read from that profile of the config file instead, and is empty if there is none.
 func LoadConfig() Config {
+	config, _, _ := loadConfig(os.Getenv("BSUBIO_PROFILE"))
+	return config
+}
+
+// NewBsubClientFromConfig creates a client with the API key and base URL found by
+// LoadConfig: the config file written by the bsubio CLI, or else the BSUBIO_API_KEY
+// environment variable. When neither has an API key, the error lists where it looked.
+// A profile selected with BSUBIO_PROFILE must exist, see NewBsubClientWithProfile.
+func NewBsubClientFromConfig() (*BsubClient, error) {
+	return NewBsubClientWithProfile(os.Getenv("BSUBIO_PROFILE"))
+}
+
+// NewBsubClientWithProfile creates a client with the API key and base URL of the named
+// profile in the config file, such as "staging". An empty name selects the default
+// profile, with the BSUBIO_API_KEY fallback of NewBsubClientFromConfig. A missing
+// profile returns an error wrapping ErrProfileNotFound.
+func NewBsubClientWithProfile(name string) (*BsubClient, error) {
+	config, searched, err := loadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("bsub.io API key not found in %s. Run 'bsubio register' or set BSUBIO_API_KEY", strings.Join(searched, " or "))
+	}
+	return NewBsubClient(config)
+}
+
+// loadConfig implements LoadConfig for the named profile, or the default one when
+// profile is empty, and also returns the locations it searched
+func loadConfig(profile string) (Config, []string, error) {
 	config := Config{}
+	var searched []string
 
 	// Try to load from config file first
 	homeDir, err := os.UserHomeDir()
 	if err == nil {
 		configPath := filepath.Join(homeDir, ".config", "bsubio", "config.json")
+		searched = append(searched, configPath)
 		data, err := os.ReadFile(configPath)
 		if err == nil {
 			var cf configFile
 			if err := json.Unmarshal(data, &cf); err == nil {
+				if profile != "" {
+					named, ok := cf.Profiles[profile]
+					if !ok {
+						return config, searched, fmt.Errorf("%w: %q in %s", ErrProfileNotFound, profile, configPath)
+					}
+					cf = named
+				}
 				config.APIKey = cf.APIKey
 				config.BaseURL = cf.BaseURL
-				return config
+				return config, searched, nil
 			}
 		}
 	}
 
+	// Named profiles only exist in the config file
+	if profile != "" {
+		return config, searched, fmt.Errorf("%w: %q, no config file in %s", ErrProfileNotFound, profile, strings.Join(searched, " or "))
+	}
+
 	// Fall back to environment variable
+	searched = append(searched, "$BSUBIO_API_KEY")
 	if apiKey := os.Getenv("BSUBIO_API_KEY"); apiKey != "" {
 		config.APIKey = apiKey
 	}
 
-	return config
+	return config, searched, nil
 }
 
 // NewBsubClient creates a new BSUB.IO API client
 func NewBsubClient(config Config) (*BsubClient, error) {
-	if config.APIKey == "" {
-		return nil, fmt.Errorf("bsub.io API key not found. Run 'bsubio register' or set BSUBIO_API_KEY")
+	var tokens TokenSource
+	if config.TokenSource != nil {
+		tokens = &cachingTokenSource{src: config.TokenSource}
+	} else {
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("bsub.io API key not found. Run 'bsubio register' or set BSUBIO_API_KEY")
+		}
+		if err := ValidateAPIKey(config.APIKey); err != nil {
+			return nil, err
+		}
+		tokens = staticTokenSource(config.APIKey)
 	}
 
-	baseURL := config.BaseURL
-	if baseURL == "" {
-		baseURL = "https://app.bsub.io"
+	baseURL, err := joinBasePath(cmp.Or(config.BaseURL, os.Getenv("BSUBIO_BASE_URL"), DefaultBaseURL), config.BasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	ioBufferSize := config.IOBufferSize
+	if ioBufferSize == 0 {
+		ioBufferSize = DefaultIOBufferSize
+	}
+	if ioBufferSize < MinIOBufferSize {
+		return nil, fmt.Errorf("invalid IO buffer size %d: must be at least %d bytes", config.IOBufferSize, MinIOBufferSize)
 	}
 
+	if config.MaxRetries < 0 {
+		return nil, fmt.Errorf("invalid max retries %d: must not be negative", config.MaxRetries)
+	}
+	retryBackoff := config.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+	rateLimitRetries := config.MaxRateLimitRetries
+	if rateLimitRetries == 0 {
+		rateLimitRetries = defaultRateLimitRetries
+	}
+	typeCacheTTL := config.TypeCacheTTL
+	if typeCacheTTL == 0 {
+		typeCacheTTL = defaultTypeCacheTTL
+	}
+	inlineDataThreshold := config.InlineDataThreshold
+	if inlineDataThreshold == 0 {
+		inlineDataThreshold = DefaultInlineDataThreshold
+	}
+	retryStatusCodes := config.RetryStatusCodes
+	if len(retryStatusCodes) == 0 {
+		retryStatusCodes = DefaultRetryStatusCodes
+	}
+
+	if config.MaxIdleConnsPerHost < 0 || config.MaxConnsPerHost < 0 || config.IdleConnTimeout < 0 {
+		return nil, fmt.Errorf("invalid connection pool settings: must not be negative")
+	}
+	requestTimeout := config.DefaultTimeout
+	if requestTimeout == 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
 	httpClient := config.HTTPClient
+	if httpClient != nil || requestTimeout < 0 {
+		requestTimeout = 0
+	}
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		if config.ShareDefaultHTTPClient {
+			httpClient = http.DefaultClient
+		} else {
+			httpClient = newHTTPClient(config)
+		}
+	}
+
+	c := &BsubClient{
+		apiKey:           config.APIKey,
+		httpClient:       httpClient,
+		pollInterval:     defaultPollInterval,
+		useLongPoll:      config.UseLongPoll,
+		longPollWait:     defaultLongPollWait,
+		ioBufferSize:     ioBufferSize,
+		uploadChunkSize:  defaultUploadChunkSize,
+		compressUploads:  config.CompressUploads,
+		verifyChecksum:   config.VerifyChecksum,
+		captureLimit:     config.CaptureBodiesOnError,
+		maxRetries:       config.MaxRetries,
+		retryBackoff:     retryBackoff,
+		rateLimitRetries: rateLimitRetries,
+		requestTimeout:   requestTimeout,
+		typeCacheTTL:     typeCacheTTL,
+		logger:           cmp.Or[Logger](config.Logger, NopLogger{}),
+		defaultParams:    make(map[string]map[string]any),
+
+		inlineDataThreshold:  inlineDataThreshold,
+		requestInterceptors:  slices.Clone(config.RequestInterceptors),
+		responseInterceptors: slices.Clone(config.ResponseInterceptors),
+	}
+
+	if config.RequestsPerSecond < 0 || config.Burst < 0 {
+		return nil, fmt.Errorf("invalid rate limit %v/s with burst %d: must not be negative", config.RequestsPerSecond, config.Burst)
+	}
+	if config.RequestsPerSecond > 0 {
+		c.limiter = rate.NewLimiter(rate.Limit(config.RequestsPerSecond), max(config.Burst, 1))
 	}
 
-	// Create client with auth interceptor
+	if config.TracerProvider != nil {
+		c.tracer = config.TracerProvider.Tracer(tracerName)
+	}
+
+	c.retryStatusCodes = make(map[int]bool, len(retryStatusCodes))
+	for _, code := range retryStatusCodes {
+		if code < http.StatusInternalServerError {
+			return nil, fmt.Errorf("invalid retry status code %d: only server errors (5xx) can be retried", code)
+		}
+		c.retryStatusCodes[code] = true
+	}
+
+	// Create client with auth interceptor, sending requests through the SDK's
+	// own layers before they reach the HTTP client
 	clientWithResponses, err := NewClientWithResponses(
 		baseURL,
-		WithHTTPClient(httpClient),
-		WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
-			req.Header.Set("Authorization", "Bearer "+config.APIKey)
-			return nil
-		}),
+		WithHTTPClient(c.doer(httpClient)),
+		WithRequestEditorFn(bearerAuth(tokens)),
+		WithRequestEditorFn(userAgent(strings.TrimSpace(cmp.Or(config.UserAgent, DefaultUserAgent)+" "+config.UserAgentSuffix))),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
+	c.ClientWithResponses = clientWithResponses
+
+	return c, nil
+}
+
+// joinBasePath appends basePath to the path of baseURL. The generated client resolves
+// request paths such as /v1/jobs against the result, so they keep the prefix.
+func joinBasePath(baseURL, basePath string) (string, error) {
+	if strings.Trim(basePath, "/") == "" {
+		return baseURL, nil
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	u.Path = path.Join("/", u.Path, basePath) + "/"
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// ValidateAPIKey checks that key could be an API key, so that a mangled key is reported
+// when the client is created instead of as a 401 on the first request. The key format is
+// not assumed; only keys that no server could accept are rejected: empty keys, keys with
+// whitespace or control characters (such as a trailing newline from a file or env var),
+// and keys that still carry the "Bearer " prefix.
+func ValidateAPIKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: key is empty", ErrMalformedAPIKey)
+	}
+	if strings.HasPrefix(strings.ToLower(key), "bearer ") {
+		return fmt.Errorf("%w: remove the \"Bearer \" prefix", ErrMalformedAPIKey)
+	}
+	for _, r := range key {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("%w: key contains whitespace or control characters", ErrMalformedAPIKey)
+		}
+	}
+	return nil
+}
+
+// newHTTPClient creates an HTTP client with its own copy of the default transport,
+// with the connection pool set up as config asks
+func newHTTPClient(config Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cmp.Or(config.MaxIdleConnsPerHost, DefaultMaxIdleConnsPerHost)
+	transport.MaxIdleConns = max(transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	transport.MaxConnsPerHost = config.MaxConnsPerHost
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// DefaultUserAgent is the User-Agent sent when Config.UserAgent is empty
+var DefaultUserAgent = "bsubio-go/" + sdkVersion()
+
+// sdkVersion returns the version of this module the program was built with, or
+// "devel" when it is not known, e.g. in the module's own tests
+func sdkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		if dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
+
+// modulePath is the import path of this module
+const modulePath = "github.com/bsubio/bsubio-go"
+
+// userAgent returns the request editor that sets the User-Agent header, unless it is
+// already set, so a per-call header wins
+func userAgent(value string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		if req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", value)
+		}
+		return nil
+	}
+}
+
+// withHeader returns a request editor that sets a header on a single call
+func withHeader(key, value string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set(key, value)
+		return nil
+	}
+}
+
+// WithQueryParam returns a request editor that adds a query parameter to a single call,
+// e.g. client.GetJobWithResponse(ctx, id, bsubio.WithQueryParam("verbose", "1")).
+//
+// This is an escape hatch for server features the generated client does not know about yet.
+// Parameters that are part of the API should be passed through their typed fields instead.
+func WithQueryParam(key, value string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		query := req.URL.Query()
+		query.Add(key, value)
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+}
+
+// uploadPartHeader is the header of the single file part of an upload.
+// It matches what multipart.Writer.CreateFormFile("file", "upload") produces,
+// but is built once instead of on every upload.
+var uploadPartHeader = textproto.MIMEHeader{
+	"Content-Disposition": {`form-data; name="file"; filename="upload"`},
+	"Content-Type":        {"application/octet-stream"},
+}
+
+// quoteEscaper escapes a file name for the Content-Disposition header of an upload
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
 
-	return &BsubClient{
-		ClientWithResponses: clientWithResponses,
-		apiKey:              config.APIKey,
-	}, nil
+// partHeader returns the header of the upload part, with the file name and media type
+// set by the call options
+func (o *callOptions) partHeader() textproto.MIMEHeader {
+	if o.uploadFilename == "" && o.uploadContentType == "" {
+		return uploadPartHeader
+	}
+
+	filename := cmp.Or(o.uploadFilename, "upload")
+	return textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="file"; filename="` + quoteEscaper.Replace(filename) + `"`},
+		"Content-Type":        {cmp.Or(o.uploadContentType, "application/octet-stream")},
+	}
 }
 
-// JobResult represents the result of a completed job
+// IdempotencyKeyHeader carries the idempotency key of a create job request, see
+// WithIdempotencyKey
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ChecksumHeader carries the hex SHA-256 of the uploaded input, see WithUploadChecksum,
+// and in output responses that of the output, see WithVerifyOutputChecksum
+const ChecksumHeader = "X-Checksum-SHA256"
+
+// multipartOverhead is a generous estimate of the boundary and part headers
+// that wrap an upload in its multipart form
+const multipartOverhead = 512
+
+// JobResult represents the result of a completed job. The helpers also return it, with
+// their error, for jobs that failed or were cancelled.
 type JobResult struct {
-	Job    *Job
+	Job *Job
+	// Output is the job output; for a failed job, any partial output the server kept
 	Output []byte
-	Logs   string
+	// Logs are the job logs, empty when the server has none
+	Logs string
+	// OutputContentType is the media type the server returned the output in
+	OutputContentType string
+	// OutputContentEncoding is the encoding of the output when it was requested with
+	// WithRawOutput and the server compressed it, and empty otherwise
+	OutputContentEncoding string
+	// OutputContentLength is the size of the output announced by the server, or -1 when
+	// it sent none, e.g. because the output was compressed in transit
+	OutputContentLength int64
+}
+
+// OutputString returns the output as a string
+func (r *JobResult) OutputString() string {
+	return string(r.Output)
+}
+
+// OutputReader returns a reader over the output, to pass it on to code that expects one
+func (r *JobResult) OutputReader() io.Reader {
+	return bytes.NewReader(r.Output)
+}
+
+// OutputJSON decodes the output of a job type that emits JSON into v
+func (r *JobResult) OutputJSON(v any) error {
+	if err := json.Unmarshal(r.Output, v); err != nil {
+		return fmt.Errorf("failed to decode output: %w", err)
+	}
+	return nil
+}
+
+// SetDefaultParams registers processor parameters that every job of jobType created
+// through the helpers inherits. Parameters passed with WithParams override them per call.
+// Passing nil removes the defaults for jobType.
+func (c *BsubClient) SetDefaultParams(jobType string, params map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if params == nil {
+		delete(c.defaultParams, jobType)
+		return
+	}
+
+	defaults := make(map[string]any, len(params))
+	for k, v := range params {
+		defaults[k] = v
+	}
+	c.defaultParams[jobType] = defaults
+}
+
+// jobParams merges the per-call params over the defaults registered for jobType
+func (c *BsubClient) jobParams(jobType string, params map[string]any) map[string]any {
+	c.mu.RLock()
+	defaults := c.defaultParams[jobType]
+	c.mu.RUnlock()
+
+	if len(defaults) == 0 {
+		return params
+	}
+
+	merged := make(map[string]any, len(defaults)+len(params))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+	return merged
+}
+
+// createJobRequest is the body sent to create a job. It extends CreateJobJSONRequestBody
+// with fields the generated client does not model yet.
+type createJobRequest struct {
+	Type     string            `json:"type"`
+	Params   map[string]any    `json:"params,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Priority JobPriority       `json:"priority,omitempty"`
+	// CallbackURL is where the server posts the job once it ends, see WithCallbackURL
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Data is the input of a job created with CreateJobWithData, sent base64-encoded
+	Data []byte `json:"data,omitempty"`
 }
 
 // CreateAndSubmitJob is a helper that creates a job, uploads data, and submits it for processing
-func (c *BsubClient) CreateAndSubmitJob(ctx context.Context, jobType string, data io.Reader) (*Job, error) {
-	// Create job
-	createResp, err := c.CreateJobWithResponse(ctx, CreateJobJSONRequestBody{
-		Type: jobType,
+func (c *BsubClient) CreateAndSubmitJob(ctx context.Context, jobType string, data io.Reader, opts ...CallOption) (*Job, error) {
+	jobType = jobTypeFromContext(ctx, jobType)
+	if err := validateJobType(jobType); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrNilInput
+	}
+
+	options := newCallOptions(opts)
+
+	job, err := c.createJob(ctx, jobType, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// A repeated idempotency key returns the job as it is now, which may be submitted
+	if isSubmitted(job) {
+		return job, nil
+	}
+
+	if err := c.uploadAndSubmit(ctx, job, data, options); err != nil {
+		return nil, c.abandonJob(ctx, *job.Id, err, options.cleanupOnError)
+	}
+
+	return job, nil
+}
+
+// CreateAndSubmitJobWithCallback is CreateAndSubmitJob for long jobs that are not worth
+// polling for: the server POSTs the job to callbackURL once it ends, and the receiving
+// handler decodes it with ParseWebhook. It is the same as passing WithCallbackURL.
+func (c *BsubClient) CreateAndSubmitJobWithCallback(ctx context.Context, jobType string, data io.Reader, callbackURL string, opts ...CallOption) (*Job, error) {
+	if err := validateCallbackURL(callbackURL); err != nil {
+		return nil, err
+	}
+	return c.CreateAndSubmitJob(ctx, jobType, data, append(opts, WithCallbackURL(callbackURL))...)
+}
+
+// CreateJobWithData is CreateAndSubmitJob for small inputs held in memory. Inputs of up
+// to Config.InlineDataThreshold bytes are sent in the create request, which saves the
+// upload and submit round trips on servers that accept inline data. Servers that do
+// not get the input uploaded as usual, as do larger inputs and calls that ask for an
+// upload checksum or set the upload file name.
+func (c *BsubClient) CreateJobWithData(ctx context.Context, jobType string, data []byte, opts ...CallOption) (*Job, error) {
+	jobType = jobTypeFromContext(ctx, jobType)
+	if err := validateJobType(jobType); err != nil {
+		return nil, err
+	}
+
+	options := newCallOptions(opts)
+	if !c.canInline(data, options) {
+		return c.CreateAndSubmitJob(ctx, jobType, bytes.NewReader(data), opts...)
+	}
+
+	job, err := c.createJob(ctx, jobType, data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isSubmitted(job) {
+		// The server ignored the inline data
+		if err := c.uploadAndSubmit(ctx, job, bytes.NewReader(data), options); err != nil {
+			return nil, c.abandonJob(ctx, *job.Id, err, options.cleanupOnError)
+		}
+	} else if options.onUploadProgress != nil && len(data) > 0 {
+		options.onUploadProgress(int64(len(data)), int64(len(data)))
+	}
+
+	return job, nil
+}
+
+// canInline reports whether data may be sent in the create request, which is only done
+// for inputs below the threshold whose upload needs nothing the create request lacks
+func (c *BsubClient) canInline(data []byte, options *callOptions) bool {
+	if c.inlineDataThreshold < 0 || len(data) > c.inlineDataThreshold {
+		return false
+	}
+	return !options.checksum && !c.shouldVerifyChecksum(options) &&
+		options.uploadFilename == "" && options.uploadContentType == ""
+}
+
+// isSubmitted reports whether a job was already submitted, e.g. when it was created with
+// inline data or returned for a repeated idempotency key
+func isSubmitted(job *Job) bool {
+	return isTerminal(job.Status) || job.CurrentStatus().AtLeast(JobStatusPending)
+}
+
+// createJob creates a job of jobType with the parameters from options merged over the
+// registered defaults, and checks that the server returned its ID and, unless it was
+// submitted with the inline data, its upload token
+func (c *BsubClient) createJob(ctx context.Context, jobType string, data []byte, options *callOptions) (*Job, error) {
+	if options.callbackURL != "" {
+		if err := validateCallbackURL(options.callbackURL); err != nil {
+			return nil, err
+		}
+	}
+	if options.validateType {
+		if err := c.checkJobType(ctx, jobType); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(createJobRequest{
+		Type:        jobType,
+		Params:      c.jobParams(jobType, options.params),
+		Metadata:    options.metadata,
+		Priority:    options.priority,
+		CallbackURL: options.callbackURL,
+		Data:        data,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job request: %w", err)
+	}
+
+	// The key is fixed before sending, so the retries of retryDoer repeat it
+	var editors []RequestEditorFn
+	if key := cmp.Or(options.idempotencyKey, c.idempotencyKey()); key != "" {
+		editors = append(editors, withHeader(IdempotencyKeyHeader, key))
+	}
+
+	createResp, err := c.CreateJobWithBodyWithResponse(ctx, "application/json", bytes.NewReader(body), editors...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create job: %w", err)
 	}
 
 	if createResp.StatusCode() != http.StatusCreated {
-		return nil, fmt.Errorf("failed to create job: status %d", createResp.StatusCode())
+		return nil, c.apiError("create job", createResp.HTTPResponse, createResp.Body)
 	}
 
 	if createResp.JSON201 == nil || createResp.JSON201.Data == nil {
@@ -124,78 +826,590 @@ func (c *BsubClient) CreateAndSubmitJob(ctx context.Context, jobType string, dat
 	}
 
 	job := createResp.JSON201.Data
-	if job.UploadToken == nil {
+	if job.Id == nil || (job.UploadToken == nil && !isSubmitted(job)) {
 		return nil, fmt.Errorf("no upload token in response")
 	}
+	if options.priorityDst != nil || options.metadataDst != nil {
+		assigned := assignedFields(createResp.Body)
+		if options.priorityDst != nil {
+			*options.priorityDst = assigned.Priority
+		}
+		if options.metadataDst != nil {
+			*options.metadataDst = assigned.Metadata
+		}
+	}
+
+	return job, nil
+}
+
+// createdJobFields are the fields of the job in a create response that the Job type
+// does not model
+type createdJobFields struct {
+	Priority JobPriority       `json:"priority"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// assignedFields reads the createdJobFields of the job in a create response
+func assignedFields(body []byte) createdJobFields {
+	var resp struct {
+		Data createdJobFields `json:"data"`
+	}
+	_ = json.Unmarshal(body, &resp)
+	return resp.Data
+}
+
+// idempotencyKey returns a random idempotency key when create requests may be retried,
+// and an empty one otherwise
+func (c *BsubClient) idempotencyKey() string {
+	if c.maxRetries <= 0 {
+		return ""
+	}
+	return uuid.NewString()
+}
+
+// uploadAndSubmit uploads data as the input of a created job and submits it
+func (c *BsubClient) uploadAndSubmit(ctx context.Context, job *Job, data io.Reader, options *callOptions) error {
+	options.step(ProcessEventUploading, job)
+	if err := c.upload(ctx, *job.Id, *job.UploadToken, data, options); err != nil {
+		return err
+	}
+	options.step(ProcessEventUploaded, job)
+
+	if err := c.submitJob(ctx, *job.Id); err != nil {
+		return err
+	}
+	options.step(ProcessEventSubmitted, job)
+	return nil
+}
 
-	// Upload data as multipart form
+// submitJob submits an uploaded job for processing
+func (c *BsubClient) submitJob(ctx context.Context, jobID JobId) error {
+	submitResp, err := c.SubmitJobWithResponse(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to submit job: %w", err)
+	}
+
+	// Servers that queue submissions asynchronously answer 202 Accepted
+	if code := submitResp.StatusCode(); code != http.StatusOK && code != http.StatusAccepted {
+		return c.apiError("submit job", submitResp.HTTPResponse, submitResp.Body)
+	}
+
+	return nil
+}
+
+// upload sends data as the input of a job in a single multipart request. The form is
+// streamed to the server while it is read from data, so memory use does not grow with
+// the input, unless the whole form is needed before sending: to compress it, or to hash
+// input that cannot be rewound and sent after its checksum.
+func (c *BsubClient) upload(ctx context.Context, jobID JobId, token string, data io.Reader, options *callOptions) error {
+	hashInput := options.checksum || c.shouldVerifyChecksum(options)
+	seeker, seekable := data.(io.ReadSeeker)
+	if c.shouldCompressUpload(options) || (hashInput && !seekable) {
+		return c.bufferedUpload(ctx, jobID, token, data, hashInput, options)
+	}
+
+	var sum string
+	if hashInput {
+		var err error
+		if sum, err = c.hashReadSeeker(seeker); err != nil {
+			return fmt.Errorf("failed to hash data: %w", err)
+		}
+	}
+
+	return c.streamedUpload(ctx, jobID, token, data, sum, options)
+}
+
+// streamedUpload sends data through a pipe, writing the multipart form around it on
+// another goroutine while the request reads the other end. A pipe cannot be replayed,
+// so an upload throttled with 429 is only sent again when data is an io.Seeker: it is
+// rewound and the form written into a new pipe. Other readers fail with the 429.
+func (c *BsubClient) streamedUpload(ctx context.Context, jobID JobId, token string, data io.Reader, sum string, options *callOptions) error {
+	size := options.uploadSize
+	if size < 0 {
+		if n, ok := readerSize(data); ok {
+			size = n
+		}
+	}
+
+	// Every attempt writes the form with the same boundary, which is in the Content-Type
+	form := multipart.NewWriter(io.Discard)
+	boundary := form.Boundary()
+	var (
+		body     *io.PipeReader
+		formDone chan error
+	)
+	writeForm := func() io.ReadCloser {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		_ = writer.SetBoundary(boundary)
+		done := make(chan error, 1)
+		go func() {
+			err := c.writeUploadForm(writer, data, size, options)
+			pw.CloseWithError(err)
+			done <- err
+		}()
+		body, formDone = pr, done
+		return pr
+	}
+	// endForm unblocks the writer when the request ended without reading the whole form
+	endForm := func() error {
+		body.Close()
+		return <-formDone
+	}
+
+	// Without a length the request is sent with chunked encoding
+	var editors []RequestEditorFn
+	if size >= 0 {
+		formSize, err := multipartFormSize(boundary, options.partHeader(), size)
+		if err != nil {
+			return err
+		}
+		editors = append(editors, withContentLength(formSize))
+	}
+	if seeker, ok := data.(io.Seeker); ok {
+		if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			editors = append(editors, withGetBody(func() (io.ReadCloser, error) {
+				if err := endForm(); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+					return nil, err
+				}
+				if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+					return nil, fmt.Errorf("failed to rewind data: %w", err)
+				}
+				return writeForm(), nil
+			}))
+		}
+	}
+
+	err := c.sendUpload(ctx, jobID, token, form.FormDataContentType(), writeForm(), sum, options, editors...)
+
+	formErr := endForm()
+	switch {
+	case formErr != nil && !errors.Is(formErr, io.ErrClosedPipe):
+		// A failure reading data is the cause of the request failing
+		return formErr
+	case err != nil:
+		return err
+	case formErr != nil:
+		return fmt.Errorf("failed to upload data: server answered before the input was sent")
+	}
+	return nil
+}
+
+// writeUploadForm writes the multipart form of an upload, with data as its only part,
+// reporting the progress of data as it is written
+func (c *BsubClient) writeUploadForm(writer *multipart.Writer, data io.Reader, size int64, options *callOptions) error {
+	part, err := writer.CreatePart(options.partHeader())
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+
+	if options.onUploadProgress != nil {
+		part = &uploadProgressWriter{w: part, size: size, onProgress: options.onUploadProgress}
+	}
+	if _, err := c.copyData(part, data); err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+	return nil
+}
+
+// multipartFormSize returns the length of the multipart form with boundary that holds
+// a single part of size bytes under header
+func multipartFormSize(boundary string, header textproto.MIMEHeader, size int64) (int64, error) {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, fmt.Errorf("failed to measure form: %w", err)
+	}
+	if _, err := writer.CreatePart(header); err != nil {
+		return 0, fmt.Errorf("failed to measure form: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return 0, fmt.Errorf("failed to measure form: %w", err)
+	}
+	return int64(buf.Len()) + size, nil
+}
 
-	part, err := writer.CreateFormFile("file", "upload")
+// bufferedUpload builds the whole multipart form in memory before sending it, hashing
+// the input in the same pass when hashInput is set and compressing the form when that
+// makes it smaller
+func (c *BsubClient) bufferedUpload(ctx context.Context, jobID JobId, token string, data io.Reader, hashInput bool, options *callOptions) error {
+	// Size the buffer up front when the reader knows its length so large payloads are
+	// not copied on every grow
+	var buf bytes.Buffer
+	if size, ok := readerSize(data); ok {
+		buf.Grow(int(size) + multipartOverhead)
+	}
+	writer := multipart.NewWriter(&buf)
+
+	part, err := writer.CreatePart(options.partHeader())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
+		return fmt.Errorf("failed to create form file: %w", err)
 	}
+	dataStart := int64(buf.Len())
 
-	if _, err := io.Copy(part, data); err != nil {
-		return nil, fmt.Errorf("failed to copy data: %w", err)
+	source := data
+	var digest hash.Hash
+	if hashInput {
+		digest = sha256.New()
+		source = io.TeeReader(data, digest)
+	}
+
+	size, err := c.copyData(part, source)
+	if err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
 	}
 
 	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close writer: %w", err)
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	var uploadEditors []RequestEditorFn
+	var sum string
+	if digest != nil {
+		sum = hex.EncodeToString(digest.Sum(nil))
+	}
+
+	var body io.Reader = &buf
+	bodySize := int64(buf.Len())
+	if compressed, ok := compressUpload(buf.Bytes()); ok {
+		uploadEditors = append(uploadEditors, withHeader("Content-Encoding", "gzip"))
+		body = compressed
+		bodySize = int64(compressed.Len())
+
+		// Input offsets are lost in compression, so progress counts the compressed body
+		dataStart, size = 0, bodySize
+	}
+
+	if options.onUploadProgress != nil {
+		uploadEditors = append(uploadEditors, withContentLength(bodySize))
+		body = &progressReader{r: body, start: dataStart, size: size, onProgress: options.onUploadProgress}
 	}
 
-	uploadResp, err := c.UploadJobDataWithBodyWithResponse(ctx, *job.Id, &UploadJobDataParams{
-		Token: *job.UploadToken,
-	}, writer.FormDataContentType(), &buf)
+	return c.sendUpload(ctx, jobID, token, writer.FormDataContentType(), body, sum, options, uploadEditors...)
+}
+
+// sendUpload sends an upload form and checks the response. A non-empty sum is sent as
+// the checksum of the input and compared with the one the server computed.
+func (c *BsubClient) sendUpload(ctx context.Context, jobID JobId, token string, contentType string, body io.Reader, sum string, options *callOptions, editors ...RequestEditorFn) error {
+	if sum != "" {
+		editors = append(editors, withHeader(ChecksumHeader, sum))
+		if options.checksumDst != nil {
+			*options.checksumDst = sum
+		}
+	}
+
+	uploadResp, err := c.UploadJobDataWithBodyWithResponse(ctx, jobID, &UploadJobDataParams{
+		Token: token,
+	}, contentType, body, editors...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload data: %w", err)
+		return fmt.Errorf("failed to upload data: %w", err)
 	}
 
 	if uploadResp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("failed to upload data: status %d", uploadResp.StatusCode())
+		apiErr := c.apiError("upload data", uploadResp.HTTPResponse, uploadResp.Body)
+		if sum != "" && isChecksumRejection(apiErr) {
+			return &ChecksumMismatchError{Expected: sum, Err: apiErr}
+		}
+		return apiErr
+	}
+
+	if got := uploadResp.HTTPResponse.Header.Get(ChecksumHeader); sum != "" && got != "" && !strings.EqualFold(got, sum) {
+		return &ChecksumMismatchError{Expected: sum, Actual: got}
 	}
 
-	// Submit job
-	submitResp, err := c.SubmitJobWithResponse(ctx, *job.Id)
+	return nil
+}
+
+// hashReadSeeker returns the hex SHA-256 of the rest of r, and rewinds r to where it was
+func (c *BsubClient) hashReadSeeker(r io.ReadSeeker) (string, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to submit job: %w", err)
+		return "", err
 	}
 
-	if submitResp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("failed to submit job: status %d", submitResp.StatusCode())
+	digest := sha256.New()
+	if _, err := c.copyData(digest, r); err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
 
-	return job, nil
+// shouldVerifyChecksum reports whether Config.VerifyChecksum, or its override for the
+// call, asks for the upload checksum to be sent
+func (c *BsubClient) shouldVerifyChecksum(options *callOptions) bool {
+	if options.verifyChecksum != nil {
+		return *options.verifyChecksum
+	}
+	return c.verifyChecksum
+}
+
+// isChecksumRejection reports whether the server refused an upload because its content
+// did not match the checksum sent with it
+func isChecksumRejection(apiErr *APIError) bool {
+	if apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	if apiErr.Code == "checksum_mismatch" {
+		return true
+	}
+	text := strings.ToLower(apiErr.Message + " " + string(apiErr.RawBody))
+	return strings.Contains(text, "checksum")
+}
+
+// shouldCompressUpload reports whether Config.CompressUploads, or its override for the
+// call, asks for the upload to be compressed
+func (c *BsubClient) shouldCompressUpload(options *callOptions) bool {
+	if options.compressUpload != nil {
+		return *options.compressUpload
+	}
+	return c.compressUploads
+}
+
+// compressUpload gzips an upload body, and reports false when it would not get smaller
+func compressUpload(body []byte) (*bytes.Buffer, bool) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(body); err != nil {
+		return nil, false
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false
+	}
+
+	if compressed.Len() >= len(body) {
+		return nil, false
+	}
+	return &compressed, true
+}
+
+// copyData copies src to dst through a buffer of the configured IOBufferSize
+func (c *BsubClient) copyData(dst io.Writer, src io.Reader) (int64, error) {
+	return io.CopyBuffer(dst, src, make([]byte, c.ioBufferSize))
+}
+
+// cleanupTimeout bounds the best-effort deletion of a job abandoned by a failed helper
+const cleanupTimeout = 10 * time.Second
+
+// abandonJob handles a job that was created but could not be uploaded or submitted.
+// With cleanup it deletes the job on a best-effort basis, even if ctx is already done.
+// The returned error wraps err and records the job ID and whether it was deleted.
+func (c *BsubClient) abandonJob(ctx context.Context, jobID JobId, err error, cleanup bool) error {
+	jobErr := &IncompleteJobError{JobID: jobID, Err: err}
+	if !cleanup {
+		return jobErr
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+
+	jobErr.Deleted = c.DeleteJob(cleanupCtx, jobID) == nil
+	return jobErr
+}
+
+// readerSize reports how many bytes r will yield, for readers that know it up front
+// such as *bytes.Reader, *strings.Reader, *io.SectionReader, regular files and other
+// readers that can seek
+func readerSize(r io.Reader) (int64, bool) {
+	switch sized := r.(type) {
+	case interface{ Len() int }:
+		return int64(sized.Len()), true
+	case interface{ Size() int64 }:
+		return sized.Size(), true
+	case *os.File:
+		info, err := sized.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		offset, err := sized.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return max(info.Size()-offset, 0), true
+	case io.Seeker:
+		return seekerSize(sized)
+	}
+	return 0, false
+}
+
+// seekerSize measures what is left of a seekable reader by seeking to its end and back
+func seekerSize(s io.Seeker) (int64, bool) {
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return max(end-offset, 0), true
+}
+
+// validateJobType rejects job types that the server could never accept
+func validateJobType(jobType string) error {
+	if strings.TrimSpace(jobType) == "" {
+		return ErrInvalidJobType
+	}
+	return nil
+}
+
+// validateCallbackURL rejects callback URLs that the server could never post to
+func validateCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidCallbackURL, callbackURL)
+	}
+	return nil
 }
 
 // CreateAndSubmitJobFromFile is a helper that creates a job, uploads a file, and submits it for processing
-func (c *BsubClient) CreateAndSubmitJobFromFile(ctx context.Context, jobType string, filePath string) (*Job, error) {
+func (c *BsubClient) CreateAndSubmitJobFromFile(ctx context.Context, jobType string, filePath string, opts ...CallOption) (*Job, error) {
+	jobType = jobTypeFromContext(ctx, jobType)
+	if err := validateJobType(jobType); err != nil {
+		return nil, err
+	}
+	if filePath == "" {
+		return nil, ErrEmptyFilePath
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	return c.CreateAndSubmitJob(ctx, jobType, file)
+	return c.CreateAndSubmitJob(ctx, jobType, file, opts...)
+}
+
+// CreateAndSubmitJobFromFileWithProgress is CreateAndSubmitJobFromFile that reports the
+// upload progress to onProgress, see WithUploadProgress
+func (c *BsubClient) CreateAndSubmitJobFromFileWithProgress(ctx context.Context, jobType string, filePath string, onProgress func(bytesSent, totalBytes int64), opts ...CallOption) (*Job, error) {
+	if onProgress == nil {
+		return nil, ErrNilCallback
+	}
+	return c.CreateAndSubmitJobFromFile(ctx, jobType, filePath, append(opts, WithUploadProgress(onProgress))...)
 }
 
-// WaitForJob polls the job status until it's finished or failed
+// CreateAndSubmitJobFromReaderAt is a helper that creates a job, uploads the first size bytes
+// of r, and submits it for processing. It suits callers that already hold an open file;
+// unlike CreateAndSubmitJobFromFile it leaves closing the handle to them.
+func (c *BsubClient) CreateAndSubmitJobFromReaderAt(ctx context.Context, jobType string, r io.ReaderAt, size int64, opts ...CallOption) (*Job, error) {
+	if r == nil {
+		return nil, ErrNilInput
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("invalid input: negative size %d", size)
+	}
+
+	return c.CreateAndSubmitJob(ctx, jobType, io.NewSectionReader(r, 0, size), opts...)
+}
+
+// WaitForJob polls the job status until it's finished or failed.
+// Responses without a status are treated as not done yet.
+//
+// When ctx is done before the job ends, the last job snapshot fetched is returned along
+// with the context error, so callers can tell how far the job got, e.g. to log that it
+// is still processing. It is nil if no status request succeeded.
 func (c *BsubClient) WaitForJob(ctx context.Context, jobID JobId) (*Job, error) {
+	return c.WaitForJobWithOptions(ctx, jobID, WaitForJobOptions{})
+}
+
+// WaitForJobWithOptions is WaitForJob with control over the delay between status
+// requests, which can grow exponentially while the job status does not change and
+// starts over from the initial interval when it does.
+func (c *BsubClient) WaitForJobWithOptions(ctx context.Context, jobID JobId, opts WaitForJobOptions) (*Job, error) {
+	return c.pollJobWith(ctx, jobID, opts, func(job *Job) bool {
+		return isTerminal(job.Status)
+	})
+}
+
+// WaitForJobWithCallback is WaitForJob that calls onPoll with the job snapshot fetched
+// by every status request, including the final one, so callers can report progress
+// without their own polling loop. onPoll runs synchronously on the polling goroutine;
+// it cannot stop the wait, so keep it quick.
+func (c *BsubClient) WaitForJobWithCallback(ctx context.Context, jobID JobId, onPoll func(*Job)) (*Job, error) {
+	if onPoll == nil {
+		return nil, ErrNilCallback
+	}
+
+	return c.pollJob(ctx, jobID, func(job *Job) bool {
+		onPoll(job)
+		return isTerminal(job.Status)
+	})
+}
+
+// WaitForJobStatus polls the job until it reaches target, e.g. JobStatusProcessing to
+// learn that a worker picked it up, or until it ends. Since statuses can change between
+// two polls, a job seen in a later status of the pipeline has reached target too, see
+// JobStatus.AtLeast. A job that ends without reaching target is returned with
+// ErrStatusNotReached.
+func (c *BsubClient) WaitForJobStatus(ctx context.Context, jobID JobId, target JobStatus) (*Job, error) {
+	job, err := c.pollJob(ctx, jobID, func(job *Job) bool {
+		return (job.Status != nil && job.Status.AtLeast(target)) || isTerminal(job.Status)
+	})
+	if err != nil {
+		return job, err
+	}
+
+	if !job.Status.AtLeast(target) {
+		return job, fmt.Errorf("%w: job %s ended as %s", ErrStatusNotReached, jobID, *job.Status)
+	}
+	return job, nil
+}
+
+// pollJob fetches the job until visit reports that waiting is over, sleeping
+// pollInterval between requests. It returns the job passed to the final visit, or when
+// ctx is done first, the last job fetched, if any, with the context error.
+func (c *BsubClient) pollJob(ctx context.Context, jobID JobId, visit func(*Job) bool) (*Job, error) {
+	return c.pollJobWith(ctx, jobID, WaitForJobOptions{}, visit)
+}
+
+// pollJobWith is pollJob with the delays between requests set by opts.
+//
+// With long polling enabled the server holds each request until the job changes,
+// so no sleep is needed between them. A server that answers immediately with an
+// unchanged status does not support it, and polling falls back to the interval.
+func (c *BsubClient) pollJobWith(ctx context.Context, jobID JobId, opts WaitForJobOptions, visit func(*Job) bool) (*Job, error) {
+	defer c.stats.addJobWait(time.Now())
+
+	longPoll := c.useLongPoll && !c.longPollUnsupported.Load()
+	backoff := newPollBackoff(opts, c.pollInterval)
+	var lastStatus JobStatus
+	var last *Job
+	polled := false
+
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return last, ctx.Err()
 		default:
 		}
 
-		resp, err := c.GetJobWithResponse(ctx, jobID)
+		var editors []RequestEditorFn
+		if longPoll {
+			editors = append(editors, WithQueryParam("wait", c.longPollWait.String()))
+		}
+
+		start := time.Now()
+		resp, err := c.GetJobWithResponse(ctx, jobID, editors...)
 		if err != nil {
+			if ctx.Err() != nil {
+				return last, fmt.Errorf("failed to get job status: %w", err)
+			}
 			return nil, fmt.Errorf("failed to get job status: %w", err)
 		}
 
 		if resp.StatusCode() != http.StatusOK {
-			return nil, fmt.Errorf("failed to get job status: status %d", resp.StatusCode())
+			return nil, c.apiError("get job status", resp.HTTPResponse, resp.Body)
 		}
 
 		if resp.JSON200 == nil || resp.JSON200.Data == nil {
@@ -203,24 +1417,70 @@ func (c *BsubClient) WaitForJob(ctx context.Context, jobID JobId) (*Job, error)
 		}
 
 		job := resp.JSON200.Data
+		var status JobStatus
+		if job.Status != nil {
+			status = *job.Status
+		}
+		unchanged := polled && status == lastStatus
+		if !unchanged {
+			c.logJobStatus(jobID, job)
+		}
 
-		// Check if job is in a terminal state
-		if job.Status != nil && (*job.Status == JobStatusFinished || *job.Status == JobStatusFailed) {
+		if visit(job) {
 			return job, nil
 		}
 
-		// Wait before polling again (simple implementation, could be improved with backoff)
+		lastStatus, last, polled = status, job, true
+
+		if longPoll {
+			if !unchanged || time.Since(start) >= c.longPollWait/2 {
+				continue
+			}
+
+			c.longPollUnsupported.Store(true)
+			longPoll = false
+		}
+
+		if !unchanged {
+			backoff.reset()
+		}
+
+		// Wait before polling again
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(2 * time.Second):
+			return last, ctx.Err()
+		case <-time.After(backoff.next()):
 			// Continue polling
 		}
 	}
 }
 
-// GetJobResult retrieves the complete result of a finished job including output and logs
-func (c *BsubClient) GetJobResult(ctx context.Context, jobID JobId) (*JobResult, error) {
+// logJobStatus logs a job status seen while waiting, at info level once it is terminal
+func (c *BsubClient) logJobStatus(jobID JobId, job *Job) {
+	switch {
+	case job.Status == nil:
+		c.logger.Debug("job status missing", "job_id", jobID)
+	case *job.Status == JobStatusFailed:
+		failed := jobFailedError(job)
+		c.logger.Error("job failed", "job_id", jobID, "error_code", failed.Code, "error", failed.Message)
+	case isTerminal(job.Status):
+		c.logger.Info("job ended", "job_id", jobID, "status", *job.Status)
+	default:
+		c.logger.Debug("job status changed", "job_id", jobID, "status", *job.Status)
+	}
+}
+
+// isTerminal reports whether status is one a job never leaves
+func isTerminal(status *JobStatus) bool {
+	return status != nil && (*status == JobStatusFinished || *status == JobStatusFailed || *status == JobStatusCancelled)
+}
+
+// GetJobResult retrieves the complete result of a finished job including output and logs.
+// For a failed job it returns the error details in Job along with any partial output and
+// logs the server kept; failing to download that output is not an error.
+func (c *BsubClient) GetJobResult(ctx context.Context, jobID JobId, opts ...CallOption) (*JobResult, error) {
+	options := newCallOptions(opts)
+
 	// Get job details
 	jobResp, err := c.GetJobWithResponse(ctx, jobID)
 	if err != nil {
@@ -228,7 +1488,7 @@ func (c *BsubClient) GetJobResult(ctx context.Context, jobID JobId) (*JobResult,
 	}
 
 	if jobResp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("failed to get job: status %d", jobResp.StatusCode())
+		return nil, c.apiError("get job", jobResp.HTTPResponse, jobResp.Body)
 	}
 
 	if jobResp.JSON200 == nil || jobResp.JSON200.Data == nil {
@@ -236,30 +1496,26 @@ func (c *BsubClient) GetJobResult(ctx context.Context, jobID JobId) (*JobResult,
 	}
 
 	job := jobResp.JSON200.Data
+	if job.Status == nil {
+		return nil, ErrMissingJobStatus
+	}
 
 	result := &JobResult{
-		Job: job,
+		Job:                 job,
+		OutputContentLength: -1,
 	}
 
-	// Get output if job is finished
-	if job.Status != nil && *job.Status == JobStatusFinished {
-		outputResp, err := c.GetJobOutput(ctx, jobID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get job output: %w", err)
-		}
-		defer outputResp.Body.Close()
-
-		if outputResp.StatusCode == http.StatusOK {
-			output, err := io.ReadAll(outputResp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read output: %w", err)
-			}
-			result.Output = output
+	// Get output if job is finished, or if WaitForOutput already saw it. Failed jobs
+	// may have left partial output, which is fetched on a best-effort basis.
+	failed := *job.Status == JobStatusFailed
+	if options.outputReady || failed || *job.Status == JobStatusFinished {
+		if err := c.readJobOutput(ctx, jobID, result, options); err != nil && !failed {
+			return nil, err
 		}
 	}
 
 	// Get logs
-	logsResp, err := c.GetJobLogs(ctx, jobID)
+	logsResp, err := c.getJobLogs(ctx, jobID)
 	if err != nil {
 		// Logs might not always be available, so we don't fail here
 		return result, nil
@@ -277,56 +1533,188 @@ func (c *BsubClient) GetJobResult(ctx context.Context, jobID JobId) (*JobResult,
 	return result, nil
 }
 
-// ProcessFile is a complete helper that creates, uploads, submits, waits, and retrieves results
-func (c *BsubClient) ProcessFile(ctx context.Context, jobType string, filePath string) (*JobResult, error) {
-	// Create and submit job
-	job, err := c.CreateAndSubmitJobFromFile(ctx, jobType, filePath)
+// readJobOutput downloads the job output into result. A missing output is not an error
+// and leaves result as it is.
+func (c *BsubClient) readJobOutput(ctx context.Context, jobID JobId, result *JobResult, options *callOptions) error {
+	outputResp, err := c.getJobOutput(ctx, jobID, options)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to get job output: %w", err)
 	}
+	defer outputResp.Body.Close()
 
-	// Wait for completion
-	finishedJob, err := c.WaitForJob(ctx, *job.Id)
-	if err != nil {
-		return nil, fmt.Errorf("failed waiting for job: %w", err)
+	if outputResp.StatusCode != http.StatusOK {
+		return nil
 	}
 
-	// Check if job failed
-	if finishedJob.Status != nil && *finishedJob.Status == JobStatusFailed {
-		result, _ := c.GetJobResult(ctx, *job.Id)
-		if result != nil && finishedJob.ErrorMessage != nil {
-			return result, fmt.Errorf("job failed: %s", *finishedJob.ErrorMessage)
-		}
-		return result, fmt.Errorf("job failed")
+	// Size the buffer up front when the server announced the length, which
+	// for decompressed output is only a lower bound
+	var output bytes.Buffer
+	if hint := outputSizeHint(outputResp); hint > 0 {
+		output.Grow(int(hint))
 	}
+	if _, err := output.ReadFrom(outputResp.Body); err != nil {
+		return fmt.Errorf("failed to read output: %w", err)
+	}
+	result.Output = output.Bytes()
+	result.OutputContentType = outputResp.Header.Get("Content-Type")
+	result.OutputContentEncoding = outputResp.Header.Get("Content-Encoding")
+	result.OutputContentLength = outputResp.ContentLength
+	return nil
+}
 
-	// Get results
-	return c.GetJobResult(ctx, *job.Id)
+// ProcessFile is a complete helper that creates, uploads, submits, waits, and retrieves results
+func (c *BsubClient) ProcessFile(ctx context.Context, jobType string, filePath string, opts ...CallOption) (*JobResult, error) {
+	return c.ProcessFileWithOptions(ctx, jobType, filePath, ProcessOptions{}, opts...)
+}
+
+// ProcessOptions sets separate time limits for the phases of ProcessFileWithOptions,
+// within the deadline of the caller's context. Zero means no limit of its own.
+type ProcessOptions struct {
+	// UploadTimeout bounds creating the job, uploading its input and submitting it.
+	// Running out returns an error wrapping ErrUploadTimeout.
+	UploadTimeout time.Duration
+	// WaitTimeout bounds waiting for the job and retrieving its result.
+	// Running out returns an error wrapping ErrWaitTimeout.
+	WaitTimeout time.Duration
+}
+
+// ProcessFileWithOptions is ProcessFile with separate timeouts for the upload and the
+// wait, so a stalled upload fails fast while slow processing is still allowed. With
+// WithJobRetry each attempt gets the full timeouts.
+func (c *BsubClient) ProcessFileWithOptions(ctx context.Context, jobType string, filePath string, phases ProcessOptions, opts ...CallOption) (result *JobResult, err error) {
+	ctx, span := c.startSpan(ctx, "bsubio.ProcessFile", jobTypeFromContext(ctx, jobType))
+	defer func() { endSpan(span, err) }()
+
+	return c.processWithRetry(ctx, phases, opts, func(ctx context.Context) (*Job, error) {
+		return c.CreateAndSubmitJobFromFile(ctx, jobType, filePath, opts...)
+	})
 }
 
 // Process is a complete helper that creates, uploads, submits, waits, and retrieves results from a reader
-func (c *BsubClient) Process(ctx context.Context, jobType string, data io.Reader) (*JobResult, error) {
-	// Create and submit job
-	job, err := c.CreateAndSubmitJob(ctx, jobType, data)
-	if err != nil {
-		return nil, err
+func (c *BsubClient) Process(ctx context.Context, jobType string, data io.Reader, opts ...CallOption) (result *JobResult, err error) {
+	ctx, span := c.startSpan(ctx, "bsubio.Process", jobTypeFromContext(ctx, jobType))
+	defer func() { endSpan(span, err) }()
+
+	// Failed jobs can only be re-run if the input can be read again
+	if newCallOptions(opts).jobAttempts > 1 && data != nil {
+		replay, err := newReplayableReader(data)
+		if err != nil {
+			return nil, err
+		}
+		return c.processWithRetry(ctx, ProcessOptions{}, opts, func(ctx context.Context) (*Job, error) {
+			input, err := replay()
+			if err != nil {
+				return nil, err
+			}
+			return c.CreateAndSubmitJob(ctx, jobType, input, opts...)
+		})
+	}
+
+	return c.processWithRetry(ctx, ProcessOptions{}, opts, func(ctx context.Context) (*Job, error) {
+		return c.CreateAndSubmitJob(ctx, jobType, data, opts...)
+	})
+}
+
+// ProcessBytes processes data like Process, uploading it as a file named "input.bin"
+// unless WithUploadFilename says otherwise
+func (c *BsubClient) ProcessBytes(ctx context.Context, jobType string, data []byte, opts ...CallOption) (*JobResult, error) {
+	opts = append([]CallOption{WithUploadFilename("input.bin")}, opts...)
+	return c.Process(ctx, jobType, bytes.NewReader(data), opts...)
+}
+
+// ProcessString processes s like Process, uploading it as UTF-8 text in a file named
+// "input.txt" unless WithUploadFilename says otherwise. Use JobResult.OutputString to
+// read a text output.
+func (c *BsubClient) ProcessString(ctx context.Context, jobType string, s string, opts ...CallOption) (*JobResult, error) {
+	opts = append([]CallOption{WithUploadFilename("input.txt"), withUploadContentType("text/plain; charset=utf-8")}, opts...)
+	return c.Process(ctx, jobType, strings.NewReader(s), opts...)
+}
+
+// WaitForJobResult waits for a submitted job to end and retrieves its output and logs,
+// which is what Process and ProcessFile do after submitting. A failed job is returned
+// with its partial result and a *JobFailedError carrying the error code and message,
+// a cancelled one with ErrJobCancelled. When ctx is done before the job ends, the
+// result holds the last job snapshot fetched, without output, as in WaitForJob.
+//
+// WithWaitForOutput and WithPollCallback change how it waits, and WithOutputAccept
+// and WithRawOutput how the output is fetched.
+func (c *BsubClient) WaitForJobResult(ctx context.Context, jobID JobId, opts ...CallOption) (*JobResult, error) {
+	if newCallOptions(opts).waitForOutput {
+		err := c.WaitForOutput(ctx, jobID)
+		if errors.Is(err, ErrJobFailed) {
+			result, resultErr := c.GetJobResult(ctx, jobID, opts...)
+			if resultErr != nil {
+				job, jobErr := c.getJob(ctx, jobID)
+				if jobErr != nil {
+					return nil, jobFailedError(nil)
+				}
+				result = &JobResult{Job: job, OutputContentLength: -1}
+			}
+			return result, jobFailedError(result.Job)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting for job: %w", err)
+		}
+
+		return c.GetJobResult(ctx, jobID, append(opts, outputReady())...)
 	}
 
 	// Wait for completion
-	finishedJob, err := c.WaitForJob(ctx, *job.Id)
+	var finishedJob *Job
+	var err error
+	if onPoll := newCallOptions(opts).onPoll; onPoll != nil {
+		finishedJob, err = c.WaitForJobWithCallback(ctx, jobID, onPoll)
+	} else {
+		finishedJob, err = c.WaitForJob(ctx, jobID)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed waiting for job: %w", err)
+		return lastPolledResult(finishedJob), fmt.Errorf("failed waiting for job: %w", err)
 	}
 
-	// Check if job failed
-	if finishedJob.Status != nil && *finishedJob.Status == JobStatusFailed {
-		result, _ := c.GetJobResult(ctx, *job.Id)
-		if result != nil && finishedJob.ErrorMessage != nil {
-			return result, fmt.Errorf("job failed: %s", *finishedJob.ErrorMessage)
+	// A job that did not finish is returned with whatever output and logs it left,
+	// to help diagnose it
+	if err := jobEndError(finishedJob); err != nil {
+		result, resultErr := c.GetJobResult(ctx, jobID, opts...)
+		if resultErr != nil {
+			result = &JobResult{Job: finishedJob, OutputContentLength: -1}
 		}
-		return result, fmt.Errorf("job failed")
+		return result, err
 	}
 
 	// Get results
-	return c.GetJobResult(ctx, *job.Id)
+	return c.GetJobResult(ctx, jobID, opts...)
+}
+
+// lastPolledResult wraps the job snapshot of an interrupted wait in a result without
+// output, so callers still learn how far the job got; nil when there is no snapshot
+func lastPolledResult(job *Job) *JobResult {
+	if job == nil {
+		return nil
+	}
+	return &JobResult{Job: job, OutputContentLength: -1}
+}
+
+// jobEndError returns the error for a terminal job that did not finish successfully,
+// nil for a finished job
+func jobEndError(job *Job) error {
+	switch job.CurrentStatus() {
+	case JobStatusFailed:
+		return jobFailedError(job)
+	case JobStatusCancelled:
+		return ErrJobCancelled
+	default:
+		return nil
+	}
+}
+
+// jobFailedError describes why job failed; job may be nil if it could not be fetched
+func jobFailedError(job *Job) *JobFailedError {
+	err := &JobFailedError{Job: job, Attempts: 1}
+	if job != nil && job.ErrorCode != nil {
+		err.Code = *job.ErrorCode
+	}
+	if job != nil && job.ErrorMessage != nil {
+		err.Message = *job.ErrorMessage
+	}
+	return err
 }
@@ -3,9 +3,17 @@ package bsubio
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/textproto"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -50,6 +58,23 @@ func TestNewBsubClient(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid config with custom IO buffer size",
+			config: Config{
+				APIKey:       "test-api-key",
+				IOBufferSize: 1 << 20,
+			},
+			wantErr: false,
+		},
+		{
+			name: "IO buffer size below minimum",
+			config: Config{
+				APIKey:       "test-api-key",
+				IOBufferSize: 512,
+			},
+			wantErr:     true,
+			errContains: "invalid IO buffer size",
+		},
 		{
 			name: "missing API key",
 			config: Config{
@@ -87,6 +112,326 @@ func TestNewBsubClient(t *testing.T) {
 	}
 }
 
+// TestNewBsubClient_HTTPClient verifies which HTTP client the SDK ends up using
+func TestNewBsubClient_HTTPClient(t *testing.T) {
+	t.Run("dedicated client by default", func(t *testing.T) {
+		first, err := NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+		second, err := NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+
+		assert.NotSame(t, http.DefaultClient, first.httpClient)
+		assert.NotSame(t, first.httpClient, second.httpClient)
+		require.NotNil(t, first.httpClient.Transport)
+		assert.NotSame(t, http.DefaultTransport, first.httpClient.Transport)
+		assert.NotSame(t, first.httpClient.Transport, second.httpClient.Transport)
+	})
+
+	t.Run("opt into sharing the default client", func(t *testing.T) {
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", ShareDefaultHTTPClient: true})
+		require.NoError(t, err)
+		assert.Same(t, http.DefaultClient, client.httpClient)
+	})
+
+	t.Run("connection pool", func(t *testing.T) {
+		client, err := NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+		transport := client.httpClient.Transport.(*http.Transport)
+		assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+		assert.Zero(t, transport.MaxConnsPerHost)
+
+		client, err = NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithConnectionPool(200, 64, time.Minute))
+		require.NoError(t, err)
+		transport = client.httpClient.Transport.(*http.Transport)
+		assert.Equal(t, 200, transport.MaxIdleConnsPerHost)
+		assert.GreaterOrEqual(t, transport.MaxIdleConns, 200)
+		assert.Equal(t, 64, transport.MaxConnsPerHost)
+		assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+
+		_, err = NewBsubClient(Config{APIKey: "test-api-key", MaxConnsPerHost: -1})
+		assert.Error(t, err)
+	})
+
+	t.Run("custom client wins", func(t *testing.T) {
+		custom := &http.Client{Timeout: testHTTPTimeout}
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", HTTPClient: custom, ShareDefaultHTTPClient: true})
+		require.NoError(t, err)
+		assert.Same(t, custom, client.httpClient)
+		assert.Zero(t, client.requestTimeout)
+	})
+}
+
+// TestNewBsubClientWithOptions tests configuring a client with option functions
+func TestNewBsubClientWithOptions(t *testing.T) {
+	t.Run("options are applied", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+
+		custom := &http.Client{Timeout: testHTTPTimeout}
+		client, err := NewBsubClientWithOptions(
+			WithAPIKey("test-api-key"),
+			WithAPIBaseURL(mockServer.URL),
+			WithCustomHTTPClient(custom),
+			WithRetries(2, time.Second),
+			WithUserAgent("my-app/1.0"),
+		)
+		require.NoError(t, err)
+		assert.Same(t, custom, client.httpClient)
+		assert.Equal(t, 2, client.maxRetries)
+		assert.Equal(t, time.Second, client.retryBackoff)
+
+		_, err = client.CreateJob(context.Background(), "test/linecount")
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer test-api-key", mockServer.LastRequest().Header.Get("Authorization"))
+		assert.Equal(t, "my-app/1.0", mockServer.LastRequest().Header.Get("User-Agent"))
+	})
+
+	t.Run("later options win", func(t *testing.T) {
+		client, err := NewBsubClientWithOptions(
+			WithConfig(Config{APIKey: "first-key", MaxRetries: 1}),
+			WithAPIKey("second-key"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "second-key", client.apiKey)
+		assert.Equal(t, 1, client.maxRetries)
+	})
+
+	t.Run("validation matches NewBsubClient", func(t *testing.T) {
+		_, err := NewBsubClientWithOptions()
+		assert.ErrorContains(t, err, "API key not found")
+
+		_, err = NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithRetries(-1, 0))
+		assert.ErrorContains(t, err, "invalid max retries")
+	})
+}
+
+// TestNewBsubClient_UserAgent verifies the User-Agent header sent with API requests
+func TestNewBsubClient_UserAgent(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"default", Config{}, DefaultUserAgent},
+		{"suffix", Config{UserAgentSuffix: "my-app/1.2"}, DefaultUserAgent + " my-app/1.2"},
+		{"replaced", Config{UserAgent: "custom/2.0"}, "custom/2.0"},
+		{"replaced with suffix", Config{UserAgent: "custom/2.0", UserAgentSuffix: "my-app/1.2"}, "custom/2.0 my-app/1.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.APIKey = "test-api-key"
+			tt.config.BaseURL = mockServer.URL
+			client, err := NewBsubClient(tt.config)
+			require.NoError(t, err)
+
+			_, err = client.CreateJob(context.Background(), "test/linecount")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, mockServer.LastRequest().Header.Get("User-Agent"))
+		})
+	}
+
+	assert.True(t, strings.HasPrefix(DefaultUserAgent, "bsubio-go/"), DefaultUserAgent)
+}
+
+// TestNewBsubClientFromConfig tests resolving credentials from the config file and environment
+func TestNewBsubClientFromConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configPath := filepath.Join(home, ".config", "bsubio", "config.json")
+
+	t.Run("nothing found", func(t *testing.T) {
+		t.Setenv("BSUBIO_API_KEY", "")
+
+		client, err := NewBsubClientFromConfig()
+		require.Error(t, err)
+		assert.Nil(t, client)
+		assert.Contains(t, err.Error(), configPath)
+		assert.Contains(t, err.Error(), "$BSUBIO_API_KEY")
+	})
+
+	t.Run("environment variable", func(t *testing.T) {
+		t.Setenv("BSUBIO_API_KEY", "env-api-key")
+
+		client, err := NewBsubClientFromConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "env-api-key", client.apiKey)
+	})
+
+	t.Run("config file wins", func(t *testing.T) {
+		t.Setenv("BSUBIO_API_KEY", "env-api-key")
+
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+
+		require.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0755))
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"api_key": "file-api-key", "base_url": "`+mockServer.URL+`"}`), 0600))
+
+		client, err := NewBsubClientFromConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "file-api-key", client.apiKey)
+
+		_, err = client.ListTypes(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer file-api-key", mockServer.LastRequest().Header.Get("Authorization"))
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		t.Setenv("BSUBIO_API_KEY", "env-api-key")
+		t.Setenv("BSUBIO_PROFILE", "")
+
+		require.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0755))
+		require.NoError(t, os.WriteFile(configPath, []byte(`{
+			"api_key": "default-api-key",
+			"profiles": {
+				"staging": {"api_key": "staging-api-key", "base_url": "https://staging.bsub.io"},
+				"prod": {"api_key": "prod-api-key"}
+			}
+		}`), 0600))
+
+		client, err := NewBsubClientFromConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "default-api-key", client.apiKey)
+
+		client, err = NewBsubClientWithProfile("staging")
+		require.NoError(t, err)
+		assert.Equal(t, "staging-api-key", client.apiKey)
+		assert.Equal(t, "https://staging.bsub.io/", client.ClientWithResponses.ClientInterface.(*Client).Server)
+
+		t.Setenv("BSUBIO_PROFILE", "prod")
+		client, err = NewBsubClientFromConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "prod-api-key", client.apiKey)
+		assert.Equal(t, "prod-api-key", LoadConfig().APIKey)
+
+		t.Setenv("BSUBIO_PROFILE", "missing")
+		_, err = NewBsubClientFromConfig()
+		assert.ErrorIs(t, err, ErrProfileNotFound)
+		assert.ErrorContains(t, err, `"missing"`)
+		assert.Empty(t, LoadConfig().APIKey)
+	})
+}
+
+// TestNewBsubClient_BaseURL tests the precedence of the base URL sources
+func TestNewBsubClient_BaseURL(t *testing.T) {
+	explicit := NewMockServer()
+	defer explicit.Close()
+	fromEnv := NewMockServer()
+	defer fromEnv.Close()
+
+	t.Run("environment variable", func(t *testing.T) {
+		t.Setenv("BSUBIO_BASE_URL", fromEnv.URL)
+
+		client, err := NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+		_, err = client.ListTypes(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, fromEnv.LastRequest())
+		assert.Nil(t, explicit.LastRequest())
+	})
+
+	t.Run("explicit config wins", func(t *testing.T) {
+		t.Setenv("BSUBIO_BASE_URL", "http://127.0.0.1:1")
+
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: explicit.URL})
+		require.NoError(t, err)
+		_, err = client.ListTypes(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, explicit.LastRequest())
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Setenv("BSUBIO_BASE_URL", "")
+
+		client, err := NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+		assert.Equal(t, DefaultBaseURL+"/", client.ClientWithResponses.ClientInterface.(*Client).Server)
+	})
+
+	t.Run("base path", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+
+		// A gateway that serves the API under /api/bsub
+		var paths []string
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			http.StripPrefix("/api/bsub", http.HandlerFunc(mockServer.handler)).ServeHTTP(w, r)
+		}))
+		defer gateway.Close()
+
+		for _, basePath := range []string{"/api/bsub", "api/bsub/"} {
+			paths = nil
+			client, err := NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithAPIBaseURL(gateway.URL), WithBaseURLPath(basePath))
+			require.NoError(t, err)
+			client.pollInterval = testPollInterval
+
+			result, err := client.ProcessString(context.Background(), "test/linecount", "a\nb")
+			require.NoError(t, err, basePath)
+			assert.Equal(t, "2", result.OutputString())
+
+			require.NotEmpty(t, paths)
+			for _, path := range paths {
+				assert.True(t, strings.HasPrefix(path, "/api/bsub/v1/"), path)
+			}
+		}
+
+		// The prefix is joined to a path already in the base URL
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: "https://gw.example.com/api", BasePath: "bsub"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://gw.example.com/api/bsub/", client.ClientWithResponses.ClientInterface.(*Client).Server)
+	})
+}
+
+// TestValidateAPIKey tests rejecting keys that cannot be valid while accepting unknown formats
+func TestValidateAPIKey(t *testing.T) {
+	for _, key := range []string{"test-api-key", "bsub_0123456789abcdef", "x"} {
+		assert.NoError(t, ValidateAPIKey(key), "key %q", key)
+	}
+
+	for _, key := range []string{"", " ", "test-api-key\n", " test-api-key", "test api key", "Bearer test-api-key", "test-\x00key"} {
+		assert.ErrorIs(t, ValidateAPIKey(key), ErrMalformedAPIKey, "key %q", key)
+	}
+
+	client, err := NewBsubClient(Config{APIKey: "test-api-key\n"})
+	assert.ErrorIs(t, err, ErrMalformedAPIKey)
+	assert.Nil(t, client)
+}
+
+// maxWriteRecorder records the largest single write it receives
+type maxWriteRecorder struct {
+	max int
+}
+
+func (w *maxWriteRecorder) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	return len(p), nil
+}
+
+// TestCopyData verifies that streamed copies use the configured buffer size
+func TestCopyData(t *testing.T) {
+	client, err := NewBsubClient(Config{APIKey: "test-api-key"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultIOBufferSize, client.ioBufferSize)
+
+	client, err = NewBsubClient(Config{APIKey: "test-api-key", IOBufferSize: MinIOBufferSize})
+	require.NoError(t, err)
+
+	// Hide bytes.Reader's WriterTo so the copy goes through the buffer
+	src := struct{ io.Reader }{bytes.NewReader(make([]byte, 3*MinIOBufferSize))}
+	dst := &maxWriteRecorder{}
+
+	n, err := client.copyData(dst, src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3*MinIOBufferSize), n)
+	assert.Equal(t, MinIOBufferSize, dst.max)
+}
+
 // TestNewBsubClient_AuthInterceptor verifies that the auth interceptor adds Bearer token
 func TestNewBsubClient_AuthInterceptor(t *testing.T) {
 	mockServer := NewMockServer()
@@ -109,6 +454,71 @@ func TestNewBsubClient_AuthInterceptor(t *testing.T) {
 	assert.Equal(t, 201, resp.StatusCode())
 }
 
+// TestNewBsubClient_AuthOverride verifies that a per-call Authorization header survives the auth interceptor
+func TestNewBsubClient_AuthOverride(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	client, err := NewBsubClient(Config{
+		APIKey:  "test-api-key",
+		BaseURL: mockServer.URL,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	override := func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer per-call-key")
+		return nil
+	}
+	resp, err := client.CreateJobWithResponse(ctx, CreateJobJSONRequestBody{Type: "test/linecount"}, override)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	assert.Equal(t, "Bearer per-call-key", mockServer.LastRequest().Header.Get("Authorization"))
+
+	// Without an override the client's key is used
+	_, err = client.CreateJobWithResponse(ctx, CreateJobJSONRequestBody{Type: "test/linecount"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer test-api-key", mockServer.LastRequest().Header.Get("Authorization"))
+
+	// A header set before the interceptor runs is kept as well
+	req, err := http.NewRequest(http.MethodGet, mockServer.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Token custom-scheme")
+	require.NoError(t, bearerAuth(staticTokenSource("test-api-key"))(ctx, req))
+	assert.Equal(t, "Token custom-scheme", req.Header.Get("Authorization"))
+}
+
+// TestWithQueryParam verifies that extra query parameters reach the server
+func TestWithQueryParam(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	client, err := NewBsubClient(Config{
+		APIKey:  "test-api-key",
+		BaseURL: mockServer.URL,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resp, err := client.CreateJobWithResponse(ctx, CreateJobJSONRequestBody{Type: "test/linecount"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.JSON201)
+
+	jobID := *resp.JSON201.Data.Id
+	getResp, err := client.GetJobWithResponse(ctx, jobID,
+		WithQueryParam("verbose", "1"),
+		WithQueryParam("fields", "a b"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, getResp.StatusCode())
+
+	req := mockServer.LastRequest()
+	require.NotNil(t, req)
+	assert.Equal(t, "/v1/jobs/"+jobID.String(), req.URL.Path)
+	assert.Equal(t, "1", req.URL.Query().Get("verbose"))
+	assert.Equal(t, "a b", req.URL.Query().Get("fields"))
+}
+
 // TestCreateAndSubmitJob tests the job creation and submission flow with passthrough
 func TestCreateAndSubmitJob(t *testing.T) {
 	t.Run("successful job creation and submission with passthrough", func(t *testing.T) {
@@ -155,6 +565,156 @@ func TestCreateAndSubmitJob(t *testing.T) {
 			assert.Equal(t, JobStatusFinished, *storedJob.Status)
 		}
 	})
+
+	t.Run("submit answered with 202 Accepted", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Submit status override only supported in mock mode")
+		}
+		mockServer.SetSubmitStatus(http.StatusAccepted)
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("queued")))
+
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, JobStatusFinished, *mockServer.GetJob(*job.Id).Status)
+	})
+
+	t.Run("submit rejected", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Submit status override only supported in mock mode")
+		}
+		mockServer.SetSubmitStatus(http.StatusConflict)
+
+		_, err := client.CreateAndSubmitJob(context.Background(), "test/linecount", bytes.NewReader([]byte("data")))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to submit job: status 409")
+	})
+}
+
+// TestCreateAndSubmitJob_CleanupOnError tests handling of jobs created but never submitted
+func TestCreateAndSubmitJob_CleanupOnError(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Submit failures only supported in mock mode")
+	}
+
+	t.Run("deleted by default", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.SetSubmitStatus(http.StatusConflict)
+
+		_, err := client.CreateAndSubmitJob(context.Background(), "test/linecount", bytes.NewReader([]byte("data")))
+
+		var jobErr *IncompleteJobError
+		require.ErrorAs(t, err, &jobErr)
+		assert.True(t, jobErr.Deleted)
+		assert.Nil(t, mockServer.GetJob(jobErr.JobID))
+		assert.Equal(t, http.MethodDelete, mockServer.LastRequest().Method)
+		assert.Contains(t, err.Error(), "deleted")
+	})
+
+	t.Run("deleted after the context is cancelled", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.SetDelay("/submit", testContextTimeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout/2)
+		defer cancel()
+
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+
+		var jobErr *IncompleteJobError
+		require.ErrorAs(t, err, &jobErr)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, jobErr.Deleted)
+	})
+
+	t.Run("kept when disabled", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.SetSubmitStatus(http.StatusConflict)
+
+		_, err := client.CreateAndSubmitJob(context.Background(), "test/linecount", bytes.NewReader([]byte("data")), WithCleanupOnError(false))
+
+		var jobErr *IncompleteJobError
+		require.ErrorAs(t, err, &jobErr)
+		assert.False(t, jobErr.Deleted)
+		assert.NotNil(t, mockServer.GetJob(jobErr.JobID))
+		assert.Contains(t, err.Error(), jobErr.JobID.String())
+	})
+}
+
+// TestCreateJobWithData tests sending small inputs in the create request, and falling
+// back to uploading them
+func TestCreateJobWithData(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Inline data only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	input := []byte("a\nb")
+
+	// create makes a job from input and reports whether it was uploaded separately
+	create := func(t *testing.T, client *BsubClient, mockServer *MockServer, opts ...CallOption) (*Job, bool) {
+		t.Helper()
+		uploads := client.Stats().Requests["UploadJobData"]
+		job, err := client.CreateJobWithData(ctx, "test/linecount", input, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		return job, client.Stats().Requests["UploadJobData"] > uploads
+	}
+
+	t.Run("sent inline", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+		mockServer.SetInlineData(true)
+
+		var sent int64
+		job, uploaded := create(t, client, mockServer, WithUploadProgress(func(bytesSent, totalBytes int64) { sent = bytesSent }))
+		assert.False(t, uploaded)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(input), mockServer.CreateRequest(*job.Id)["data"])
+		assert.Equal(t, int64(len(input)), sent)
+		assert.Zero(t, client.Stats().Requests["SubmitJob"])
+
+		result, err := client.WaitForJobResult(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "2", result.OutputString())
+	})
+
+	t.Run("server without inline data", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		job, uploaded := create(t, client, mockServer)
+		assert.True(t, uploaded)
+		assert.Equal(t, JobStatusFinished, *mockServer.GetJob(*job.Id).Status)
+	})
+
+	t.Run("uploaded", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.SetInlineData(true)
+
+		for name, setup := range map[string]func() []CallOption{
+			"above threshold": func() []CallOption { client.inlineDataThreshold = len(input) - 1; return nil },
+			"disabled":        func() []CallOption { client.inlineDataThreshold = -1; return nil },
+			"upload options": func() []CallOption {
+				client.inlineDataThreshold = DefaultInlineDataThreshold
+				return []CallOption{WithUploadFilename("input.txt")}
+			},
+		} {
+			job, uploaded := create(t, client, mockServer, setup()...)
+			assert.True(t, uploaded, name)
+			assert.NotContains(t, mockServer.CreateRequest(*job.Id), "data", name)
+		}
+	})
 }
 
 // TestWaitForJob tests the polling mechanism
@@ -216,8 +776,81 @@ func TestWaitForJob(t *testing.T) {
 		finalJob, err := client.WaitForJob(ctxWithTimeout, jobID)
 
 		require.Error(t, err)
-		assert.Nil(t, finalJob)
-		assert.Contains(t, err.Error(), "context")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// The last status seen is returned with the error
+		require.NotNil(t, finalJob)
+		assert.Equal(t, jobID, *finalJob.Id)
+		assert.Equal(t, JobStatusProcessing, *finalJob.Status)
+	})
+
+	t.Run("slow server", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Injected latency only supported in mock mode")
+		}
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		mockServer.SetDelay("/v1/jobs/", 2*testContextTimeout)
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, testContextTimeout)
+		defer cancel()
+
+		_, err = client.WaitForJob(ctxWithTimeout, *job.Id)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		mockServer.SetDelay("/v1/jobs/", 0)
+		finalJob, err := client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *finalJob.Status)
+	})
+}
+
+// TestWaitForJob_TerminalWithoutExtraSleep verifies that WaitForJob returns as soon as it
+// observes a terminal status instead of sleeping for another poll interval
+func TestWaitForJob_TerminalWithoutExtraSleep(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+
+	const interval = 300 * time.Millisecond
+	client.pollInterval = interval
+	ctx := context.Background()
+
+	t.Run("failed on first poll", func(t *testing.T) {
+		mockServer.SetProgression("test/fails-now", JobStatusFailed)
+		job, err := client.CreateAndSubmitJob(ctx, "test/fails-now", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		start := time.Now()
+		finalJob, err := client.WaitForJob(ctx, *job.Id)
+		elapsed := time.Since(start)
+
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFailed, *finalJob.Status)
+		assert.Less(t, elapsed, interval)
+	})
+
+	t.Run("failed between polls", func(t *testing.T) {
+		mockServer.SetProgression("test/fails-later", JobStatusProcessing, JobStatusFailed)
+		job, err := client.CreateAndSubmitJob(ctx, "test/fails-later", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		start := time.Now()
+		finalJob, err := client.WaitForJob(ctx, *job.Id)
+		elapsed := time.Since(start)
+
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFailed, *finalJob.Status)
+		assert.GreaterOrEqual(t, elapsed, interval)
+		assert.Less(t, elapsed, 2*interval)
 	})
 }
 
@@ -238,23 +871,43 @@ func TestGetJobResult(t *testing.T) {
 
 		require.NoError(t, err)
 		require.NotNil(t, result)
-		assert.Equal(t, *job.Id, *result.Job.Id)
+		assert.Equal(t, *job.Id, *result.Job.Id)
+		assert.NotEmpty(t, result.Output)
+	})
+
+	t.Run("successful result with line_counter", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx := context.Background()
+		data := bytes.NewReader([]byte("line1\nline2\nline3\nline4\nline5"))
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", data)
+		require.NoError(t, err)
+
+		result, err := client.GetJobResult(ctx, *job.Id)
+		require.NoError(t, err)
+		require.NotNil(t, result)
 		assert.NotEmpty(t, result.Output)
 	})
 
-	t.Run("successful result with line_counter", func(t *testing.T) {
-		client, _, cleanup := SetupTestClient(t)
+	t.Run("unfinished job has no output length", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
 		defer cleanup()
 
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		mockServer.SetProgression("test/stuck", JobStatusProcessing)
+
 		ctx := context.Background()
-		data := bytes.NewReader([]byte("line1\nline2\nline3\nline4\nline5"))
-		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", data)
+		job, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
 		require.NoError(t, err)
 
 		result, err := client.GetJobResult(ctx, *job.Id)
 		require.NoError(t, err)
-		require.NotNil(t, result)
-		assert.NotEmpty(t, result.Output)
+		assert.Empty(t, result.Output)
+		assert.Empty(t, result.OutputContentType)
+		assert.Equal(t, int64(-1), result.OutputContentLength)
 	})
 }
 
@@ -289,6 +942,128 @@ func TestProcess(t *testing.T) {
 		assert.Equal(t, JobStatusFinished, *result.Job.Status)
 		assert.NotEmpty(t, result.Output)
 	})
+
+	t.Run("custom output generator", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Custom output generators only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("doc/pandoc", JobStatusProcessing, JobStatusFinished)
+		mockServer.SetOutputFunc("doc/pandoc", func(uploadedData []byte) []byte {
+			return append([]byte("# "), uploadedData...)
+		})
+
+		ctx := context.Background()
+		result, err := client.Process(ctx, "doc/pandoc", bytes.NewReader([]byte("Title")))
+
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, JobStatusFinished, *result.Job.Status)
+		assert.Equal(t, "# Title", string(result.Output))
+	})
+
+	t.Run("scripted outcomes", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted outcomes only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetJobOutcome("test/broken", JobStatusFailed, "invalid_input", "cannot parse input")
+		mockServer.SetJobOutcome("test/stuck", JobStatusProcessing, "", "")
+		mockServer.SetOutput("test/fixed", []byte("fixed output"))
+		mockServer.SetJobOutcome("test/fixed", JobStatusFinished, "", "")
+
+		ctx := context.Background()
+		result, err := client.Process(ctx, "test/broken", strings.NewReader("data"))
+		var failed *JobFailedError
+		require.ErrorAs(t, err, &failed)
+		assert.Equal(t, "invalid_input", failed.Code)
+		assert.Equal(t, "cannot parse input", failed.Message)
+		require.NotNil(t, result)
+		assert.Equal(t, JobStatusFailed, *result.Job.Status)
+
+		result, err = client.Process(ctx, "test/fixed", strings.NewReader("data"))
+		require.NoError(t, err)
+		assert.Equal(t, "fixed output", string(result.Output))
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = client.Process(ctx, "test/stuck", strings.NewReader("data"))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("failed job keeps partial output and logs", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted outcomes only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetOutput("test/partial", []byte("page 1 of 3"))
+		mockServer.SetJobOutcome("test/partial", JobStatusFailed, "timeout", "gave up on page 2")
+
+		path := filepath.Join(t.TempDir(), "input.txt")
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+		result, err := client.ProcessFile(context.Background(), "test/partial", path)
+		assert.ErrorIs(t, err, ErrJobFailed)
+		require.NotNil(t, result)
+		assert.Equal(t, "page 1 of 3", result.OutputString())
+		assert.Contains(t, result.Logs, "Processing test/partial job")
+		assert.Equal(t, "timeout", *result.Job.ErrorCode)
+
+		stream, err := client.GetJobResultStream(context.Background(), *result.Job.Id)
+		require.NoError(t, err)
+		defer stream.Close()
+		require.NotNil(t, stream.Output)
+		partial, err := io.ReadAll(stream.Output)
+		require.NoError(t, err)
+		assert.Equal(t, "page 1 of 3", string(partial))
+	})
+}
+
+// TestWithOutputAccept tests requesting an output format and reporting the one returned
+func TestWithOutputAccept(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Output formats only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/table", JobStatusFinished)
+	mockServer.SetOutputFormats("test/table", "application/json", "text/csv")
+
+	ctx := context.Background()
+
+	t.Run("honored", func(t *testing.T) {
+		result, err := client.Process(ctx, "test/table", bytes.NewReader([]byte("a,b")), WithOutputAccept("text/csv"))
+		require.NoError(t, err)
+		assert.Equal(t, "text/csv", result.OutputContentType)
+	})
+
+	t.Run("not honored", func(t *testing.T) {
+		result, err := client.Process(ctx, "test/table", bytes.NewReader([]byte("a,b")), WithOutputAccept("application/xml"))
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", result.OutputContentType)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		result, err := client.Process(ctx, "test/linecount", bytes.NewReader([]byte("a")))
+		require.NoError(t, err)
+		assert.Equal(t, "application/octet-stream", result.OutputContentType)
+		assert.Equal(t, int64(len(result.Output)), result.OutputContentLength)
+	})
 }
 
 // TestCreateAndSubmitJobFromFile tests file-based job submission
@@ -325,6 +1100,136 @@ func TestCreateAndSubmitJobFromFile(t *testing.T) {
 	})
 }
 
+// TestCreateAndSubmitJobFromReaderAt tests submission from an already open file
+func TestCreateAndSubmitJobFromReaderAt(t *testing.T) {
+	t.Run("uploads the requested size", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		tmpDir := t.TempDir()
+		testFilePath := filepath.Join(tmpDir, "test.txt")
+		testContent := []byte("line1\nline2\nline3")
+		require.NoError(t, os.WriteFile(testFilePath, testContent, 0644))
+
+		file, err := os.Open(testFilePath)
+		require.NoError(t, err)
+		defer file.Close()
+
+		info, err := file.Stat()
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJobFromReaderAt(ctx, "test/linecount", file, info.Size())
+		require.NoError(t, err)
+		require.NotNil(t, job)
+
+		if mockServer != nil {
+			storedJob := mockServer.GetJob(*job.Id)
+			require.NotNil(t, storedJob)
+			assert.Equal(t, JobStatusFinished, *storedJob.Status)
+			assert.NotZero(t, *storedJob.DataSize)
+		}
+
+		// The handle is still usable by the caller
+		_, err = file.Stat()
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx := context.Background()
+		_, err := client.CreateAndSubmitJobFromReaderAt(ctx, "test/linecount", nil, 10)
+		assert.ErrorIs(t, err, ErrNilInput)
+
+		_, err = client.CreateAndSubmitJobFromReaderAt(ctx, "test/linecount", bytes.NewReader(nil), -1)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "negative size")
+	})
+}
+
+// TestInputValidation tests that invalid arguments are rejected before any request is made
+func TestInputValidation(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	client, err := NewBsubClient(Config{
+		APIKey:  "test-api-key",
+		BaseURL: mockServer.URL,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	data := []byte("test data")
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{
+			name: "empty job type",
+			call: func() error {
+				_, err := client.CreateAndSubmitJob(ctx, "", bytes.NewReader(data))
+				return err
+			},
+			wantErr: ErrInvalidJobType,
+		},
+		{
+			name: "blank job type",
+			call: func() error {
+				_, err := client.Process(ctx, "  ", bytes.NewReader(data))
+				return err
+			},
+			wantErr: ErrInvalidJobType,
+		},
+		{
+			name: "nil reader",
+			call: func() error {
+				_, err := client.CreateAndSubmitJob(ctx, "test/linecount", nil)
+				return err
+			},
+			wantErr: ErrNilInput,
+		},
+		{
+			name: "nil reader in Process",
+			call: func() error {
+				_, err := client.Process(ctx, "test/linecount", nil)
+				return err
+			},
+			wantErr: ErrNilInput,
+		},
+		{
+			name: "empty file path",
+			call: func() error {
+				_, err := client.CreateAndSubmitJobFromFile(ctx, "test/linecount", "")
+				return err
+			},
+			wantErr: ErrEmptyFilePath,
+		},
+		{
+			name: "empty job type with file",
+			call: func() error {
+				_, err := client.ProcessFile(ctx, "", "input.txt")
+				return err
+			},
+			wantErr: ErrInvalidJobType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	// None of the calls should have reached the server
+	assert.Nil(t, mockServer.LastRequest())
+}
+
 // TestProcessFile tests end-to-end file processing
 func TestProcessFile(t *testing.T) {
 	t.Run("successful file processing end-to-end with passthrough", func(t *testing.T) {
@@ -399,12 +1304,12 @@ func TestJobIsTerminal(t *testing.T) {
 		{JobStatusProcessing, false},
 		{JobStatusFinished, true},
 		{JobStatusFailed, true},
+		{JobStatusCancelled, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(string(tt.status), func(t *testing.T) {
-			isTerminal := tt.status == JobStatusFinished || tt.status == JobStatusFailed
-			assert.Equal(t, tt.isTerminal, isTerminal)
+			assert.Equal(t, tt.isTerminal, isTerminal(&tt.status))
 		})
 	}
 }
@@ -435,6 +1340,98 @@ func BenchmarkCreateAndSubmitJob(b *testing.B) {
 	}
 }
 
+// BenchmarkCreateAndSubmitJobParallel benchmarks many goroutines sharing a client, with
+// the net/http default of 2 idle connections per host against the SDK default
+func BenchmarkCreateAndSubmitJobParallel(b *testing.B) {
+	for _, pool := range []struct {
+		name    string
+		maxIdle int
+	}{
+		{"net/http default pool", 2},
+		{"default pool", DefaultMaxIdleConnsPerHost},
+	} {
+		b.Run(pool.name, func(b *testing.B) {
+			mockServer := NewMockServer()
+			defer mockServer.Close()
+
+			client, err := NewBsubClient(Config{
+				APIKey:              "test-key",
+				BaseURL:             mockServer.URL,
+				MaxIdleConnsPerHost: pool.maxIdle,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			// Count the connections opened, which a pool too small for the goroutines
+			// keeps closing and dialing again
+			var dials atomic.Int64
+			transport := client.httpClient.Transport.(*http.Transport)
+			dial := transport.DialContext
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials.Add(1)
+				return dial(ctx, network, addr)
+			}
+
+			ctx := context.Background()
+			data := []byte("test data content")
+			defer func() { b.ReportMetric(float64(dials.Load())/float64(b.N), "dials/op") }()
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(data)); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkCreateAndSubmitJobPayloads benchmarks the create, upload and submit path
+// for increasing payload sizes to guard against allocation regressions
+func BenchmarkCreateAndSubmitJobPayloads(b *testing.B) {
+	sizes := []struct {
+		name string
+		size int
+	}{
+		{"1KB", 1 << 10},
+		{"1MB", 1 << 20},
+		{"100MB", 100 << 20},
+	}
+
+	for _, size := range sizes {
+		b.Run(size.name, func(b *testing.B) {
+			mockServer := NewMockServer()
+			defer mockServer.Close()
+
+			client, err := NewBsubClient(Config{
+				APIKey:  "test-key",
+				BaseURL: mockServer.URL,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			ctx := context.Background()
+			data := bytes.Repeat([]byte("x"), size.size)
+
+			b.SetBytes(int64(size.size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(data))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 // TestIntegration_RealJobTypes tests with actual job types that exist in production
 // Run with BSUB_TEST_MODE=production to test against real server
 func TestIntegration_RealJobTypes(t *testing.T) {
@@ -477,3 +1474,221 @@ func TestIntegration_RealJobTypes(t *testing.T) {
 		t.Logf("Line count output: %s", string(result.Output))
 	})
 }
+
+// TestWaitForJob_LongPoll tests long-polling status requests and the fallback to interval polling
+func TestWaitForJob_LongPoll(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Long-poll tests only supported in mock mode")
+	}
+
+	newLongPollClient := func(t *testing.T, mockServer *MockServer) *BsubClient {
+		client, err := NewBsubClient(Config{
+			APIKey:      "test-api-key",
+			BaseURL:     mockServer.URL,
+			UseLongPoll: true,
+		})
+		require.NoError(t, err)
+		client.longPollWait = 200 * time.Millisecond
+		return client
+	}
+
+	t.Run("server holds requests", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+		mockServer.SetLongPoll(true)
+		mockServer.SetProgression("test/slow", JobStatusProcessing, JobStatusProcessing, JobStatusFinished)
+
+		client := newLongPollClient(t, mockServer)
+		// Interval polling would never finish within the deadline
+		client.pollInterval = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		start := time.Now()
+		finalJob, err := client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *finalJob.Status)
+		assert.GreaterOrEqual(t, time.Since(start), client.longPollWait)
+		assert.False(t, client.longPollUnsupported.Load())
+		assert.Equal(t, "200ms", mockServer.LastRequest().URL.Query().Get("wait"))
+	})
+
+	t.Run("falls back when the server ignores wait", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+		mockServer.SetProgression("test/slow", JobStatusProcessing, JobStatusProcessing, JobStatusProcessing, JobStatusFinished)
+
+		client := newLongPollClient(t, mockServer)
+		client.pollInterval = testPollInterval
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		finalJob, err := client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *finalJob.Status)
+		assert.True(t, client.longPollUnsupported.Load())
+		assert.Empty(t, mockServer.LastRequest().URL.Query().Get("wait"))
+	})
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestProcessFileWithOptions tests that each phase times out on its own, with distinct errors
+func TestProcessFileWithOptions(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Stalled uploads only supported in mock mode")
+	}
+
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+	mockServer.SetProgression("test/stuck", JobStatusProcessing)
+
+	// Uploads of stalled job types hang until their context is done
+	var stallUploads atomic.Bool
+	client, err := NewBsubClient(Config{
+		APIKey:  "test-api-key",
+		BaseURL: mockServer.URL,
+		HTTPClient: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if stallUploads.Load() && strings.HasPrefix(req.URL.Path, "/v1/upload/") {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})},
+	})
+	require.NoError(t, err)
+	client.pollInterval = testPollInterval
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb"), 0644))
+
+	ctx := context.Background()
+
+	t.Run("upload timeout", func(t *testing.T) {
+		stallUploads.Store(true)
+		defer stallUploads.Store(false)
+
+		_, err := client.ProcessFileWithOptions(ctx, "test/linecount", path, ProcessOptions{
+			UploadTimeout: 20 * time.Millisecond,
+			WaitTimeout:   time.Minute,
+		})
+		assert.ErrorIs(t, err, ErrUploadTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, ErrWaitTimeout)
+	})
+
+	t.Run("wait timeout", func(t *testing.T) {
+		_, err := client.ProcessFileWithOptions(ctx, "test/stuck", path, ProcessOptions{
+			UploadTimeout: time.Minute,
+			WaitTimeout:   30 * time.Millisecond,
+		})
+		assert.ErrorIs(t, err, ErrWaitTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, ErrUploadTimeout)
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		result, err := client.ProcessFileWithOptions(ctx, "test/linecount", path, ProcessOptions{
+			UploadTimeout: time.Minute,
+			WaitTimeout:   time.Minute,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "2", strings.TrimSpace(string(result.Output)))
+	})
+
+	t.Run("caller deadline is not a phase timeout", func(t *testing.T) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+		defer cancel()
+
+		_, err := client.ProcessFileWithOptions(timeoutCtx, "test/stuck", path, ProcessOptions{WaitTimeout: time.Minute})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, ErrWaitTimeout)
+	})
+}
+
+// TestProcessBytesAndString tests the in-memory input helpers
+func TestProcessBytesAndString(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+	client.pollInterval = testPollInterval
+
+	ctx := context.Background()
+
+	t.Run("bytes", func(t *testing.T) {
+		result, err := client.ProcessBytes(ctx, "test/linecount", []byte("a\nb\nc"))
+		require.NoError(t, err)
+		assert.Equal(t, "3", strings.TrimSpace(result.OutputString()))
+
+		if mockServer != nil {
+			part := mockServer.UploadPart(*result.Job.Id)
+			assert.Equal(t, "input.bin", fileNameOf(t, part))
+			assert.Equal(t, "application/octet-stream", part.Get("Content-Type"))
+		}
+	})
+
+	t.Run("string", func(t *testing.T) {
+		result, err := client.ProcessString(ctx, "test/linecount", "a\nb")
+		require.NoError(t, err)
+		assert.Equal(t, "2", strings.TrimSpace(result.OutputString()))
+
+		if mockServer != nil {
+			part := mockServer.UploadPart(*result.Job.Id)
+			assert.Equal(t, "input.txt", fileNameOf(t, part))
+			assert.Equal(t, "text/plain; charset=utf-8", part.Get("Content-Type"))
+		}
+	})
+
+	t.Run("file name option wins", func(t *testing.T) {
+		if mockServer == nil {
+			t.Skip("Upload inspection only supported in mock mode")
+		}
+
+		result, err := client.ProcessString(ctx, "test/linecount", "a", WithUploadFilename(`notes "v2".md`))
+		require.NoError(t, err)
+		assert.Equal(t, `notes "v2".md`, fileNameOf(t, mockServer.UploadPart(*result.Job.Id)))
+	})
+}
+
+// fileNameOf returns the file name in the Content-Disposition of an upload part
+func fileNameOf(t *testing.T, header textproto.MIMEHeader) string {
+	t.Helper()
+	require.NotNil(t, header)
+	_, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	require.NoError(t, err)
+	return params["filename"]
+}
+
+// TestJobResultOutput tests the accessors for the job output
+func TestJobResultOutput(t *testing.T) {
+	result := &JobResult{Output: []byte(`{"lines": 2, "words": ["a", "b"]}`)}
+
+	assert.Equal(t, `{"lines": 2, "words": ["a", "b"]}`, result.OutputString())
+
+	data, err := io.ReadAll(result.OutputReader())
+	require.NoError(t, err)
+	assert.Equal(t, result.Output, data)
+
+	var decoded struct {
+		Lines int      `json:"lines"`
+		Words []string `json:"words"`
+	}
+	require.NoError(t, result.OutputJSON(&decoded))
+	assert.Equal(t, 2, decoded.Lines)
+	assert.Equal(t, []string{"a", "b"}, decoded.Words)
+
+	err = (&JobResult{Output: []byte("2\n")}).OutputJSON(&decoded)
+	assert.ErrorContains(t, err, "failed to decode output")
+	err = (&JobResult{}).OutputJSON(&decoded)
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package bsubio
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures a client created with NewBsubClientWithOptions. Options are applied
+// in order over a zero Config, so a later option overrides an earlier one.
+type Option func(*Config)
+
+// NewBsubClientWithOptions creates a client configured by opts. It accepts the same
+// settings as NewBsubClient with a Config, but new settings can be added as options
+// without affecting existing callers.
+func NewBsubClientWithOptions(opts ...Option) (*BsubClient, error) {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return NewBsubClient(config)
+}
+
+// WithConfig starts from a copy of config, so options can adjust an existing Config
+func WithConfig(config Config) Option {
+	return func(c *Config) {
+		*c = config
+	}
+}
+
+// WithAPIKey sets the API key, see Config.APIKey
+func WithAPIKey(apiKey string) Option {
+	return func(c *Config) {
+		c.APIKey = apiKey
+	}
+}
+
+// WithTokenSource authenticates requests with the tokens of src instead of an API key,
+// see Config.TokenSource
+func WithTokenSource(src TokenSource) Option {
+	return func(c *Config) {
+		c.TokenSource = src
+	}
+}
+
+// WithAPIBaseURL sets the API server URL, see Config.BaseURL. It is not called
+// WithBaseURL because the generated client already has an option of that name.
+func WithAPIBaseURL(baseURL string) Option {
+	return func(c *Config) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithBaseURLPath sets the prefix of all API request paths, see Config.BasePath
+func WithBaseURLPath(basePath string) Option {
+	return func(c *Config) {
+		c.BasePath = basePath
+	}
+}
+
+// WithCustomHTTPClient sets the HTTP client requests are sent with, see Config.HTTPClient.
+// It is not called WithHTTPClient because the generated client already has an option
+// of that name.
+func WithCustomHTTPClient(httpClient *http.Client) Option {
+	return func(c *Config) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithConnectionPool sizes the connection pool of the client's own HTTP transport, see
+// Config.MaxIdleConnsPerHost, Config.MaxConnsPerHost and Config.IdleConnTimeout. Zero
+// values keep the defaults.
+func WithConnectionPool(maxIdleConnsPerHost, maxConnsPerHost int, idleConnTimeout time.Duration) Option {
+	return func(c *Config) {
+		c.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		c.MaxConnsPerHost = maxConnsPerHost
+		c.IdleConnTimeout = idleConnTimeout
+	}
+}
+
+// WithDefaultTimeout bounds each API request by d, see Config.DefaultTimeout
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.DefaultTimeout = d
+	}
+}
+
+// WithRetries retries requests that fail transiently up to maxRetries times, waiting
+// backoff before the first retry, or the default backoff when it is zero. See
+// Config.MaxRetries for which requests are retried.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Config) {
+		c.MaxRetries = maxRetries
+		c.RetryBackoff = backoff
+	}
+}
+
+// WithUserAgent replaces the User-Agent header sent with every request, see Config.UserAgent
+func WithUserAgent(userAgent string) Option {
+	return func(c *Config) {
+		c.UserAgent = userAgent
+	}
+}
+
+// WithUserAgentSuffix identifies the application in the User-Agent header, see
+// Config.UserAgentSuffix
+func WithUserAgentSuffix(suffix string) Option {
+	return func(c *Config) {
+		c.UserAgentSuffix = suffix
+	}
+}
+
+// WithTracerProvider records OpenTelemetry spans with tp, see Config.TracerProvider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Config) {
+		c.TracerProvider = tp
+	}
+}
+
+// WithLogger sends the client's log events to logger, see Config.Logger
+func WithLogger(logger Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithRequestInterceptor adds an interceptor that runs on every API request before it is
+// sent, after the built-in Authorization and User-Agent headers are set. See
+// Config.RequestInterceptors.
+func WithRequestInterceptor(intercept RequestInterceptor) Option {
+	return func(c *Config) {
+		c.RequestInterceptors = append(c.RequestInterceptors, intercept)
+	}
+}
+
+// WithResponseInterceptor adds an interceptor that runs on every API response, see
+// Config.ResponseInterceptors
+func WithResponseInterceptor(intercept ResponseInterceptor) Option {
+	return func(c *Config) {
+		c.ResponseInterceptors = append(c.ResponseInterceptors, intercept)
+	}
+}
+
+// WithRateLimit limits the client to requestsPerSecond API requests, with bursts of up to
+// burst requests, see Config.RequestsPerSecond
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(c *Config) {
+		c.RequestsPerSecond = requestsPerSecond
+		c.Burst = burst
+	}
+}
+
+// WithInlineDataThreshold sets the largest input CreateJobWithData sends inline, see
+// Config.InlineDataThreshold
+func WithInlineDataThreshold(n int) Option {
+	return func(c *Config) {
+		c.InlineDataThreshold = n
+	}
+}
@@ -0,0 +1,345 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors returned by the helpers when their arguments are rejected before any request is made
+var (
+	// ErrInvalidJobType is returned when the job type is empty
+	ErrInvalidJobType = errors.New("invalid job type: must not be empty")
+	// ErrNilInput is returned when the data reader is nil
+	ErrNilInput = errors.New("invalid input: data reader must not be nil")
+	// ErrEmptyFilePath is returned when the input file path is empty
+	ErrEmptyFilePath = errors.New("invalid input: file path must not be empty")
+	// ErrNilCallback is returned when a required callback is nil
+	ErrNilCallback = errors.New("invalid input: callback must not be nil")
+	// ErrUnsupportedOption is returned when a helper is called with a CallOption it cannot honor
+	ErrUnsupportedOption = errors.New("invalid input: unsupported option")
+	// ErrInvalidCallbackURL is returned when a callback URL is not an absolute http or https URL
+	ErrInvalidCallbackURL = errors.New("invalid input: callback URL must be an absolute http or https URL")
+	// ErrMalformedAPIKey is returned by ValidateAPIKey and NewBsubClient for keys that cannot be valid
+	ErrMalformedAPIKey = errors.New("API key looks malformed")
+)
+
+// ErrProfileNotFound is returned by NewBsubClientWithProfile and NewBsubClientFromConfig
+// when the selected config profile is not in the config file
+var ErrProfileNotFound = errors.New("config profile not found")
+
+// ErrUnknownJobType is matched by the *UnknownJobTypeError returned when job type
+// validation is enabled and the server does not support the job type
+var ErrUnknownJobType = errors.New("unknown job type")
+
+// UnknownJobTypeError is returned by helpers called with WithJobTypeValidation when the
+// job type is not one the server supports
+type UnknownJobTypeError struct {
+	// JobType is the rejected job type
+	JobType string
+	// ValidTypes lists the job types the server supports, sorted
+	ValidTypes []string
+}
+
+func (e *UnknownJobTypeError) Error() string {
+	return fmt.Sprintf("unknown job type %q (valid types: %s)", e.JobType, strings.Join(e.ValidTypes, ", "))
+}
+
+// Is makes errors.Is(err, ErrUnknownJobType) report true for an UnknownJobTypeError
+func (e *UnknownJobTypeError) Is(target error) bool {
+	return target == ErrUnknownJobType
+}
+
+// ErrMissingJobStatus is returned when the server sends a job without its status where
+// the status is needed. Waiting helpers keep polling instead.
+var ErrMissingJobStatus = errors.New("unexpected response format: job has no status")
+
+// ErrPaginationUnsupported is returned by JobIterator and ListJobsFiltered when the
+// server sends the same page again instead of the next one, because it does not support
+// the offset query parameter. Only the jobs of the first page could be listed.
+var ErrPaginationUnsupported = errors.New("server does not support paging through jobs")
+
+// ErrRequestTimeout is returned when a single API request outlasts Config.DefaultTimeout.
+// Unlike the context errors it is transient, so IsRetryable reports true for it.
+var ErrRequestTimeout = errors.New("request timed out")
+
+// ErrInvalidWebhook is returned by ParseWebhook for requests that do not carry a job
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Errors wrapped around the context error when a phase of ProcessFileWithOptions runs
+// out of time, to tell a slow upload apart from slow processing
+var (
+	ErrUploadTimeout = errors.New("upload timed out")
+	ErrWaitTimeout   = errors.New("waiting for job timed out")
+)
+
+// ErrJobFailed is returned by the helpers that wait for a job when it ends in the failed
+// state. Helpers that know the failed job return a *JobFailedError, which matches it.
+var ErrJobFailed = errors.New("job failed")
+
+// ErrInputUnavailable is returned by RetryJob when the server no longer holds the input
+// of the job, so it cannot be run again without uploading the input anew
+var ErrInputUnavailable = errors.New("job input no longer available")
+
+// ErrStatusNotReached is returned by WaitForJobStatus when the job ends without reaching
+// the target status
+var ErrStatusNotReached = errors.New("job ended before reaching the target status")
+
+// ErrJobCancelled is returned by the helpers that wait for a job when it was cancelled
+var ErrJobCancelled = errors.New("job cancelled")
+
+// JobFailedError is returned when a job ends in the failed state. With job retries
+// enabled it describes the last attempt, and Codes lists every distinct error code
+// seen across all attempts, in order.
+type JobFailedError struct {
+	// Job is the failed job, nil if it could not be fetched
+	Job *Job
+	// Code and Message are the error reported by the server for the job
+	Code    string
+	Message string
+	// Attempts is how many times the job was run
+	Attempts int
+	// Codes holds the error codes of all attempts
+	Codes []string
+}
+
+func (e *JobFailedError) Error() string {
+	msg := "job failed"
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts", e.Attempts)
+		if len(e.Codes) > 0 {
+			msg += ", error codes: " + strings.Join(e.Codes, ", ")
+		}
+		msg += ")"
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrJobFailed) report true for a JobFailedError
+func (e *JobFailedError) Is(target error) bool {
+	return target == ErrJobFailed
+}
+
+// Error codes the server reports for failed jobs, in Job.ErrorCode and
+// JobFailedError.Code. The server may send codes not listed here.
+const (
+	// ErrorCodeTimeout means the job ran longer than its type allows
+	ErrorCodeTimeout = "timeout"
+	// ErrorCodeOutOfMemory means the job needed more memory than a worker has
+	ErrorCodeOutOfMemory = "out_of_memory"
+	// ErrorCodeInvalidInput means the worker could not process the uploaded data
+	ErrorCodeInvalidInput = "invalid_input"
+	// ErrorCodeUnsupportedType means no worker handles the job type
+	ErrorCodeUnsupportedType = "unsupported_type"
+	// ErrorCodeWorkerEvicted means the worker running the job went away
+	ErrorCodeWorkerEvicted = "worker_evicted"
+	// ErrorCodeInternal means the job failed for reasons unrelated to its input
+	ErrorCodeInternal = "internal_error"
+	// ErrorCodeInputExpired means the server no longer holds the input of the job
+	ErrorCodeInputExpired = "input_expired"
+)
+
+// IsRetryableErrorCode reports whether a job that failed with code may succeed when
+// submitted again: timeouts, evicted workers and internal errors are retryable, while
+// codes that point at the input or the job type, and unknown codes, are not. It can
+// be passed to WithJobRetry as the ErrorCodeRetryPredicate.
+func IsRetryableErrorCode(code string) bool {
+	switch code {
+	case ErrorCodeTimeout, ErrorCodeWorkerEvicted, ErrorCodeInternal:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether repeating the operation that returned err, e.g. with
+// RetryJob for a failed job, might succeed. It is true for jobs that failed with a
+// retryable error code, for API errors with a 5xx, 408 or 429 status, for corrupted
+// transfers and for transient network errors such as reset connections. It is false
+// for cancelled or expired contexts, rejected arguments and anything it does not
+// recognise.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var failed *JobFailedError
+	if errors.As(err, &failed) {
+		return IsRetryableErrorCode(failed.Code)
+	}
+
+	var decompressErr *DecompressError
+	if errors.Is(err, ErrChecksumMismatch) || errors.As(err, &decompressErr) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode == http.StatusTooManyRequests ||
+			IsRetryableErrorCode(apiErr.Code)
+	}
+
+	return isTransientError(err)
+}
+
+// IncompleteJobError is returned when a job was created but uploading its input or
+// submitting it failed. Unless cleanup was disabled with WithCleanupOnError(false),
+// the helper tries to delete the job first; Deleted reports whether that worked,
+// and otherwise JobID identifies the job left behind.
+type IncompleteJobError struct {
+	JobID   JobId
+	Deleted bool
+	Err     error
+}
+
+func (e *IncompleteJobError) Error() string {
+	if e.Deleted {
+		return fmt.Sprintf("%v (job %s deleted)", e.Err, e.JobID)
+	}
+	return fmt.Sprintf("%v (job %s left behind)", e.Err, e.JobID)
+}
+
+func (e *IncompleteJobError) Unwrap() error {
+	return e.Err
+}
+
+// SourceError is returned by ProcessURL when the source could not be fetched. It is
+// separate from APIError so callers can tell a bad source apart from a rejected job.
+type SourceError struct {
+	// URL is the source that was fetched
+	URL string
+	// StatusCode is the status the source answered with, 0 if there was no response
+	StatusCode int
+	// Err is the underlying failure, nil for an unexpected status code
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("failed to fetch source %s: %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("failed to fetch source %s: status %d", e.URL, e.StatusCode)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// DecompressError is returned when compressed job output cannot be decoded, because it
+// is corrupt or not in the encoding the server declared
+type DecompressError struct {
+	// Encoding is the Content-Encoding the output was sent with
+	Encoding string
+	Err      error
+}
+
+func (e *DecompressError) Error() string {
+	return fmt.Sprintf("failed to decompress %s output: %v", e.Encoding, e.Err)
+}
+
+func (e *DecompressError) Unwrap() error {
+	return e.Err
+}
+
+// ErrChecksumMismatch is matched by the *ChecksumMismatchError returned when an upload
+// sent with a checksum arrived corrupted, and by the *OutputChecksumError returned when
+// downloaded output does not match the server's checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumMismatchError is returned by the upload helpers when the SHA-256 of the input,
+// sent with Config.VerifyChecksum or WithUploadChecksum, does not match what the server
+// received: either the server rejected the upload, in which case Err holds its answer,
+// or it accepted it and reported a different checksum in ChecksumHeader.
+type ChecksumMismatchError struct {
+	// Expected is the hex SHA-256 of the input computed by the client
+	Expected string
+	// Actual is the checksum reported by the server, empty when it rejected the upload
+	Actual string
+	// Err is the server's rejection of the upload, or nil
+	Err error
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	if e.Actual != "" {
+		return fmt.Sprintf("upload checksum mismatch: sent %s, server received %s", e.Expected, e.Actual)
+	}
+	return fmt.Sprintf("upload checksum mismatch: server rejected %s: %v", e.Expected, e.Err)
+}
+
+// Is makes errors.Is(err, ErrChecksumMismatch) report true for a ChecksumMismatchError
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+func (e *ChecksumMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// OutputChecksumError is returned when downloaded job output does not match the SHA-256
+// the server sent in ChecksumHeader. It matches ErrChecksumMismatch.
+type OutputChecksumError struct {
+	// Expected is the checksum sent by the server
+	Expected string
+	// Actual is the hex SHA-256 of the output received
+	Actual string
+}
+
+func (e *OutputChecksumError) Error() string {
+	return fmt.Sprintf("output checksum mismatch: server sent %s, received %s", e.Expected, e.Actual)
+}
+
+// Is makes errors.Is(err, ErrChecksumMismatch) report true for an OutputChecksumError
+func (e *OutputChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// APIError is returned when the API answers a request with an unexpected status code.
+// Code, Message and RequestID are read from the JSON error body the server sends; when
+// the body is not JSON it is kept in RawBody instead.
+//
+// With Config.CaptureBodiesOnError set it also carries the start of the request and
+// response bodies and the request headers, with credentials redacted, for debugging
+// rejected requests.
+type APIError struct {
+	// Op describes the failed operation, e.g. "create job"
+	Op string
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Code is the machine-readable error code, if the server sent one
+	Code string
+	// Message is the human-readable error message, if the server sent one
+	Message string
+	// RequestID identifies the request in the server logs, from the X-Request-Id
+	// header or the error body
+	RequestID string
+	// RawBody holds the start of the response body when it could not be parsed as JSON
+	RawBody []byte
+	// RequestHeader holds the headers that were sent, with Authorization redacted
+	RequestHeader http.Header
+	// RequestBody and ResponseBody hold up to the configured number of body bytes
+	RequestBody  []byte
+	ResponseBody []byte
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("failed to %s: status %d", e.Op, e.StatusCode)
+	if e.Code != "" {
+		msg += fmt.Sprintf(" (%s)", e.Code)
+	}
+
+	detail := e.Message
+	if detail == "" {
+		detail = strings.TrimSpace(string(e.RawBody))
+	}
+	if detail != "" {
+		msg += ": " + detail
+	}
+	return msg
+}
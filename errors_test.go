@@ -0,0 +1,197 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIError tests the error returned for rejected requests, with and without body capture
+func TestAPIError(t *testing.T) {
+	detail := `{"error":"unknown parameter 'colour' for type test/linecount"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(detail))
+	}))
+	defer server.Close()
+
+	createJob := func(t *testing.T, capture int) *APIError {
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL, CaptureBodiesOnError: capture})
+		require.NoError(t, err)
+
+		_, err = client.CreateJob(context.Background(), "test/linecount", WithParams(map[string]any{"colour": "red"}))
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr), "error: %v", err)
+		assert.Equal(t, "create job", apiErr.Op)
+		assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+		return apiErr
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		apiErr := createJob(t, 0)
+		assert.Equal(t, "failed to create job: status 422: unknown parameter 'colour' for type test/linecount", apiErr.Error())
+		assert.Equal(t, "unknown parameter 'colour' for type test/linecount", apiErr.Message)
+		assert.Nil(t, apiErr.RawBody)
+		assert.Nil(t, apiErr.RequestHeader)
+		assert.Nil(t, apiErr.RequestBody)
+		assert.Nil(t, apiErr.ResponseBody)
+	})
+
+	t.Run("bodies capped", func(t *testing.T) {
+		apiErr := createJob(t, 1024)
+		assert.Equal(t, detail, string(apiErr.ResponseBody))
+		assert.Contains(t, apiErr.Error(), "unknown parameter 'colour'")
+		assert.Contains(t, string(apiErr.RequestBody), `"colour":"red"`)
+		assert.Equal(t, "REDACTED", apiErr.RequestHeader.Get("Authorization"))
+		assert.Equal(t, "application/json", apiErr.RequestHeader.Get("Content-Type"))
+
+		apiErr = createJob(t, 8)
+		assert.Equal(t, detail[:8], string(apiErr.ResponseBody))
+		assert.Len(t, apiErr.RequestBody, 8)
+	})
+
+	t.Run("raw responses", func(t *testing.T) {
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL, CaptureBodiesOnError: 1024})
+		require.NoError(t, err)
+
+		_, err = client.StreamJobLogs(context.Background(), JobId{}, &strings.Builder{})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, "get job logs", apiErr.Op)
+		assert.Equal(t, detail, string(apiErr.ResponseBody))
+		assert.NotContains(t, apiErr.RequestHeader.Get("Authorization"), "test-api-key")
+	})
+}
+
+// TestParseErrorBody tests reading the error details from the forms of error bodies
+func TestParseErrorBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected APIError
+		message  string
+	}{
+		{
+			name:     "documented form",
+			body:     `{"success":false,"error":"job not found"}`,
+			expected: APIError{Message: "job not found"},
+			message:  "failed to get job: status 404: job not found",
+		},
+		{
+			name:     "nested error object",
+			body:     `{"error":{"code":"invalid_type","message":"unknown job type"},"request_id":"req-1"}`,
+			expected: APIError{Code: "invalid_type", Message: "unknown job type", RequestID: "req-1"},
+			message:  "failed to get job: status 404 (invalid_type): unknown job type",
+		},
+		{
+			name:     "flat fields",
+			body:     `{"code":"quota_exceeded","message":"monthly quota used up"}`,
+			expected: APIError{Code: "quota_exceeded", Message: "monthly quota used up"},
+			message:  "failed to get job: status 404 (quota_exceeded): monthly quota used up",
+		},
+		{
+			name:     "not JSON",
+			body:     "Job not found\n",
+			expected: APIError{RawBody: []byte("Job not found\n")},
+			message:  "failed to get job: status 404: Job not found",
+		},
+		{
+			name:    "empty",
+			body:    "",
+			message: "failed to get job: status 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := APIError{Op: "get job", StatusCode: http.StatusNotFound}
+			parseErrorBody(&apiErr, []byte(tt.body))
+
+			assert.Equal(t, tt.expected.Code, apiErr.Code)
+			assert.Equal(t, tt.expected.Message, apiErr.Message)
+			assert.Equal(t, tt.expected.RequestID, apiErr.RequestID)
+			assert.Equal(t, tt.expected.RawBody, apiErr.RawBody)
+			assert.Equal(t, tt.message, apiErr.Error())
+		})
+	}
+
+	t.Run("request ID header", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req-42")
+			http.Error(w, "Job not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL})
+		require.NoError(t, err)
+
+		_, err = client.WaitForJob(context.Background(), JobId{})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr), "error: %v", err)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "req-42", apiErr.RequestID)
+		assert.Equal(t, "Job not found\n", string(apiErr.RawBody))
+	})
+}
+
+// TestIsRetryable tests telling errors worth retrying apart from permanent ones
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"evicted worker", &JobFailedError{Code: ErrorCodeWorkerEvicted}, true},
+		{"job timeout", fmt.Errorf("job 1: %w", &JobFailedError{Code: ErrorCodeTimeout}), true},
+		{"invalid input", &JobFailedError{Code: ErrorCodeInvalidInput}, false},
+		{"out of memory", &JobFailedError{Code: ErrorCodeOutOfMemory}, false},
+		{"unknown code", &JobFailedError{Code: "something_new"}, false},
+		{"cancelled job", ErrJobCancelled, false},
+		{"service unavailable", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest, Code: "bad_params"}, false},
+		{"retryable code", &APIError{StatusCode: http.StatusConflict, Code: ErrorCodeInternal}, true},
+		{"corrupted upload", &ChecksumMismatchError{Err: &APIError{StatusCode: http.StatusBadRequest}}, true},
+		{"corrupted output", &OutputChecksumError{}, true},
+		{"reset connection", fmt.Errorf("upload: %w", syscall.ECONNRESET), true},
+		{"cancelled context", context.Canceled, false},
+		{"phase timeout", fmt.Errorf("%w: %w", ErrWaitTimeout, context.DeadlineExceeded), false},
+		{"invalid argument", ErrInvalidJobType, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+
+	t.Run("failed job", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted outcomes only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetJobOutcome("test/evicted", JobStatusFailed, ErrorCodeWorkerEvicted, "worker went away")
+		mockServer.SetJobOutcome("test/broken", JobStatusFailed, ErrorCodeInvalidInput, "bad input")
+
+		_, err := client.ProcessString(context.Background(), "test/evicted", "a")
+		require.ErrorIs(t, err, ErrJobFailed)
+		assert.True(t, IsRetryable(err))
+
+		_, err = client.ProcessString(context.Background(), "test/broken", "a")
+		require.ErrorIs(t, err, ErrJobFailed)
+		assert.False(t, IsRetryable(err))
+	})
+}
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sync"
 
 	"github.com/bsubio/bsubio-go"
 )
@@ -36,86 +36,44 @@ func main() {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	ctx := context.Background()
+	// Ctrl-C stops starting new files; jobs already running are allowed to finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	fmt.Printf("Processing %d files with job type: %s\n\n", len(files), jobType)
 
-	// Process files concurrently
-	var wg sync.WaitGroup
-	results := make(chan processingResult, len(files))
-
-	for _, filePath := range files {
-		wg.Add(1)
-		go func(file string) {
-			defer wg.Done()
-			result := processFile(ctx, client, jobType, file)
-			results <- result
-		}(filePath)
-	}
-
-	// Wait for all goroutines to complete
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Collect and display results
 	successful := 0
 	failed := 0
 
-	for result := range results {
-		if result.err != nil {
-			fmt.Printf("[FAILED] %s: %v\n", result.fileName, result.err)
+	// Process files concurrently, reporting each one as soon as it is done
+	processor := client.NewBatchProcessor(jobType, 4)
+	processor.OnResult = func(result bsubio.BatchItemResult) {
+		fileName := filepath.Base(result.Name)
+		if result.Err != nil {
+			fmt.Printf("[FAILED] %s: %v\n", fileName, result.Err)
 			failed++
-		} else {
-			fmt.Printf("[SUCCESS] %s: Job ID %s, Output: %d bytes\n",
-				result.fileName,
-				result.jobID,
-				result.outputSize,
-			)
-			successful++
+			return
+		}
 
-			// Optionally save output
-			outputPath := result.fileName + ".out"
-			if err := os.WriteFile(outputPath, result.output, 0644); err != nil {
-				fmt.Printf("  Warning: Failed to save output to %s: %v\n", outputPath, err)
-			} else {
-				fmt.Printf("  Saved output to: %s\n", outputPath)
-			}
+		fmt.Printf("[SUCCESS] %s: Job ID %s, Output: %d bytes\n",
+			fileName,
+			result.Result.Job.Id,
+			len(result.Result.Output),
+		)
+		successful++
+
+		// Optionally save output
+		outputPath := fileName + ".out"
+		if err := os.WriteFile(outputPath, result.Result.Output, 0644); err != nil {
+			fmt.Printf("  Warning: Failed to save output to %s: %v\n", outputPath, err)
+		} else {
+			fmt.Printf("  Saved output to: %s\n", outputPath)
 		}
 	}
+	processor.Run(ctx, bsubio.FileInputs(files...))
 
 	fmt.Printf("\n=== Summary ===\n")
 	fmt.Printf("Total files: %d\n", len(files))
 	fmt.Printf("Successful: %d\n", successful)
 	fmt.Printf("Failed: %d\n", failed)
 }
-
-type processingResult struct {
-	fileName   string
-	jobID      string
-	output     []byte
-	outputSize int
-	err        error
-}
-
-func processFile(ctx context.Context, client *bsubio.BsubClient, jobType, filePath string) processingResult {
-	fileName := filepath.Base(filePath)
-
-	result := processingResult{
-		fileName: fileName,
-	}
-
-	// Process the file
-	jobResult, err := client.ProcessFile(ctx, jobType, filePath)
-	if err != nil {
-		result.err = err
-		return result
-	}
-
-	result.jobID = jobResult.Job.Id.String()
-	result.output = jobResult.Output
-	result.outputSize = len(jobResult.Output)
-
-	return result
-}
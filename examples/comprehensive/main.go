@@ -29,13 +29,13 @@ func main() {
 
 	// Example 1: Get available processing types
 	fmt.Println("Available processing types:")
-	typesResp, err := client.GetTypesWithResponse(ctx)
+	types, err := client.ListTypes(ctx)
 	if err != nil {
 		log.Fatalf("Failed to get types: %v", err)
 	}
 
-	if typesResp.JSON200 != nil && typesResp.JSON200.Types != nil {
-		for _, procType := range *typesResp.JSON200.Types {
+	for _, procType := range types {
+		if procType.Name != nil && procType.Description != nil {
 			fmt.Printf("  - %s: %s\n", *procType.Name, *procType.Description)
 		}
 	}
@@ -55,7 +55,7 @@ func main() {
 		for _, job := range *listResp.JSON200.Data.Jobs {
 			fmt.Printf("  Job %s: %s (type: %s)\n",
 				*job.Id,
-				*job.Status,
+				job.CurrentStatus(),
 				*job.Type,
 			)
 		}
@@ -43,20 +43,13 @@ func main() {
 
 	// Step 1: Create job
 	fmt.Println("Step 1: Creating job...")
-	createResp, err := client.CreateJobWithResponse(ctx, bsubio.CreateJobJSONRequestBody{
-		Type: jobType,
-	})
+	job, err := client.CreateJob(ctx, jobType)
 	if err != nil {
 		log.Fatalf("Failed to create job: %v", err)
 	}
 
-	if createResp.JSON201 == nil || createResp.JSON201.Data == nil {
-		log.Fatal("Unexpected response from create job")
-	}
-
-	job := createResp.JSON201.Data
-	fmt.Printf("  Job created: %s\n", job.Id)
-	fmt.Printf("  Status: %s\n", *job.Status)
+	fmt.Printf("  Job created: %s\n", *job.Id)
+	fmt.Printf("  Status: %s\n", job.CurrentStatus())
 	fmt.Printf("  Upload token: %s\n\n", *job.UploadToken)
 
 	// Step 2: Upload file
@@ -120,15 +113,16 @@ func main() {
 			}
 
 			currentJob := jobResp.JSON200.Data
-			fmt.Printf("  Status: %s", *currentJob.Status)
+			status := currentJob.CurrentStatus()
+			fmt.Printf("  Status: %s", status)
 
-			if currentJob.ClaimedBy != nil {
-				fmt.Printf(" (claimed by: %s)", *currentJob.ClaimedBy)
+			if worker, ok := currentJob.Worker(); ok {
+				fmt.Printf(" (claimed by: %s)", worker)
 			}
 			fmt.Println()
 
 			// Check if job is finished
-			if *currentJob.Status == bsubio.JobStatusFinished || *currentJob.Status == bsubio.JobStatusFailed {
+			if status == bsubio.JobStatusFinished || status == bsubio.JobStatusFailed {
 				finishedJob = currentJob
 				goto done
 			}
@@ -139,7 +133,7 @@ done:
 	fmt.Println()
 
 	// Step 5: Retrieve results
-	if finishedJob.Status != nil && *finishedJob.Status == bsubio.JobStatusFailed {
+	if finishedJob.CurrentStatus() == bsubio.JobStatusFailed {
 		fmt.Println("Step 5: Job failed!")
 		if finishedJob.ErrorCode != nil {
 			fmt.Printf("  Error code: %s\n", *finishedJob.ErrorCode)
@@ -0,0 +1,502 @@
+package bsubio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeClient is a BsubClient backed by an in-memory fake of the API instead of a server,
+// for testing code that uses the SDK. Requests never leave the process, and every helper,
+// such as Process, ProcessFile or WaitForJob, runs the same code as against the real API.
+//
+// Only job types registered with one of the Set methods exist; creating a job of another
+// type fails with a 400 *APIError. Submitted jobs end at once, so waiting for them does
+// not sleep; to exercise timeouts and retries in the code under test, add latency to
+// requests with SetDelay. The jobs submitted so far are recorded for assertions, see
+// Submissions. A FakeClient is safe for concurrent use.
+type FakeClient struct {
+	*BsubClient
+	backend *fakeBackend
+}
+
+// FakeSubmission is a job submitted to a FakeClient, as returned by Submissions
+type FakeSubmission struct {
+	JobID   JobId
+	JobType string
+	// Params and Metadata are those the job was created with, see WithParams and WithMetadata
+	Params   map[string]any
+	Metadata map[string]string
+	// Priority is the priority the job was created with, see WithPriority
+	Priority JobPriority
+	// CallbackURL is the callback URL the job was created with, see WithCallbackURL.
+	// The fake does not post to it.
+	CallbackURL string
+	// Filename and ContentType are those the input was uploaded with
+	Filename    string
+	ContentType string
+	// Input is the uploaded input
+	Input []byte
+}
+
+// fakePollInterval is how often a FakeClient polls jobs that have not ended, such as
+// created jobs that were never submitted
+const fakePollInterval = 10 * time.Millisecond
+
+// NewFakeClient returns a FakeClient without any job types
+func NewFakeClient() *FakeClient {
+	backend := &fakeBackend{
+		types:  make(map[string]*fakeJobType),
+		jobs:   make(map[JobId]*fakeJob),
+		delays: make(map[string]time.Duration),
+	}
+
+	client, err := NewBsubClient(Config{
+		APIKey:     "fake-api-key",
+		BaseURL:    "http://bsubio.fake",
+		HTTPClient: &http.Client{Transport: backend},
+	})
+	if err != nil {
+		panic("bsubio: failed to create fake client: " + err.Error())
+	}
+	client.pollInterval = fakePollInterval
+
+	return &FakeClient{BsubClient: client, backend: backend}
+}
+
+// SetOutput registers jobType with a fixed output for all its jobs
+func (f *FakeClient) SetOutput(jobType string, output []byte) {
+	output = bytes.Clone(output)
+	f.SetOutputFunc(jobType, func([]byte) []byte { return output })
+}
+
+// SetOutputFunc registers jobType with an output computed from each job's input
+func (f *FakeClient) SetOutputFunc(jobType string, fn func(input []byte) []byte) {
+	f.backend.setType(jobType, func(t *fakeJobType) {
+		t.output = fn
+		t.errorCode, t.errorMessage = "", ""
+	})
+}
+
+// SetFailure registers jobType with jobs that fail with the given error code and
+// message, which Process reports as a *JobFailedError
+func (f *FakeClient) SetFailure(jobType, code, message string) {
+	f.backend.setType(jobType, func(t *fakeJobType) {
+		t.output = nil
+		t.errorCode, t.errorMessage = code, message
+	})
+}
+
+// SetLogs sets the logs of the jobs of jobType, registering it with an empty output if
+// it is not registered yet
+func (f *FakeClient) SetLogs(jobType, logs string) {
+	f.backend.setType(jobType, func(t *fakeJobType) {
+		t.logs = logs
+	})
+}
+
+// SetDelay makes the fake wait d before answering requests whose path contains
+// operation, like MockServer.SetDelay in this package's tests: "/v1/upload/" for
+// uploads, "/submit" for submits, "/output" for output downloads or "/v1/jobs/" for
+// every job endpoint. When several operations match, the longest delay applies. A
+// request whose context ends during the delay fails with the context error, as it
+// would against a slow server. A zero d removes the delay.
+func (f *FakeClient) SetDelay(operation string, d time.Duration) {
+	f.backend.mu.Lock()
+	defer f.backend.mu.Unlock()
+	if d == 0 {
+		delete(f.backend.delays, operation)
+		return
+	}
+	f.backend.delays[operation] = d
+}
+
+// Submissions returns the jobs submitted so far, in order
+func (f *FakeClient) Submissions() []FakeSubmission {
+	f.backend.mu.Lock()
+	defer f.backend.mu.Unlock()
+	return slices.Clone(f.backend.submissions)
+}
+
+// fakeJobType is what a FakeClient does with the jobs of one type
+type fakeJobType struct {
+	output       func([]byte) []byte
+	errorCode    string
+	errorMessage string
+	logs         string
+}
+
+// fakeJob is a job held by a fakeBackend
+type fakeJob struct {
+	job         Job
+	request     createJobRequest
+	input       []byte
+	filename    string
+	contentType string
+	output      []byte
+	logs        string
+}
+
+// fakeBackend serves the API from memory as the transport of a FakeClient
+type fakeBackend struct {
+	mu          sync.Mutex
+	types       map[string]*fakeJobType
+	jobs        map[JobId]*fakeJob
+	submissions []FakeSubmission
+	delays      map[string]time.Duration // Latency added to requests, by path substring
+}
+
+func (b *fakeBackend) setType(jobType string, set func(*fakeJobType)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.types[jobType]
+	if t == nil {
+		t = &fakeJobType{}
+		b.types[jobType] = t
+	}
+	set(t)
+}
+
+// RoundTrip answers req like the API would
+func (b *fakeBackend) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	if err := b.delay(req); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v1/")
+	if path == req.URL.Path {
+		return fakeError(req, http.StatusNotFound, "not found"), nil
+	}
+
+	switch {
+	case path == "jobs" && req.Method == http.MethodPost:
+		return b.createJob(req), nil
+	case path == "jobs" && req.Method == http.MethodGet:
+		return b.listJobs(req), nil
+	case path == "types" && req.Method == http.MethodGet:
+		return b.listTypes(req), nil
+	}
+
+	resource, rest, _ := strings.Cut(path, "/")
+	id, action, _ := strings.Cut(rest, "/")
+	jobID, err := uuid.Parse(id)
+	if err != nil || (resource != "jobs" && resource != "upload") {
+		return fakeError(req, http.StatusNotFound, "not found"), nil
+	}
+	job := b.jobs[jobID]
+	if job == nil {
+		return fakeError(req, http.StatusNotFound, "job not found"), nil
+	}
+
+	switch {
+	case resource == "upload" && req.Method == http.MethodPost:
+		return b.upload(req, job), nil
+	case resource == "upload":
+		// Resumable uploads are not supported, so clients fall back to single requests
+		return fakeError(req, http.StatusMethodNotAllowed, "method not allowed"), nil
+	case action == "" && req.Method == http.MethodGet:
+		return fakeJSON(req, http.StatusOK, map[string]any{"data": job.job, "success": true}), nil
+	case action == "" && req.Method == http.MethodDelete:
+		return b.deleteJob(req, job), nil
+	case action == "submit" && req.Method == http.MethodPost:
+		return b.submit(req, job), nil
+	case action == "cancel" && req.Method == http.MethodPost:
+		return b.cancel(req, job), nil
+	case action == "output" && (req.Method == http.MethodGet || req.Method == http.MethodHead):
+		if job.job.CurrentStatus() != JobStatusFinished {
+			return fakeError(req, http.StatusNotFound, "output not available"), nil
+		}
+		return fakeResponse(req, http.StatusOK, "application/octet-stream", job.output), nil
+	case action == "logs" && req.Method == http.MethodGet:
+		if !isTerminal(job.job.Status) || job.logs == "" {
+			return fakeError(req, http.StatusNotFound, "logs not available"), nil
+		}
+		return fakeResponse(req, http.StatusOK, "text/plain; charset=utf-8", []byte(job.logs)), nil
+	}
+
+	return fakeError(req, http.StatusNotFound, "not found"), nil
+}
+
+// delay waits for the longest delay set for the path of req, or until its context ends
+func (b *fakeBackend) delay(req *http.Request) error {
+	b.mu.Lock()
+	var d time.Duration
+	for op, opDelay := range b.delays {
+		if strings.Contains(req.URL.Path, op) {
+			d = max(d, opDelay)
+		}
+	}
+	b.mu.Unlock()
+	if d == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func (b *fakeBackend) createJob(req *http.Request) *http.Response {
+	var body createJobRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return fakeError(req, http.StatusBadRequest, "invalid request body")
+	}
+	if _, ok := b.types[body.Type]; !ok {
+		return fakeError(req, http.StatusBadRequest, "unknown job type "+strconv.Quote(body.Type))
+	}
+
+	id := uuid.New()
+	token := uuid.NewString()
+	now := time.Now()
+	status := JobStatusCreated
+	var size int64
+
+	job := &fakeJob{
+		job: Job{
+			Id:          &id,
+			Type:        &body.Type,
+			Status:      &status,
+			CreatedAt:   &now,
+			UpdatedAt:   &now,
+			UploadToken: &token,
+			DataSize:    &size,
+		},
+		request: body,
+	}
+	b.jobs[id] = job
+
+	// Inline data is accepted, which submits the job right away
+	if body.Data != nil {
+		status, size = JobStatusLoaded, int64(len(body.Data))
+		job.input = body.Data
+		b.run(job)
+	}
+
+	// The job is echoed with its priority and metadata, which the Job type does not model
+	created := struct {
+		Job
+		Priority JobPriority       `json:"priority,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}{job.job, body.Priority, body.Metadata}
+	return fakeJSON(req, http.StatusCreated, map[string]any{"data": created, "success": true})
+}
+
+func (b *fakeBackend) upload(req *http.Request, job *fakeJob) *http.Response {
+	if job.job.UploadToken == nil || req.URL.Query().Get("token") != *job.job.UploadToken {
+		return fakeError(req, http.StatusUnauthorized, "invalid upload token")
+	}
+	if job.job.CurrentStatus().AtLeast(JobStatusPending) {
+		return fakeError(req, http.StatusConflict, "job already submitted")
+	}
+
+	var source io.Reader = req.Body
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(source)
+		if err != nil {
+			return fakeError(req, http.StatusBadRequest, "invalid gzip body")
+		}
+		defer zr.Close()
+		source = zr
+	}
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return fakeError(req, http.StatusBadRequest, "expected a multipart upload")
+	}
+	part, err := multipart.NewReader(source, params["boundary"]).NextPart()
+	if err != nil {
+		return fakeError(req, http.StatusBadRequest, "missing file part")
+	}
+
+	input, err := io.ReadAll(part)
+	if err != nil {
+		return fakeError(req, http.StatusBadRequest, "failed to read upload")
+	}
+
+	size := int64(len(input))
+	status := JobStatusLoaded
+	job.input = input
+	job.filename = part.FileName()
+	job.contentType = part.Header.Get("Content-Type")
+	job.job.DataSize = &size
+	job.job.Status = &status
+
+	return fakeJSON(req, http.StatusOK, map[string]any{"data_size": size, "message": "Upload successful"})
+}
+
+func (b *fakeBackend) submit(req *http.Request, job *fakeJob) *http.Response {
+	// Failed jobs may be submitted again, see RetryJob
+	if status := job.job.CurrentStatus(); status != JobStatusLoaded && status != JobStatusFailed {
+		return fakeError(req, http.StatusConflict, "job has no input or was already submitted")
+	}
+	job.job.ErrorCode, job.job.ErrorMessage = nil, nil
+
+	b.run(job)
+
+	return fakeJSON(req, http.StatusOK, map[string]any{"success": true, "message": "Job submitted successfully"})
+}
+
+// run processes a submitted job at once, recording its submission
+func (b *fakeBackend) run(job *fakeJob) {
+	jobType := b.types[*job.job.Type]
+	now := time.Now()
+	status := JobStatusFinished
+	if code, message := jobType.errorCode, jobType.errorMessage; code != "" || message != "" {
+		status = JobStatusFailed
+		job.job.ErrorCode, job.job.ErrorMessage = &code, &message
+	} else if jobType.output != nil {
+		job.output = jobType.output(bytes.Clone(job.input))
+	}
+	job.logs = jobType.logs
+	job.job.Status = &status
+	job.job.UpdatedAt = &now
+	job.job.FinishedAt = &now
+	job.job.UploadToken = nil
+
+	b.submissions = append(b.submissions, FakeSubmission{
+		JobID:       *job.job.Id,
+		JobType:     *job.job.Type,
+		Params:      maps.Clone(job.request.Params),
+		Metadata:    maps.Clone(job.request.Metadata),
+		Priority:    job.request.Priority,
+		CallbackURL: job.request.CallbackURL,
+		Filename:    job.filename,
+		ContentType: job.contentType,
+		Input:       job.input,
+	})
+}
+
+func (b *fakeBackend) cancel(req *http.Request, job *fakeJob) *http.Response {
+	if isTerminal(job.job.Status) {
+		return fakeError(req, http.StatusBadRequest, "job already ended")
+	}
+
+	now := time.Now()
+	status := JobStatusCancelled
+	job.job.Status = &status
+	job.job.UpdatedAt = &now
+	job.job.FinishedAt = &now
+	return fakeJSON(req, http.StatusOK, map[string]any{"success": true, "message": "job cancelled"})
+}
+
+func (b *fakeBackend) deleteJob(req *http.Request, job *fakeJob) *http.Response {
+	delete(b.jobs, *job.job.Id)
+	return fakeResponse(req, http.StatusNoContent, "", nil)
+}
+
+func (b *fakeBackend) listJobs(req *http.Request) *http.Response {
+	query := req.URL.Query()
+
+	// Jobs are listed with their metadata, which the Job type does not model
+	type listedJob struct {
+		Job
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}
+	var jobs []listedJob
+	for _, job := range b.jobs {
+		if status := query.Get("status"); status != "" && string(job.job.CurrentStatus()) != status {
+			continue
+		}
+		jobs = append(jobs, listedJob{Job: job.job, Metadata: job.request.Metadata})
+	}
+
+	// Newest first, with the ID as a tie breaker so pages are stable
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(*jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(*jobs[j].CreatedAt)
+		}
+		return jobs[i].Id.String() < jobs[j].Id.String()
+	})
+
+	total := len(jobs)
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	jobs = jobs[min(max(offset, 0), len(jobs)):]
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 && limit < len(jobs) {
+		jobs = jobs[:limit]
+	}
+	if jobs == nil {
+		jobs = []listedJob{}
+	}
+
+	return fakeJSON(req, http.StatusOK, map[string]any{
+		"data":    map[string]any{"jobs": jobs, "total": total},
+		"success": true,
+	})
+}
+
+func (b *fakeBackend) listTypes(req *http.Request) *http.Response {
+	names := slices.Sorted(maps.Keys(b.types))
+	types := make([]ProcessingType, len(names))
+	for i, name := range names {
+		types[i] = ProcessingType{Type: &name}
+	}
+	return fakeJSON(req, http.StatusOK, map[string]any{"types": types})
+}
+
+// fakeResponse builds a response to req with the given body
+func fakeResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	header := make(http.Header)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	length := int64(len(body))
+	if req.Method == http.MethodHead {
+		body = nil
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: length,
+		Request:       req,
+	}
+}
+
+// fakeJSON builds a JSON response to req
+func fakeJSON(req *http.Request, status int, v any) *http.Response {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fakeError(req, http.StatusInternalServerError, err.Error())
+	}
+	return fakeResponse(req, status, "application/json", body)
+}
+
+// fakeError builds a JSON error response to req
+func fakeError(req *http.Request, status int, message string) *http.Response {
+	body, _ := json.Marshal(map[string]any{"success": false, "error": message})
+	return fakeResponse(req, status, "application/json", body)
+}
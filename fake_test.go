@@ -0,0 +1,129 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFakeClient tests the in-memory fake offered to SDK users
+func TestFakeClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("canned outputs", func(t *testing.T) {
+		fake := NewFakeClient()
+		fake.SetOutput("text/summary", []byte("short"))
+		fake.SetOutputFunc("text/upper", bytes.ToUpper)
+		fake.SetLogs("text/upper", "uppercased\n")
+
+		// Code under test only sees the JobClient interface
+		var client JobClient = fake
+
+		result, err := client.ProcessString(ctx, "text/summary", "a long text", WithParams(map[string]any{"words": 1}), WithPriority(PriorityLow))
+		require.NoError(t, err)
+		assert.Equal(t, "short", result.OutputString())
+
+		path := filepath.Join(t.TempDir(), "input.txt")
+		require.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+		result, err = client.ProcessFile(ctx, "text/upper", path, WithMetadata(map[string]string{"source": "test"}))
+		require.NoError(t, err)
+		assert.Equal(t, "HELLO", result.OutputString())
+		assert.Equal(t, "uppercased\n", result.Logs)
+
+		submissions := fake.Submissions()
+		require.Len(t, submissions, 2)
+		assert.Equal(t, "text/summary", submissions[0].JobType)
+		assert.Equal(t, []byte("a long text"), submissions[0].Input)
+		assert.Equal(t, "input.txt", submissions[0].Filename)
+		assert.EqualValues(t, 1, submissions[0].Params["words"])
+		assert.Equal(t, PriorityLow, submissions[0].Priority)
+		assert.Empty(t, submissions[1].Priority)
+		assert.Equal(t, *result.Job.Id, submissions[1].JobID)
+		assert.Equal(t, []byte("hello"), submissions[1].Input)
+		assert.Equal(t, map[string]string{"source": "test"}, submissions[1].Metadata)
+
+		// Inline data is accepted and submits the job at once
+		job, err := client.CreateJobWithData(ctx, "text/upper", []byte("hi"))
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *job.Status)
+		assert.Equal(t, []byte("hi"), fake.Submissions()[2].Input)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		fake := NewFakeClient()
+		fake.SetFailure("text/broken", "invalid_input", "cannot parse input")
+
+		result, err := fake.Process(ctx, "text/broken", strings.NewReader("data"))
+		var failed *JobFailedError
+		require.ErrorAs(t, err, &failed)
+		assert.Equal(t, "invalid_input", failed.Code)
+		require.NotNil(t, result)
+		assert.Equal(t, JobStatusFailed, *result.Job.Status)
+
+		_, err = fake.Process(ctx, "text/unknown", strings.NewReader("data"))
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 400, apiErr.StatusCode)
+		assert.Len(t, fake.Submissions(), 1)
+	})
+
+	t.Run("job management", func(t *testing.T) {
+		fake := NewFakeClient()
+		fake.SetOutput("text/summary", []byte("short"))
+
+		created, err := fake.CreateJob(ctx, "text/summary")
+		require.NoError(t, err)
+		job, err := fake.CreateAndSubmitJob(ctx, "text/summary", strings.NewReader("data"))
+		require.NoError(t, err)
+
+		active, err := fake.ListActiveJobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, active, 1)
+		assert.Equal(t, *created.Id, *active[0].Id)
+
+		jobs, err := fake.WaitForAll(ctx, []JobId{*job.Id})
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *jobs[*job.Id].Status)
+
+		require.NoError(t, fake.CancelJob(ctx, *created.Id))
+		status, err := fake.GetJobStatus(ctx, *created.Id)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCancelled, status)
+
+		require.NoError(t, fake.DeleteJob(ctx, *created.Id))
+		_, err = fake.GetJobStatus(ctx, *created.Id)
+		assert.Error(t, err)
+
+		types, err := fake.ListTypes(ctx)
+		require.NoError(t, err)
+		require.Len(t, types, 1)
+		assert.Equal(t, "text/summary", *types[0].Type)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		fake := NewFakeClient()
+		fake.SetOutput("text/summary", []byte("short"))
+		fake.SetDelay("/submit", time.Second)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := fake.ProcessString(timeoutCtx, "text/summary", "a long text")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, fake.Submissions())
+
+		fake.SetDelay("/submit", 0)
+		fake.SetDelay("/v1/jobs/", 20*time.Millisecond)
+		start := time.Now()
+		result, err := fake.ProcessString(ctx, "text/summary", "a long text")
+		require.NoError(t, err)
+		assert.Equal(t, "short", result.OutputString())
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+}
@@ -0,0 +1,84 @@
+package bsubio
+
+import (
+	"context"
+	"io"
+)
+
+// JobClient is the set of high-level job helpers implemented by BsubClient.
+//
+// Code that depends on JobClient instead of *BsubClient can be unit tested with an
+// in-memory fake, without an HTTP server, such as the one returned by NewFakeClient.
+// The generated low-level API methods are deliberately left out; use
+// ClientWithResponsesInterface for those.
+//
+// A few helpers are left out on purpose too. IterateJobs, StartUpload and
+// NewBatchProcessor return a *JobIterator, *UploadSession or *BatchProcessor that makes
+// its requests through the *BsubClient that created it, so a fake could not provide
+// them; ListJobsFiltered, ResumableUpload and ProcessMany cover the same ground. And
+// SetDefaultParams, Stats and ResetStats configure or inspect the client itself rather
+// than any job.
+type JobClient interface {
+	// Submitting jobs
+	CreateJob(ctx context.Context, jobType string, opts ...CallOption) (*Job, error)
+	CreateAndSubmitJob(ctx context.Context, jobType string, data io.Reader, opts ...CallOption) (*Job, error)
+	CreateAndSubmitJobWithCallback(ctx context.Context, jobType string, data io.Reader, callbackURL string, opts ...CallOption) (*Job, error)
+	CreateJobWithData(ctx context.Context, jobType string, data []byte, opts ...CallOption) (*Job, error)
+	CreateAndSubmitJobFromFile(ctx context.Context, jobType string, filePath string, opts ...CallOption) (*Job, error)
+	CreateAndSubmitJobFromFileWithProgress(ctx context.Context, jobType string, filePath string, onProgress func(bytesSent, totalBytes int64), opts ...CallOption) (*Job, error)
+	CreateAndSubmitJobFromReaderAt(ctx context.Context, jobType string, r io.ReaderAt, size int64, opts ...CallOption) (*Job, error)
+	CreateAndSubmitJobFromFileResumable(ctx context.Context, jobType string, filePath string, opts ...CallOption) (*Job, error)
+	ResumableUpload(ctx context.Context, jobID JobId, r io.ReaderAt, size int64) error
+
+	// Following jobs
+	GetJobStatus(ctx context.Context, jobID JobId) (JobStatus, error)
+	WaitForJob(ctx context.Context, jobID JobId) (*Job, error)
+	WaitForJobWithCallback(ctx context.Context, jobID JobId, onPoll func(*Job)) (*Job, error)
+	WaitForJobStatus(ctx context.Context, jobID JobId, target JobStatus) (*Job, error)
+	WaitForJobResult(ctx context.Context, jobID JobId, opts ...CallOption) (*JobResult, error)
+	WaitForJobWithOptions(ctx context.Context, jobID JobId, opts WaitForJobOptions) (*Job, error)
+	WaitForJobEvents(ctx context.Context, jobID JobId) (<-chan JobEvent, <-chan error)
+	WatchJobs(ctx context.Context, ids []JobId) (<-chan JobEvent, <-chan map[JobId]*Job)
+	WaitForAll(ctx context.Context, ids []JobId) (map[JobId]*Job, error)
+	IsOutputReady(ctx context.Context, jobID JobId) (bool, error)
+	WaitForOutput(ctx context.Context, jobID JobId) error
+	ListActiveJobs(ctx context.Context) ([]Job, error)
+	ListJobsFiltered(ctx context.Context, filter ListJobsFilter) ([]Job, error)
+	CancelJob(ctx context.Context, jobID JobId) error
+	RetryJob(ctx context.Context, jobID JobId) (*Job, error)
+	DeleteJob(ctx context.Context, jobID JobId) error
+
+	// Retrieving results
+	GetJobResult(ctx context.Context, jobID JobId, opts ...CallOption) (*JobResult, error)
+	GetJobResultStream(ctx context.Context, jobID JobId, opts ...CallOption) (*JobResultStream, error)
+	GetJobOutputTo(ctx context.Context, jobID JobId, w io.Writer, opts ...CallOption) (int64, error)
+	StreamJobArtifact(ctx context.Context, jobID JobId, w io.Writer) error
+	StreamJobLogs(ctx context.Context, jobID JobId, w io.Writer) (int64, error)
+	FollowJobLogs(ctx context.Context, jobID JobId) (io.ReadCloser, error)
+	GetJobLogsString(ctx context.Context, jobID JobId) (string, error)
+	IterateJobLogs(ctx context.Context, jobID JobId) (*LogLineIterator, error)
+
+	// End-to-end processing
+	Process(ctx context.Context, jobType string, data io.Reader, opts ...CallOption) (*JobResult, error)
+	ProcessBytes(ctx context.Context, jobType string, data []byte, opts ...CallOption) (*JobResult, error)
+	ProcessString(ctx context.Context, jobType string, s string, opts ...CallOption) (*JobResult, error)
+	ProcessFile(ctx context.Context, jobType string, filePath string, opts ...CallOption) (*JobResult, error)
+	ProcessFileWithOptions(ctx context.Context, jobType string, filePath string, phases ProcessOptions, opts ...CallOption) (*JobResult, error)
+	ProcessFileEvents(ctx context.Context, jobType string, filePath string, opts ...CallOption) <-chan ProcessEvent
+	ProcessFileToFile(ctx context.Context, jobType string, inputPath string, outputPath string, opts ...CallOption) (*Job, error)
+	ProcessURL(ctx context.Context, jobType string, sourceURL string, opts ...CallOption) (*JobResult, error)
+	ProcessStreaming(ctx context.Context, jobType string, in io.Reader, onChunk func([]byte) error, opts ...CallOption) (*Job, error)
+	ProcessFileMulti(ctx context.Context, filePath string, jobTypes []string, opts ...CallOption) (map[string]*JobResult, error)
+	ProcessBatch(ctx context.Context, jobType string, filePaths []string, opts BatchOptions, callOpts ...CallOption) []BatchResult
+	ProcessMany(ctx context.Context, jobType string, inputs []io.Reader, concurrency int, opts ...CallOption) ([]*JobResult, []error)
+
+	// Discovery
+	Ping(ctx context.Context) error
+	ListTypes(ctx context.Context) ([]ProcessingType, error)
+	GetTypesCached(ctx context.Context) ([]ProcessingType, error)
+	RefreshTypes(ctx context.Context) ([]ProcessingType, error)
+	ValidateJobType(ctx context.Context, jobType string) (bool, error)
+}
+
+// BsubClient is the concrete JobClient
+var _ JobClient = (*BsubClient)(nil)
@@ -0,0 +1,50 @@
+package bsubio
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJobClient is an in-memory JobClient that only implements Process;
+// calling any other method panics through the nil embedded interface
+type fakeJobClient struct {
+	JobClient
+	output []byte
+}
+
+func (f *fakeJobClient) Process(ctx context.Context, jobType string, data io.Reader, opts ...CallOption) (*JobResult, error) {
+	status := JobStatusFinished
+	return &JobResult{Job: &Job{Type: &jobType, Status: &status}, Output: f.output}, nil
+}
+
+// countLines is code under test that only depends on the JobClient interface
+func countLines(ctx context.Context, client JobClient, text string) (string, error) {
+	result, err := client.Process(ctx, "test/linecount", strings.NewReader(text))
+	if err != nil {
+		return "", err
+	}
+	return string(result.Output), nil
+}
+
+// TestJobClient tests that helpers written against JobClient work with both a fake and BsubClient
+func TestJobClient(t *testing.T) {
+	t.Run("fake", func(t *testing.T) {
+		lines, err := countLines(context.Background(), &fakeJobClient{output: []byte("42")}, "ignored")
+		require.NoError(t, err)
+		assert.Equal(t, "42", lines)
+	})
+
+	t.Run("bsub client", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		lines, err := countLines(context.Background(), client, "a\nb\nc")
+		require.NoError(t, err)
+		assert.Equal(t, "3", strings.TrimSpace(lines))
+	})
+}
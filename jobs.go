@@ -0,0 +1,335 @@
+package bsubio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// CreateJob creates a job of jobType without uploading or submitting anything, and
+// returns it with the ID and upload token needed for the next steps. It is the first
+// step of a custom workflow; CreateAndSubmitJob does all of them at once.
+//
+// It takes the place of the generated CreateJob method on BsubClient, which remains
+// available as c.ClientWithResponses.CreateJob.
+func (c *BsubClient) CreateJob(ctx context.Context, jobType string, opts ...CallOption) (*Job, error) {
+	jobType = jobTypeFromContext(ctx, jobType)
+	if err := validateJobType(jobType); err != nil {
+		return nil, err
+	}
+
+	return c.createJob(ctx, jobType, nil, newCallOptions(opts))
+}
+
+// CancelJob stops a job that has not reached a terminal state yet. The job ends in
+// the cancelled state, which WaitForJob treats as terminal.
+//
+// It takes the place of the generated CancelJob method on BsubClient, which remains
+// available as c.ClientWithResponses.CancelJob.
+func (c *BsubClient) CancelJob(ctx context.Context, jobID JobId) error {
+	resp, err := c.CancelJobWithResponse(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	default:
+		return c.apiError("cancel job", resp.HTTPResponse, resp.Body)
+	}
+}
+
+// RetryJob submits a failed job again, to be run on the input it was uploaded with, and
+// returns the job as it is after the submit, to be followed with WaitForJob as usual.
+// Only failed jobs can be retried. When the server no longer holds the input, the error
+// matches ErrInputUnavailable and the input has to be uploaded again in a new job.
+func (c *BsubClient) RetryJob(ctx context.Context, jobID JobId) (*Job, error) {
+	job, err := c.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if status := job.CurrentStatus(); status != JobStatusFailed {
+		return nil, fmt.Errorf("cannot retry job %s: only failed jobs can be retried, it is %s", jobID, status)
+	}
+
+	if err := c.submitJob(ctx, jobID); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusGone || apiErr.Code == ErrorCodeInputExpired) {
+			return nil, fmt.Errorf("%w for job %s, create a new job and upload it again: %w", ErrInputUnavailable, jobID, err)
+		}
+		return nil, err
+	}
+
+	return c.getJob(ctx, jobID)
+}
+
+// DeleteJob removes a job along with its input and output. Jobs that are being
+// processed cannot be deleted; cancel them first.
+//
+// It takes the place of the generated DeleteJob method on BsubClient, which remains
+// available as c.ClientWithResponses.DeleteJob.
+func (c *BsubClient) DeleteJob(ctx context.Context, jobID JobId) error {
+	resp, err := c.DeleteJobWithResponse(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	default:
+		return c.apiError("delete job", resp.HTTPResponse, resp.Body)
+	}
+}
+
+// ListActiveJobs returns the jobs that have not reached a terminal state yet, walking
+// all pages of the job list like IterateJobs. When there are none it returns an empty
+// slice and a nil error. Servers that cannot page only list the first page: its
+// active jobs are returned with ErrPaginationUnsupported, as in ListJobsFiltered.
+func (c *BsubClient) ListActiveJobs(ctx context.Context) ([]Job, error) {
+	active := []Job{}
+	it := c.IterateJobs(ctx, ListJobsFilter{})
+	for it.Next(ctx) {
+		if job := it.Job(); !isTerminal(job.Status) {
+			active = append(active, *job)
+		}
+	}
+	if err := it.Err(); err != nil {
+		if errors.Is(err, ErrPaginationUnsupported) {
+			return active, err
+		}
+		return nil, err
+	}
+
+	return active, nil
+}
+
+// defaultJobPageSize is how many jobs JobIterator requests per page by default
+const defaultJobPageSize = 100
+
+// ListJobsFilter selects the jobs returned by ListJobsFiltered and IterateJobs.
+// Empty fields match every job.
+type ListJobsFilter struct {
+	// Status only includes jobs in that status. It is filtered by the server.
+	Status JobStatus
+	// Type only includes jobs of that job type. The API does not document filtering by
+	// type, so it is sent as a type query parameter for servers that support it and
+	// also applied to every returned page on the client.
+	Type string
+	// Metadata only includes jobs tagged with all of these key/value pairs, see
+	// WithMetadata. Each pair is sent as a metadata.<key> query parameter for servers
+	// that filter by it, and also checked against the metadata of every returned job.
+	Metadata map[string]string
+	// PageSize is how many jobs are requested at once (defaults to 100). The API only
+	// documents the limit parameter, not an offset, so on servers that follow it only
+	// the first PageSize jobs can be listed; see JobIterator.
+	PageSize int
+}
+
+// ListJobsFiltered returns every job matching filter, newest first, walking all pages.
+// When there are none it returns an empty slice and a nil error. When a page cannot be
+// fetched, the jobs listed so far are returned with the error, which is
+// ErrPaginationUnsupported for servers that cannot page, see JobIterator.
+func (c *BsubClient) ListJobsFiltered(ctx context.Context, filter ListJobsFilter) ([]Job, error) {
+	jobs := []Job{}
+	it := c.IterateJobs(ctx, filter)
+	for it.Next(ctx) {
+		jobs = append(jobs, *it.Job())
+	}
+	return jobs, it.Err()
+}
+
+// matches reports whether job, tagged with metadata, passes the filter
+func (f ListJobsFilter) matches(job *Job, metadata map[string]string) bool {
+	if f.Status != "" && job.CurrentStatus() != f.Status {
+		return false
+	}
+	if f.Type != "" && (job.Type == nil || *job.Type != f.Type) {
+		return false
+	}
+	for k, v := range f.Metadata {
+		if value, ok := metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// JobIterator walks all jobs matching a filter, fetching them a page at a time, newest
+// first. Use it like a scanner:
+//
+//	it := client.IterateJobs(ctx, filter)
+//	for it.Next(ctx) {
+//		job := it.Job()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Pages are requested with the limit parameter and an offset query parameter. The API
+// does not document the offset, so only the first page is reliable: a server that
+// ignores it, and so sends the first page again, makes the iteration fail with
+// ErrPaginationUnsupported after the first page. Raise ListJobsFilter.PageSize to list
+// more jobs from such servers. Jobs that move between pages while iterating, because
+// new jobs were created, are only returned once.
+//
+// A JobIterator is not safe for concurrent use.
+type JobIterator struct {
+	client *BsubClient
+	ctx    context.Context
+	filter ListJobsFilter
+
+	page     []Job
+	metadata []map[string]string // Metadata of the jobs in page, by index
+	pos      int
+	offset   int
+	seen     map[JobId]bool
+	done     bool
+
+	job         *Job
+	jobMetadata map[string]string
+	err         error
+}
+
+// IterateJobs returns an iterator over the jobs matching filter. No request is made
+// until the first call to Next. ctx bounds the whole iteration, while the context
+// passed to Next bounds that call; a page is only fetched while both are live.
+func (c *BsubClient) IterateJobs(ctx context.Context, filter ListJobsFilter) *JobIterator {
+	if filter.PageSize <= 0 {
+		filter.PageSize = defaultJobPageSize
+	}
+	return &JobIterator{client: c, ctx: ctx, filter: filter, seen: make(map[JobId]bool)}
+}
+
+// Next advances to the next job, fetching another page when needed. It returns false
+// when all jobs have been returned or a request failed; Err tells the two apart.
+func (it *JobIterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		for it.pos < len(it.page) {
+			job, metadata := &it.page[it.pos], it.metadata[it.pos]
+			it.pos++
+			if job.Id != nil {
+				if it.seen[*job.Id] {
+					continue
+				}
+				it.seen[*job.Id] = true
+			}
+			if !it.filter.matches(job, metadata) {
+				continue
+			}
+			it.job, it.jobMetadata = job, metadata
+			return true
+		}
+
+		if it.done {
+			break
+		}
+		it.err = it.fetchPage(ctx)
+	}
+
+	it.job, it.jobMetadata = nil, nil
+	return false
+}
+
+// Job returns the job Next advanced to
+func (it *JobIterator) Job() *Job {
+	return it.job
+}
+
+// Metadata returns the metadata the job Next advanced to was tagged with, see
+// WithMetadata. The generated Job type does not model metadata yet, so it is read from
+// the list response separately; use WithAssignedMetadata to get it when creating a job.
+func (it *JobIterator) Metadata() map[string]string {
+	return it.jobMetadata
+}
+
+// Err returns the error that stopped the iteration, nil if it ran to the end
+func (it *JobIterator) Err() error {
+	return it.err
+}
+
+// fetchPage replaces the current page with the next one from the server
+func (it *JobIterator) fetchPage(ctx context.Context) error {
+	if err := context.Cause(it.ctx); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(it.ctx, func() { cancel(context.Cause(it.ctx)) })
+	defer stop()
+
+	params := &ListJobsParams{Limit: &it.filter.PageSize}
+	if it.filter.Status != "" {
+		status := ListJobsParamsStatus(it.filter.Status)
+		params.Status = &status
+	}
+
+	var editors []RequestEditorFn
+	if it.filter.Type != "" {
+		editors = append(editors, WithQueryParam("type", it.filter.Type))
+	}
+	for _, k := range slices.Sorted(maps.Keys(it.filter.Metadata)) {
+		editors = append(editors, WithQueryParam("metadata."+k, it.filter.Metadata[k]))
+	}
+	if it.offset > 0 {
+		editors = append(editors, WithQueryParam("offset", strconv.Itoa(it.offset)))
+	}
+
+	resp, err := it.client.ListJobsWithResponse(ctx, params, editors...)
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return it.client.apiError("list jobs", resp.HTTPResponse, resp.Body)
+	}
+
+	if resp.JSON200 == nil || resp.JSON200.Data == nil {
+		return fmt.Errorf("jobs endpoint returned no data")
+	}
+
+	var jobs []Job
+	if resp.JSON200.Data.Jobs != nil {
+		jobs = *resp.JSON200.Data.Jobs
+	}
+	it.page, it.metadata, it.pos = jobs, pageMetadata(resp.Body, len(jobs)), 0
+	it.offset += len(jobs)
+
+	// A short page is the last one. A full page without any new job is what a server
+	// that ignores the offset sends, so iteration fails instead of looping or reporting
+	// the jobs seen so far as all of them.
+	total := resp.JSON200.Data.Total
+	it.done = len(jobs) < it.filter.PageSize || (total != nil && it.offset >= *total)
+	if !it.done && !slices.ContainsFunc(jobs, func(job Job) bool { return job.Id == nil || !it.seen[*job.Id] }) {
+		it.page = nil
+		return ErrPaginationUnsupported
+	}
+
+	return nil
+}
+
+// pageMetadata reads the metadata of each job in a list response body. The result has
+// n entries, nil for jobs without metadata or when the body cannot be read.
+func pageMetadata(body []byte, n int) []map[string]string {
+	var page struct {
+		Data struct {
+			Jobs []struct {
+				Metadata map[string]string `json:"metadata"`
+			} `json:"jobs"`
+		} `json:"data"`
+	}
+	metadata := make([]map[string]string, n)
+	if json.Unmarshal(body, &page) != nil {
+		return metadata
+	}
+	for i, job := range page.Data.Jobs[:min(n, len(page.Data.Jobs))] {
+		metadata[i] = job.Metadata
+	}
+	return metadata
+}
@@ -0,0 +1,525 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateJob tests creating a job without uploading or submitting it
+func TestCreateJob(t *testing.T) {
+	t.Run("returns the ID and upload token", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		job, err := client.CreateJob(context.Background(), "test/linecount", WithParams(map[string]any{"mode": "fast"}))
+		require.NoError(t, err)
+		require.NotNil(t, job.Id)
+		require.NotNil(t, job.UploadToken)
+		assert.Equal(t, JobStatusCreated, job.CurrentStatus())
+
+		if mockServer != nil {
+			assert.Equal(t, map[string]interface{}{"mode": "fast"}, mockServer.CreateRequest(*job.Id)["params"])
+		}
+	})
+
+	t.Run("job type from context", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx := WithDefaultJobType(context.Background(), "test/linecount")
+		job, err := client.CreateJob(ctx, "")
+		require.NoError(t, err)
+		assert.Equal(t, "test/linecount", *job.Type)
+	})
+
+	t.Run("invalid job type", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		_, err := client.CreateJob(context.Background(), "")
+		assert.ErrorIs(t, err, ErrInvalidJobType)
+	})
+
+	t.Run("idempotency key", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Idempotency keys only supported in mock mode")
+		}
+
+		ctx := context.Background()
+		first, err := client.CreateJob(ctx, "test/linecount", WithIdempotencyKey("row-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "row-1", mockServer.LastRequest().Header.Get(IdempotencyKeyHeader))
+
+		again, err := client.CreateJob(ctx, "test/linecount", WithIdempotencyKey("row-1"))
+		require.NoError(t, err)
+		assert.Equal(t, *first.Id, *again.Id)
+
+		other, err := client.CreateJob(ctx, "test/linecount", WithIdempotencyKey("row-2"))
+		require.NoError(t, err)
+		assert.NotEqual(t, *first.Id, *other.Id)
+
+		// A submitted job is returned as is, without uploading the input again
+		submitted, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")), WithIdempotencyKey("row-3"))
+		require.NoError(t, err)
+		resubmitted, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("other")), WithIdempotencyKey("row-3"))
+		require.NoError(t, err)
+		assert.Equal(t, *submitted.Id, *resubmitted.Id)
+		assert.Equal(t, "a\nb", string(mockServer.UploadedData(*submitted.Id)))
+	})
+
+	t.Run("random key with retries", func(t *testing.T) {
+		_, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Request inspection only supported in mock mode")
+		}
+
+		client := newRetryClient(t, mockServer.Server, 2)
+		_, err := client.CreateJob(context.Background(), "test/linecount")
+		require.NoError(t, err)
+		key := mockServer.LastRequest().Header.Get(IdempotencyKeyHeader)
+		assert.NoError(t, uuid.Validate(key))
+
+		_, err = client.CreateJob(context.Background(), "test/linecount")
+		require.NoError(t, err)
+		assert.NotEqual(t, key, mockServer.LastRequest().Header.Get(IdempotencyKeyHeader))
+
+		client = newRetryClient(t, mockServer.Server, 0)
+		_, err = client.CreateJob(context.Background(), "test/linecount")
+		require.NoError(t, err)
+		assert.Empty(t, mockServer.LastRequest().Header.Get(IdempotencyKeyHeader))
+	})
+}
+
+// TestListActiveJobs tests listing of non-terminal jobs
+func TestListActiveJobs(t *testing.T) {
+	t.Run("only non-terminal jobs are returned", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Job listing test only supported in mock mode")
+		}
+
+		ctx := context.Background()
+		finished, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		pending, err := client.CreateAndSubmitJob(ctx, "test/queued", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		jobs, err := client.ListActiveJobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, *pending.Id, *jobs[0].Id)
+		assert.NotEqual(t, *finished.Id, *jobs[0].Id)
+	})
+
+	t.Run("every page is listed", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Exact job counts only known in mock mode")
+		}
+
+		ctx := context.Background()
+		for range defaultJobPageSize + 5 {
+			_, err := client.CreateJob(ctx, "test/linecount")
+			require.NoError(t, err)
+		}
+
+		jobs, err := client.ListActiveJobs(ctx)
+		require.NoError(t, err)
+		assert.Len(t, jobs, defaultJobPageSize+5)
+		assert.Equal(t, int64(2), client.Stats().Requests["ListJobs"])
+	})
+
+	t.Run("server ignoring the offset", func(t *testing.T) {
+		listed := make([]string, defaultJobPageSize)
+		for i := range listed {
+			status := JobStatusFinished
+			if i%2 == 0 {
+				status = JobStatusPending
+			}
+			listed[i] = `{"id":"` + uuid.NewString() + `","status":"` + string(status) + `"}`
+		}
+		page := `{"data":{"jobs":[` + strings.Join(listed, ",") + `]},"success":true}`
+		client := newCannedClient(t, http.StatusOK, page)
+
+		jobs, err := client.ListActiveJobs(context.Background())
+		assert.ErrorIs(t, err, ErrPaginationUnsupported)
+		assert.Len(t, jobs, defaultJobPageSize/2)
+	})
+
+	t.Run("no jobs is not an error", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{"data": {"jobs": [], "total": 0}, "success": true}`)
+
+		jobs, err := client.ListActiveJobs(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, jobs)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("omitted jobs list is empty", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{"data": {"total": 0}, "success": true}`)
+
+		jobs, err := client.ListActiveJobs(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("missing data", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{"success": true}`)
+
+		jobs, err := client.ListActiveJobs(context.Background())
+		require.Error(t, err)
+		assert.Nil(t, jobs)
+		assert.Contains(t, err.Error(), "jobs endpoint returned no data")
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{"data": []}`)
+
+		_, err := client.ListActiveJobs(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list jobs")
+	})
+}
+
+// TestIterateJobs tests walking every job across pages
+func TestIterateJobs(t *testing.T) {
+	t.Run("all pages", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Exact job counts only known in mock mode")
+		}
+
+		ctx := context.Background()
+		for i := 0; i < 7; i++ {
+			_, err := client.CreateJob(ctx, "test/linecount")
+			require.NoError(t, err)
+		}
+		submitted, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		it := client.IterateJobs(ctx, ListJobsFilter{PageSize: 3})
+		seen := make(map[JobId]bool)
+		for it.Next(ctx) {
+			assert.False(t, seen[*it.Job().Id], "job returned twice")
+			seen[*it.Job().Id] = true
+		}
+		require.NoError(t, it.Err())
+		assert.Len(t, seen, 8)
+		assert.Nil(t, it.Job())
+		assert.False(t, it.Next(ctx))
+		assert.Equal(t, int64(3), client.Stats().Requests["ListJobs"])
+
+		it = client.IterateJobs(ctx, ListJobsFilter{Status: JobStatusCreated, PageSize: 2})
+		var filtered []JobId
+		for it.Next(ctx) {
+			filtered = append(filtered, *it.Job().Id)
+		}
+		require.NoError(t, it.Err())
+		assert.Len(t, filtered, 7)
+		assert.NotContains(t, filtered, *submitted.Id)
+	})
+
+	t.Run("server ignoring the offset", func(t *testing.T) {
+		page := `{"data":{"jobs":[{"id":"` + uuid.NewString() + `"},{"id":"` + uuid.NewString() + `"}],"total":10}}`
+		client := newCannedClient(t, http.StatusOK, page)
+
+		it := client.IterateJobs(context.Background(), ListJobsFilter{PageSize: 2})
+		count := 0
+		for it.Next(context.Background()) {
+			count++
+		}
+		assert.ErrorIs(t, it.Err(), ErrPaginationUnsupported)
+		assert.Equal(t, 2, count)
+
+		jobs, err := client.ListJobsFiltered(context.Background(), ListJobsFilter{PageSize: 2})
+		assert.ErrorIs(t, err, ErrPaginationUnsupported)
+		assert.Len(t, jobs, 2)
+	})
+
+	t.Run("request failure", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusInternalServerError, `{"error":"boom"}`)
+
+		it := client.IterateJobs(context.Background(), ListJobsFilter{})
+		assert.False(t, it.Next(context.Background()))
+		assert.ErrorContains(t, it.Err(), "boom")
+	})
+
+	t.Run("iteration context", func(t *testing.T) {
+		page := `{"data":{"jobs":[{"id":"` + uuid.NewString() + `"},{"id":"` + uuid.NewString() + `"}],"total":10}}`
+		client := newCannedClient(t, http.StatusOK, page)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		it := client.IterateJobs(ctx, ListJobsFilter{PageSize: 2})
+		require.True(t, it.Next(context.Background()))
+		cancel()
+
+		// The rest of the fetched page is still returned, but no other page is requested
+		assert.True(t, it.Next(context.Background()))
+		assert.False(t, it.Next(context.Background()))
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+		assert.Equal(t, int64(1), client.Stats().Requests["ListJobs"])
+	})
+}
+
+// TestListJobsFiltered tests combining the server-side status filter with the type filter
+func TestListJobsFiltered(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Exact job counts only known in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/broken", JobStatusFailed)
+	mockServer.SetProgression("test/other", JobStatusFailed)
+
+	ctx := context.Background()
+	var failed []JobId
+	for i := 0; i < 3; i++ {
+		job, err := client.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		_, err = client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+		failed = append(failed, *job.Id)
+	}
+	for _, jobType := range []string{"test/other", "test/other", "test/linecount"} {
+		job, err := client.CreateAndSubmitJob(ctx, jobType, bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		_, err = client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+	}
+	_, err := client.CreateJob(ctx, "test/broken")
+	require.NoError(t, err)
+
+	jobs, err := client.ListJobsFiltered(ctx, ListJobsFilter{Status: JobStatusFailed, Type: "test/broken", PageSize: 2})
+	require.NoError(t, err)
+
+	var ids []JobId
+	for _, job := range jobs {
+		assert.Equal(t, JobStatusFailed, job.CurrentStatus())
+		assert.Equal(t, "test/broken", *job.Type)
+		ids = append(ids, *job.Id)
+	}
+	assert.ElementsMatch(t, failed, ids)
+	assert.Equal(t, string(JobStatusFailed), mockServer.LastRequest().URL.Query().Get("status"))
+	assert.Equal(t, "test/broken", mockServer.LastRequest().URL.Query().Get("type"))
+
+	jobs, err = client.ListJobsFiltered(ctx, ListJobsFilter{Type: "test/missing"})
+	require.NoError(t, err)
+	assert.NotNil(t, jobs)
+	assert.Empty(t, jobs)
+}
+
+// TestJobMetadata tests tagging jobs on creation and finding them by their tags
+func TestJobMetadata(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Metadata filtering only supported in mock mode")
+		}
+
+		ctx := context.Background()
+		tagged, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")),
+			WithMetadata(map[string]string{"tenant": "acme"}), WithMetadata(map[string]string{"batch": "7"}))
+		require.NoError(t, err)
+		_, err = client.CreateJob(ctx, "test/linecount", WithMetadata(map[string]string{"tenant": "other"}))
+		require.NoError(t, err)
+		_, err = client.CreateJob(ctx, "test/linecount")
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{"tenant": "acme", "batch": "7"}, mockServer.CreateRequest(*tagged.Id)["metadata"])
+
+		it := client.IterateJobs(context.Background(), ListJobsFilter{Metadata: map[string]string{"tenant": "acme"}})
+		require.True(t, it.Next(ctx))
+		assert.Equal(t, *tagged.Id, *it.Job().Id)
+		assert.Equal(t, map[string]string{"tenant": "acme", "batch": "7"}, it.Metadata())
+		assert.False(t, it.Next(ctx))
+		require.NoError(t, it.Err())
+		assert.Equal(t, "acme", mockServer.LastRequest().URL.Query().Get("metadata.tenant"))
+	})
+
+	t.Run("filtered on the client", func(t *testing.T) {
+		tagged := uuid.NewString()
+		page := `{"data":{"jobs":[{"id":"` + uuid.NewString() + `","metadata":{"tenant":"other"}},` +
+			`{"id":"` + tagged + `","metadata":{"tenant":"acme"}},{"id":"` + uuid.NewString() + `"}],"total":3}}`
+		client := newCannedClient(t, http.StatusOK, page)
+
+		jobs, err := client.ListJobsFiltered(context.Background(), ListJobsFilter{Metadata: map[string]string{"tenant": "acme"}})
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, tagged, jobs[0].Id.String())
+	})
+
+	t.Run("assigned on creation", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Metadata echo only supported in mock mode")
+		}
+
+		ctx := context.Background()
+		var assigned map[string]string
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")),
+			WithMetadata(map[string]string{"tenant": "acme"}), WithAssignedMetadata(&assigned))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"tenant": "acme"}, assigned)
+
+		assigned = map[string]string{"stale": "value"}
+		_, err = client.CreateJob(ctx, "test/linecount", WithAssignedMetadata(&assigned))
+		require.NoError(t, err)
+		assert.Nil(t, assigned)
+	})
+}
+
+// TestCancelJob tests stopping a running job and what the waiting helpers make of it
+func TestCancelJob(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/stuck", JobStatusProcessing)
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	events, errs := client.WaitForJobEvents(ctx, *job.Id)
+	for event := range events {
+		if event.Status == JobStatusProcessing {
+			require.NoError(t, client.CancelJob(ctx, *job.Id))
+		}
+	}
+	require.NoError(t, <-errs)
+
+	cancelled, err := client.WaitForJob(ctx, *job.Id)
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusCancelled, cancelled.CurrentStatus())
+
+	_, err = client.WaitForJobResult(ctx, *job.Id)
+	assert.ErrorIs(t, err, ErrJobCancelled)
+
+	err = client.CancelJob(ctx, *job.Id)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr), "error: %v", err)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+// TestRetryJob tests running a failed job again without uploading its input
+func TestRetryJob(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted failures only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+	ctx := context.Background()
+
+	failedJob := func(t *testing.T) JobId {
+		t.Helper()
+		mockServer.FailNextJobs("test/linecount", ErrorCodeWorkerEvicted)
+		_, err := client.ProcessString(ctx, "test/linecount", "a\nb")
+		var failed *JobFailedError
+		require.ErrorAs(t, err, &failed)
+		return *failed.Job.Id
+	}
+
+	t.Run("runs on the stored input", func(t *testing.T) {
+		jobID := failedJob(t)
+		uploads := client.Stats().Requests["UploadJobData"]
+
+		job, err := client.RetryJob(ctx, jobID)
+		require.NoError(t, err)
+		assert.Equal(t, jobID, *job.Id)
+		assert.Nil(t, job.ErrorCode)
+
+		job, err = client.WaitForJob(ctx, jobID)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *job.Status)
+		assert.Equal(t, uploads, client.Stats().Requests["UploadJobData"])
+
+		result, err := client.GetJobResult(ctx, jobID)
+		require.NoError(t, err)
+		assert.Equal(t, "2", result.OutputString())
+	})
+
+	t.Run("input expired", func(t *testing.T) {
+		jobID := failedJob(t)
+		mockServer.ExpireInput(jobID)
+
+		_, err := client.RetryJob(ctx, jobID)
+		require.ErrorIs(t, err, ErrInputUnavailable)
+		assert.Contains(t, err.Error(), "upload it again")
+	})
+
+	t.Run("only failed jobs", func(t *testing.T) {
+		result, err := client.ProcessString(ctx, "test/linecount", "a")
+		require.NoError(t, err)
+
+		_, err = client.RetryJob(ctx, *result.Job.Id)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only failed jobs")
+	})
+}
+
+// TestDeleteJob tests removing finished jobs and refusing to remove running ones
+func TestDeleteJob(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	result, err := client.Process(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	require.NoError(t, client.DeleteJob(ctx, *result.Job.Id))
+	assert.Nil(t, mockServer.GetJob(*result.Job.Id))
+
+	err = client.DeleteJob(ctx, *result.Job.Id)
+	assert.ErrorContains(t, err, "failed to delete job: status 404")
+
+	mockServer.SetProgression("test/stuck", JobStatusProcessing)
+	running, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+	_, err = client.GetJobStatus(ctx, *running.Id)
+	require.NoError(t, err)
+
+	err = client.DeleteJob(ctx, *running.Id)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr), "error: %v", err)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	assert.Equal(t, "job is being processed", apiErr.Message)
+
+	require.NoError(t, client.CancelJob(ctx, *running.Id))
+	require.NoError(t, client.DeleteJob(ctx, *running.Id))
+}
@@ -0,0 +1,79 @@
+package bsubio
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Logger receives the client's log events: requests, retries and the job status changes
+// seen while waiting. Each event is a message followed by alternating keys and values,
+// like slog. Events never include the API key or upload tokens.
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
+
+// NopLogger is a Logger that discards every event. It is the default.
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, keysAndValues ...any) {}
+func (NopLogger) Info(msg string, keysAndValues ...any)  {}
+func (NopLogger) Error(msg string, keysAndValues ...any) {}
+
+// NewSlogLogger returns a Logger that writes to l, or to slog.Default() when l is nil
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return l
+}
+
+// loggingDoer logs every request attempt and its outcome. Only the method and path are
+// logged: headers carry the API key and upload URLs carry the upload token in the query.
+func (c *BsubClient) loggingDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		operation := operationName(req)
+		c.logger.Debug("request started", "operation", operation, "method", req.Method, "path", req.URL.Path)
+
+		start := time.Now()
+		resp, err := next.Do(req)
+		if err != nil {
+			c.logger.Error("request failed", "operation", operation, "error", redactError(err), "duration", time.Since(start))
+			return nil, err
+		}
+
+		c.logger.Debug("request finished", "operation", operation, "status_code", resp.StatusCode, "duration", time.Since(start))
+		return resp, nil
+	})
+}
+
+// redactedError is an error whose message has the query string of its request URL cut
+// off, see redactError
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError hides the query string of the request URL that transport errors quote, so
+// err can be logged: upload URLs carry the upload token in the query. The error chain
+// is kept for errors.Is and errors.As.
+func redactError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	redacted, _, found := strings.Cut(urlErr.URL, "?")
+	if !found {
+		return err
+	}
+	return &redactedError{err: err, msg: strings.ReplaceAll(err.Error(), urlErr.URL, redacted)}
+}
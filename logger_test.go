@@ -0,0 +1,173 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logEvent is an event received by recordingLogger
+type logEvent struct {
+	level string
+	msg   string
+	kv    []any
+}
+
+// recordingLogger is a Logger that keeps every event
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []logEvent
+}
+
+func (l *recordingLogger) record(level, msg string, kv []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, logEvent{level, msg, kv})
+}
+
+func (l *recordingLogger) Debug(msg string, kv ...any) { l.record("debug", msg, kv) }
+func (l *recordingLogger) Info(msg string, kv ...any)  { l.record("info", msg, kv) }
+func (l *recordingLogger) Error(msg string, kv ...any) { l.record("error", msg, kv) }
+
+// messages returns the messages logged at level, in order
+func (l *recordingLogger) messages(level string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var msgs []string
+	for _, event := range l.events {
+		if event.level == level {
+			msgs = append(msgs, event.msg)
+		}
+	}
+	return msgs
+}
+
+// String renders every event, to check what was logged
+func (l *recordingLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b strings.Builder
+	for _, event := range l.events {
+		fmt.Fprintln(&b, event.level, event.msg, event.kv)
+	}
+	return b.String()
+}
+
+// hangUpUploadServer creates jobs with the given upload token and hangs up on their
+// uploads, so the transport error quotes the upload URL with the token
+func hangUpUploadServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/upload/") {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data":{"id":"` + uuid.NewString() + `","status":"created","upload_token":"` + token + `"}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestLogger tests the events sent to a configured Logger
+func TestLogger(t *testing.T) {
+	t.Run("job lifecycle", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+		mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusFinished)
+
+		logger := &recordingLogger{}
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: mockServer.URL, Logger: logger})
+		require.NoError(t, err)
+		client.pollInterval = testPollInterval
+
+		result, err := client.Process(context.Background(), "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		debug := logger.messages("debug")
+		assert.Contains(t, debug, "request started")
+		assert.Contains(t, debug, "request finished")
+		assert.Contains(t, debug, "job status changed")
+		assert.Equal(t, []string{"job ended"}, logger.messages("info"))
+
+		logged := logger.String()
+		assert.Contains(t, logged, "UploadJobData")
+		assert.Contains(t, logged, "processing")
+		assert.NotContains(t, logged, "test-api-key")
+		assert.NotContains(t, logged, *mockServer.GetJob(*result.Job.Id).UploadToken)
+	})
+
+	t.Run("failed job and retries", func(t *testing.T) {
+		server, _ := flakyServer(t, 1, http.StatusServiceUnavailable)
+
+		logger := &recordingLogger{}
+		client, err := NewBsubClient(Config{
+			APIKey:       "test-api-key",
+			BaseURL:      server.URL,
+			MaxRetries:   1,
+			RetryBackoff: time.Millisecond,
+			Logger:       logger,
+		})
+		require.NoError(t, err)
+
+		_, err = client.WaitForJob(context.Background(), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"retrying request", "job ended"}, logger.messages("info"))
+		assert.Contains(t, logger.String(), "503 Service Unavailable")
+	})
+
+	t.Run("failed upload hides the token", func(t *testing.T) {
+		const token = "secret-upload-token"
+		server := hangUpUploadServer(t, token)
+
+		var out bytes.Buffer
+		logger := NewSlogLogger(slog.New(slog.NewTextHandler(&out, nil)))
+		client, err := NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithAPIBaseURL(server.URL), WithLogger(logger))
+		require.NoError(t, err)
+
+		_, err = client.CreateAndSubmitJob(context.Background(), "test/linecount", bytes.NewReader([]byte("data")), WithCleanupOnError(false))
+		require.Error(t, err)
+		assert.Contains(t, out.String(), "operation=UploadJobData")
+		assert.Contains(t, out.String(), "/v1/upload/")
+		assert.NotContains(t, out.String(), token)
+	})
+
+	t.Run("slog adapter", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+		mockServer.SetProgression("test/broken", JobStatusFailed)
+
+		var out bytes.Buffer
+		logger := NewSlogLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		client, err := NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithAPIBaseURL(mockServer.URL), WithLogger(logger))
+		require.NoError(t, err)
+		client.pollInterval = testPollInterval
+
+		_, err = client.Process(context.Background(), "test/broken", bytes.NewReader([]byte("data")))
+		require.ErrorIs(t, err, ErrJobFailed)
+		assert.Contains(t, out.String(), `level=ERROR msg="job failed"`)
+		assert.Contains(t, out.String(), "operation=CreateJob")
+	})
+
+	t.Run("no-op default", func(t *testing.T) {
+		client, err := NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+		assert.Equal(t, NopLogger{}, client.logger)
+		assert.NotNil(t, NewSlogLogger(nil))
+	})
+}
@@ -0,0 +1,265 @@
+package bsubio
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamJobLogs copies the job logs to w and returns the number of bytes written.
+//
+// Logs are requested gzip-compressed to save bandwidth on chatty processors and are
+// decompressed on the fly, so w always receives plain text and the count is the
+// decompressed size.
+func (c *BsubClient) StreamJobLogs(ctx context.Context, jobID JobId, w io.Writer) (int64, error) {
+	resp, err := c.getJobLogs(ctx, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get job logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.apiError("get job logs", resp, nil)
+	}
+
+	n, err := c.copyData(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	return n, nil
+}
+
+// GetJobLogsString returns the whole job log as a string. A response other than
+// 200 OK is returned as *APIError, never as partial logs.
+func (c *BsubClient) GetJobLogsString(ctx context.Context, jobID JobId) (string, error) {
+	var logs strings.Builder
+	if _, err := c.StreamJobLogs(ctx, jobID, &logs); err != nil {
+		return "", err
+	}
+	return logs.String(), nil
+}
+
+// LogLineIterator reads job logs one line at a time, so large logs can be processed
+// without holding them in memory. Use it like a scanner, and close it when done:
+//
+//	it, err := client.IterateJobLogs(ctx, jobID)
+//	if err != nil {
+//		...
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		line := it.Text()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Lines may be of any length. A LogLineIterator is not safe for concurrent use.
+type LogLineIterator struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	line   string
+	err    error
+}
+
+// IterateJobLogs requests the job logs and returns an iterator over their lines. A
+// response other than 200 OK is returned as *APIError before any line is read.
+func (c *BsubClient) IterateJobLogs(ctx context.Context, jobID JobId) (*LogLineIterator, error) {
+	resp, err := c.getJobLogs(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job logs: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.apiError("get job logs", resp, nil)
+	}
+
+	return &LogLineIterator{body: resp.Body, reader: bufio.NewReaderSize(resp.Body, c.ioBufferSize)}, nil
+}
+
+// Next advances to the next line, without its line ending. It returns false at the end
+// of the logs or when reading failed; Err tells the two apart.
+func (it *LogLineIterator) Next() bool {
+	if it.err != nil {
+		it.line = ""
+		return false
+	}
+
+	// The last line may have no line ending, and is returned along with io.EOF
+	line, err := it.reader.ReadString('\n')
+	it.err = err
+	if err != nil && (err != io.EOF || line == "") {
+		it.line = ""
+		return false
+	}
+
+	it.line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return true
+}
+
+// Text returns the line Next advanced to
+func (it *LogLineIterator) Text() string {
+	return it.line
+}
+
+// Err returns the error that stopped the iteration, nil if it ran to the end
+func (it *LogLineIterator) Err() error {
+	if it.err == io.EOF {
+		return nil
+	}
+	if it.err != nil {
+		return fmt.Errorf("failed to read logs: %w", it.err)
+	}
+	return nil
+}
+
+// Close releases the connection the logs are read from
+func (it *LogLineIterator) Close() error {
+	return it.body.Close()
+}
+
+// FollowJobLogs returns a reader of the job logs that keeps delivering new log output
+// as the job produces it, like tail -f, and reaches EOF once the job is in a terminal
+// state and its final logs have been read.
+//
+// The logs are polled at the client's poll interval. They are expected to only grow,
+// so each poll delivers just the bytes past those already read. Closing the reader or
+// cancelling ctx stops following; a failed request ends the stream with its error.
+// Errors getting the job, such as an unknown ID, are returned before following starts.
+func (c *BsubClient) FollowJobLogs(ctx context.Context, jobID JobId) (io.ReadCloser, error) {
+	if _, err := c.getJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+
+	// Closing the pipe unblocks a write the caller is not reading
+	stop := context.AfterFunc(ctx, func() { pw.CloseWithError(ctx.Err()) })
+	go func() {
+		defer close(done)
+		defer cancel()
+		defer stop()
+		pw.CloseWithError(c.followJobLogs(ctx, jobID, pw))
+	}()
+
+	return &logFollower{PipeReader: pr, cancel: cancel, done: done}, nil
+}
+
+// followJobLogs writes new log output to w until the job is terminal
+func (c *BsubClient) followJobLogs(ctx context.Context, jobID JobId, w io.Writer) error {
+	var written int64
+	for {
+		// The status is checked before the logs, so the logs read after
+		// a terminal status are complete
+		job, err := c.getJob(ctx, jobID)
+		if err != nil {
+			return err
+		}
+
+		n, err := c.copyNewLogs(ctx, jobID, w, written)
+		written += n
+		if err != nil {
+			return err
+		}
+
+		if isTerminal(job.Status) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// copyNewLogs fetches the job logs and copies what follows the first skip bytes to w.
+// Logs that are not available yet count as empty.
+func (c *BsubClient) copyNewLogs(ctx context.Context, jobID JobId, w io.Writer, skip int64) (int64, error) {
+	resp, err := c.getJobLogs(ctx, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get job logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return 0, nil
+	default:
+		return 0, c.apiError("get job logs", resp, nil)
+	}
+
+	if _, err := io.CopyN(io.Discard, resp.Body, skip); err != nil {
+		if err == io.EOF {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	n, err := c.copyData(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read logs: %w", err)
+	}
+	return n, nil
+}
+
+// logFollower is the reader returned by FollowJobLogs; closing it stops the follow loop
+type logFollower struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once the follow loop has returned
+}
+
+func (f *logFollower) Close() error {
+	f.cancel()
+	return f.PipeReader.Close()
+}
+
+// getJobLogs requests the job logs, accepting gzip. A compressed response gets its body
+// replaced by the decompressed stream, the way net/http does for requests it compresses
+// itself, so callers read plain text either way.
+func (c *BsubClient) getJobLogs(ctx context.Context, jobID JobId) (*http.Response, error) {
+	resp, err := c.GetJobLogs(ctx, jobID, withHeader("Accept-Encoding", "gzip"))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp, nil
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to decompress logs: %w", err)
+	}
+
+	resp.Body = &gzipBody{Reader: zr, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipBody decompresses a response body and closes it along with the gzip reader
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
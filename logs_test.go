@@ -0,0 +1,201 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamJobLogs tests streaming logs, with and without gzip compression
+func TestStreamJobLogs(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		name := "plain"
+		if compressed {
+			name = "gzip"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			client, mockServer, cleanup := SetupTestClient(t)
+			defer cleanup()
+
+			if mockServer != nil {
+				mockServer.SetGzipLogs(compressed)
+			}
+
+			ctx := context.Background()
+			job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")))
+			require.NoError(t, err)
+
+			var logs bytes.Buffer
+			n, err := client.StreamJobLogs(ctx, *job.Id, &logs)
+			require.NoError(t, err)
+			assert.Equal(t, int64(logs.Len()), n)
+			assert.Contains(t, logs.String(), "test/linecount")
+
+			if mockServer != nil {
+				assert.Equal(t, "gzip", mockServer.LastRequest().Header.Get("Accept-Encoding"))
+			}
+
+			// The other log consumers decompress too
+			result, err := client.GetJobResult(ctx, *job.Id)
+			require.NoError(t, err)
+			assert.Equal(t, logs.String(), result.Logs)
+
+			var artifact bytes.Buffer
+			require.NoError(t, client.StreamJobArtifact(ctx, *job.Id, &artifact))
+			assert.Equal(t, logs.Bytes(), readTarEntries(t, &artifact)[ArtifactLogsEntry])
+		})
+	}
+}
+
+// TestFollowJobLogs tests tailing logs while a job runs
+func TestFollowJobLogs(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Growing logs only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	ctx := context.Background()
+	base := "Processing test/slow job\nCompleted successfully"
+
+	t.Run("delivers new output once until the job ends", func(t *testing.T) {
+		mockServer.SetProgression("test/slow", JobStatusProcessing, JobStatusProcessing, JobStatusProcessing,
+			JobStatusProcessing, JobStatusProcessing, JobStatusProcessing, JobStatusFinished)
+		job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		logs, err := client.FollowJobLogs(ctx, *job.Id)
+		require.NoError(t, err)
+		defer logs.Close()
+
+		first := make([]byte, len(base))
+		_, err = io.ReadFull(logs, first)
+		require.NoError(t, err)
+		assert.Equal(t, base, string(first))
+
+		mockServer.AppendLogs(*job.Id, "\nmore output")
+
+		rest, err := io.ReadAll(logs)
+		require.NoError(t, err)
+		assert.Equal(t, "\nmore output", string(rest))
+	})
+
+	t.Run("close and cancel stop following", func(t *testing.T) {
+		mockServer.SetProgression("test/stuck", JobStatusProcessing)
+		job, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		logs, err := client.FollowJobLogs(ctx, *job.Id)
+		require.NoError(t, err)
+		require.NoError(t, logs.Close())
+		_, err = logs.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		logs, err = client.FollowJobLogs(cancelCtx, *job.Id)
+		require.NoError(t, err)
+		defer logs.Close()
+		cancel()
+
+		_, err = io.ReadAll(logs)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("cancel stops a follower nobody reads", func(t *testing.T) {
+		mockServer.SetProgression("test/stuck", JobStatusProcessing)
+		job, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		logs, err := client.FollowJobLogs(cancelCtx, *job.Id)
+		require.NoError(t, err)
+		defer logs.Close()
+
+		// Give the loop time to block writing the first logs into the pipe
+		time.Sleep(5 * testPollInterval)
+		cancel()
+
+		select {
+		case <-logs.(*logFollower).done:
+		case <-time.After(time.Second):
+			t.Fatal("follow loop still running after cancel")
+		}
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		_, err := client.FollowJobLogs(ctx, uuid.New())
+		assert.ErrorContains(t, err, "status 404")
+	})
+}
+
+// TestGetJobLogsString tests reading the whole log, and refusing error responses
+func TestGetJobLogsString(t *testing.T) {
+	client, _, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")))
+	require.NoError(t, err)
+
+	logs, err := client.GetJobLogsString(ctx, *job.Id)
+	require.NoError(t, err)
+	assert.Contains(t, logs, "test/linecount")
+
+	t.Run("unexpected status", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusNotFound, `{"error":"not found"}`)
+
+		logs, err := client.GetJobLogsString(ctx, uuid.New())
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Empty(t, logs)
+	})
+}
+
+// TestIterateJobLogs tests reading logs line by line
+func TestIterateJobLogs(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Appended logs only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")))
+	require.NoError(t, err)
+	mockServer.AppendLogs(*job.Id, "\r\n\n"+strings.Repeat("x", 100<<10)+"\nlast")
+
+	it, err := client.IterateJobLogs(ctx, *job.Id)
+	require.NoError(t, err)
+	defer it.Close()
+
+	var lines []string
+	for it.Next() {
+		lines = append(lines, it.Text())
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"Processing test/linecount job", "Completed successfully", "", strings.Repeat("x", 100<<10), "last"}, lines)
+	assert.False(t, it.Next())
+	assert.Empty(t, it.Text())
+
+	t.Run("unexpected status", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusNotFound, `{"error":"not found"}`)
+
+		it, err := client.IterateJobLogs(ctx, uuid.New())
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Nil(t, it)
+	})
+}
@@ -0,0 +1,346 @@
+package bsubio
+
+import (
+	"context"
+	"maps"
+)
+
+// CallOption customizes a single call to one of the job helpers
+// such as CreateAndSubmitJob, Process or ProcessFile
+type CallOption func(*callOptions)
+
+// callOptions holds the settings collected from CallOptions
+type callOptions struct {
+	params      map[string]any
+	metadata    map[string]string
+	metadataDst *map[string]string
+
+	priority    JobPriority
+	priorityDst *JobPriority
+
+	callbackURL string
+
+	checksum       bool
+	checksumDst    *string
+	verifyChecksum *bool
+
+	accept             string
+	rawOutput          bool
+	skipOutputChecksum bool
+
+	waitForOutput bool
+	outputReady   bool
+
+	cleanupOnError bool
+
+	validateType bool
+
+	idempotencyKey string
+
+	jobAttempts int
+	retryIf     ErrorCodeRetryPredicate
+
+	onPoll func(*Job)
+
+	onUploadProgress func(bytesSent, totalBytes int64)
+
+	onStep func(kind ProcessEventKind, job *Job)
+
+	uploadState string
+
+	uploadFilename    string
+	uploadContentType string
+	compressUpload    *bool
+	uploadSize        int64
+}
+
+// newCallOptions applies opts over the defaults
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{
+		cleanupOnError: true,
+		jobAttempts:    1,
+		uploadSize:     -1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithParams sets processor parameters for the job. They are merged over the defaults
+// registered with SetDefaultParams for the job type, with these values winning.
+// Repeating the option merges the maps in order.
+func WithParams(params map[string]any) CallOption {
+	return func(o *callOptions) {
+		if o.params == nil {
+			o.params = make(map[string]any, len(params))
+		}
+		for k, v := range params {
+			o.params[k] = v
+		}
+	}
+}
+
+// WithMetadata tags the job with key/value pairs, e.g. a tenant ID, so it can be found
+// later with ListJobsFilter.Metadata. Repeating the option merges the maps in order.
+func WithMetadata(metadata map[string]string) CallOption {
+	return func(o *callOptions) {
+		if o.metadata == nil {
+			o.metadata = make(map[string]string, len(metadata))
+		}
+		maps.Copy(o.metadata, metadata)
+	}
+}
+
+// WithAssignedMetadata stores the metadata the server reports for the created job in
+// dst, or nil when it reports none. Like WithAssignedPriority, it is read from the
+// create response because the generated Job type does not model metadata yet.
+func WithAssignedMetadata(dst *map[string]string) CallOption {
+	return func(o *callOptions) {
+		o.metadataDst = dst
+	}
+}
+
+// JobPriority asks the server to schedule a job ahead of or behind others, see WithPriority
+type JobPriority string
+
+// Job priorities understood by the server
+const (
+	PriorityHigh   JobPriority = "high"
+	PriorityNormal JobPriority = "normal"
+	PriorityLow    JobPriority = "low"
+)
+
+// WithPriority creates the job with the given priority, e.g. PriorityHigh for
+// latency-sensitive work and PriorityLow for bulk jobs. It is sent as is, so servers
+// that do not schedule by priority ignore it; use WithAssignedPriority to see what the
+// server made of it.
+func WithPriority(priority JobPriority) CallOption {
+	return func(o *callOptions) {
+		o.priority = priority
+	}
+}
+
+// WithAssignedPriority stores the priority the server reports for the created job in
+// dst, or "" when it reports none, such as servers without priorities. The generated
+// Job type does not model the priority yet, so it is read from the create response.
+func WithAssignedPriority(dst *JobPriority) CallOption {
+	return func(o *callOptions) {
+		o.priorityDst = dst
+	}
+}
+
+// WithCallbackURL asks the server to POST the job to callbackURL once it ends, so the
+// caller can skip WaitForJob; decode the request with ParseWebhook. The URL must be an
+// absolute http or https URL that the server can reach.
+func WithCallbackURL(callbackURL string) CallOption {
+	return func(o *callOptions) {
+		o.callbackURL = callbackURL
+	}
+}
+
+// WithUploadChecksum computes the SHA-256 of the input while it is read for the upload
+// and sends the hex digest in the ChecksumHeader header, so the server can verify it.
+// If sum is not nil, the digest is stored there for provenance once the upload is sent.
+// A mismatch is reported as *ChecksumMismatchError, as with Config.VerifyChecksum.
+func WithUploadChecksum(sum *string) CallOption {
+	return func(o *callOptions) {
+		o.checksum = true
+		o.checksumDst = sum
+	}
+}
+
+// WithVerifyChecksum overrides Config.VerifyChecksum for one call
+func WithVerifyChecksum(enabled bool) CallOption {
+	return func(o *callOptions) {
+		o.verifyChecksum = &enabled
+	}
+}
+
+// WithOutputAccept asks for the job output in the given media type, e.g. "text/csv",
+// for job types that can emit several formats. It is sent as the Accept header of the
+// output request. Servers that cannot honor it answer in another format, so check
+// JobResult.OutputContentType for what was actually returned.
+func WithOutputAccept(mime string) CallOption {
+	return func(o *callOptions) {
+		o.accept = mime
+	}
+}
+
+// WithRawOutput turns off the decompression of job output the server sends gzipped,
+// for callers that store it in compressed form. The output is then returned as sent,
+// and JobResult.OutputContentEncoding names its encoding.
+func WithRawOutput() CallOption {
+	return func(o *callOptions) {
+		o.rawOutput = true
+	}
+}
+
+// WithVerifyOutputChecksum controls the check of downloaded output against the SHA-256
+// the server sends in ChecksumHeader, done by GetJobResult, GetJobOutputTo and the other
+// output helpers. It is on by default and costs nothing when the server sends no
+// checksum; a mismatch is returned as *OutputChecksumError once the output is read.
+// The check is skipped for compressed output requested with WithRawOutput.
+func WithVerifyOutputChecksum(enabled bool) CallOption {
+	return func(o *callOptions) {
+		o.skipOutputChecksum = !enabled
+	}
+}
+
+// WithCleanupOnError controls whether a job that was created but could not be uploaded
+// or submitted is deleted before the helper returns the error. It is enabled by default;
+// disable it to keep the job, whose ID is then reported by IncompleteJobError.
+func WithCleanupOnError(enabled bool) CallOption {
+	return func(o *callOptions) {
+		o.cleanupOnError = enabled
+	}
+}
+
+// WithJobTypeValidation makes the job helpers check the job type against the types the
+// server supports before creating the job, so a typo fails with an *UnknownJobTypeError
+// listing the valid types instead of after the input was uploaded. The type list is
+// cached as described in ValidateJobType.
+func WithJobTypeValidation() CallOption {
+	return func(o *callOptions) {
+		o.validateType = true
+	}
+}
+
+// WithIdempotencyKey sends key in the IdempotencyKeyHeader header when the job is
+// created, so repeating a create with the same key returns the job the first attempt
+// made instead of a duplicate. Use a key that is unique per input, e.g. derived from
+// a file path or a database row. When CreateAndSubmitJob gets back a job that was
+// already submitted, it returns it without uploading the input again.
+//
+// Without this option a random key is generated for each create when HTTP retries are
+// enabled, which keeps retried create requests from making duplicate jobs.
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// WithJobRetry makes Process and ProcessFile re-run a job that fails, up to maxAttempts
+// runs in total. When retryIf is not nil it is called with the error code of each failed
+// run (empty if the server sent none) and only codes it accepts are retried, so transient
+// failures can be told apart from bad input. Process keeps a copy of the input for the
+// re-runs, in memory unless the reader can seek.
+func WithJobRetry(maxAttempts int, retryIf ErrorCodeRetryPredicate) CallOption {
+	return func(o *callOptions) {
+		o.jobAttempts = maxAttempts
+		o.retryIf = retryIf
+	}
+}
+
+// WithWaitForOutput makes Process and ProcessFile wait with WaitForOutput instead of
+// WaitForJob, treating a downloadable output as completion even if the job status lags.
+func WithWaitForOutput() CallOption {
+	return func(o *callOptions) {
+		o.waitForOutput = true
+	}
+}
+
+// WithPollCallback makes Process and ProcessFile call onPoll with every job snapshot
+// they fetch while waiting, like WaitForJobWithCallback, e.g. to show a live status line
+func WithPollCallback(onPoll func(*Job)) CallOption {
+	return func(o *callOptions) {
+		o.onPoll = onPoll
+	}
+}
+
+// WithUploadProgress makes the job helpers call onProgress as the input is sent to the
+// server, with the number of input bytes sent so far and the total input size, or -1
+// when the size is not known in advance. The last call reports all bytes sent. For
+// compressed uploads, see Config.CompressUploads, both count compressed bytes.
+func WithUploadProgress(onProgress func(bytesSent, totalBytes int64)) CallOption {
+	return func(o *callOptions) {
+		o.onUploadProgress = onProgress
+	}
+}
+
+// WithUploadFilename sets the file name the input is uploaded under, which some job
+// types use to detect the input format. It defaults to "upload".
+func WithUploadFilename(name string) CallOption {
+	return func(o *callOptions) {
+		o.uploadFilename = name
+	}
+}
+
+// WithUploadCompression overrides Config.CompressUploads for one call, e.g. to skip
+// compressing an input that is already compressed, like an image or a zip archive
+func WithUploadCompression(enabled bool) CallOption {
+	return func(o *callOptions) {
+		o.compressUpload = &enabled
+	}
+}
+
+// WithUploadSize declares that the input is size bytes long, for readers whose length
+// cannot be detected, such as a pipe whose producer knows how much it will send. The
+// upload is then sent with a Content-Length header instead of chunked encoding, which
+// some proxies and gateways reject. An input of a different length fails the upload.
+func WithUploadSize(size int64) CallOption {
+	return func(o *callOptions) {
+		o.uploadSize = size
+	}
+}
+
+// WithUploadState makes CreateAndSubmitJobFromFileResumable record its job in the file at
+// path, so that an upload interrupted by a process restart can be continued by calling it
+// again with the same file and path. The file holds the job's upload token and is created
+// with mode 0600.
+func WithUploadState(path string) CallOption {
+	return func(o *callOptions) {
+		o.uploadState = path
+	}
+}
+
+// withStepCallback makes the upload helpers call onStep as a created job is uploaded
+// and submitted, see ProcessFileEvents
+func withStepCallback(onStep func(kind ProcessEventKind, job *Job)) CallOption {
+	return func(o *callOptions) {
+		o.onStep = onStep
+	}
+}
+
+// step reports a step of the upload to the callback set with withStepCallback, if any
+func (o *callOptions) step(kind ProcessEventKind, job *Job) {
+	if o.onStep != nil {
+		o.onStep(kind, job)
+	}
+}
+
+// withUploadContentType sets the media type the input is uploaded as
+func withUploadContentType(contentType string) CallOption {
+	return func(o *callOptions) {
+		o.uploadContentType = contentType
+	}
+}
+
+// outputReady tells GetJobResult the output is known to be downloadable, so it is
+// fetched regardless of the job status
+func outputReady() CallOption {
+	return func(o *callOptions) {
+		o.outputReady = true
+	}
+}
+
+// defaultJobTypeKey is the context key for WithDefaultJobType
+type defaultJobTypeKey struct{}
+
+// WithDefaultJobType returns a copy of ctx carrying a default job type. The job helpers
+// (CreateAndSubmitJob, Process, ProcessFile and friends) use it when they are called with
+// an empty job type; a non-empty job type argument always takes precedence.
+func WithDefaultJobType(ctx context.Context, jobType string) context.Context {
+	return context.WithValue(ctx, defaultJobTypeKey{}, jobType)
+}
+
+// jobTypeFromContext returns jobType, or the context default when jobType is empty
+func jobTypeFromContext(ctx context.Context, jobType string) string {
+	if jobType != "" {
+		return jobType
+	}
+	if defaultType, ok := ctx.Value(defaultJobTypeKey{}).(string); ok {
+		return defaultType
+	}
+	return jobType
+}
@@ -0,0 +1,210 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDefaultParams tests merging of registered default params with per-call params
+func TestDefaultParams(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Request inspection only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	client.SetDefaultParams("test/linecount", map[string]any{"to": "gfm", "toc": true})
+
+	t.Run("defaults are sent", func(t *testing.T) {
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		req := mockServer.CreateRequest(*job.Id)
+		assert.Equal(t, map[string]interface{}{"to": "gfm", "toc": true}, req["params"])
+	})
+
+	t.Run("per-call params win", func(t *testing.T) {
+		result, err := client.Process(ctx, "test/linecount", bytes.NewReader([]byte("data")),
+			WithParams(map[string]any{"to": "commonmark"}),
+			WithParams(map[string]any{"wrap": "none"}),
+		)
+		require.NoError(t, err)
+
+		req := mockServer.CreateRequest(*result.Job.Id)
+		assert.Equal(t, map[string]interface{}{"to": "commonmark", "toc": true, "wrap": "none"}, req["params"])
+	})
+
+	t.Run("other job types are unaffected", func(t *testing.T) {
+		job, err := client.CreateAndSubmitJob(ctx, "test/other", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		req := mockServer.CreateRequest(*job.Id)
+		assert.NotContains(t, req, "params")
+	})
+
+	t.Run("defaults can be removed", func(t *testing.T) {
+		client.SetDefaultParams("test/linecount", nil)
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		req := mockServer.CreateRequest(*job.Id)
+		assert.NotContains(t, req, "params")
+	})
+}
+
+// TestWithUploadChecksum tests that the input digest is sent and reported
+func TestWithUploadChecksum(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Request inspection only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	input := []byte("line1\nline2\nline3")
+	want := sha256.Sum256(input)
+
+	var sum string
+	job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(input), WithUploadChecksum(&sum))
+	require.NoError(t, err)
+
+	assert.Equal(t, hex.EncodeToString(want[:]), sum)
+	assert.Equal(t, JobStatusFinished, *mockServer.GetJob(*job.Id).Status)
+
+	// The option works without a destination too
+	_, err = client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(input), WithUploadChecksum(nil))
+	require.NoError(t, err)
+}
+
+// TestWithPriority tests that the priority is sent and the server's answer reported
+func TestWithPriority(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Request inspection only supported in mock mode")
+	}
+
+	ctx := context.Background()
+
+	t.Run("sent and echoed", func(t *testing.T) {
+		var assigned JobPriority
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")),
+			WithPriority(PriorityHigh), WithAssignedPriority(&assigned))
+		require.NoError(t, err)
+
+		assert.Equal(t, "high", mockServer.CreateRequest(*job.Id)["priority"])
+		assert.Equal(t, PriorityHigh, assigned)
+	})
+
+	t.Run("omitted by default", func(t *testing.T) {
+		assigned := PriorityLow
+		job, err := client.CreateJob(ctx, "test/linecount", WithAssignedPriority(&assigned))
+		require.NoError(t, err)
+
+		assert.NotContains(t, mockServer.CreateRequest(*job.Id), "priority")
+		assert.Empty(t, assigned)
+	})
+
+	t.Run("server without priorities", func(t *testing.T) {
+		server, _ := flakyServer(t, 0, 0)
+		client, err := NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithAPIBaseURL(server.URL))
+		require.NoError(t, err)
+
+		var assigned JobPriority
+		_, err = client.CreateJob(ctx, "test/linecount", WithPriority(PriorityLow), WithAssignedPriority(&assigned))
+		require.NoError(t, err)
+		assert.Empty(t, assigned)
+	})
+}
+
+// TestVerifyChecksum tests that corrupted uploads are reported as checksum mismatches
+func TestVerifyChecksum(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Upload corruption only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	input := []byte("line1\nline2\nline3")
+	client.verifyChecksum = true
+
+	t.Run("intact upload", func(t *testing.T) {
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(input))
+		require.NoError(t, err)
+	})
+
+	t.Run("rejected by the server", func(t *testing.T) {
+		mockServer.CorruptUploads(1)
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(input))
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+
+		var mismatch *ChecksumMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Empty(t, mismatch.Actual)
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 400, apiErr.StatusCode)
+	})
+
+	t.Run("reported by the server", func(t *testing.T) {
+		mockServer.SetSkipChecksums(true)
+		defer mockServer.SetSkipChecksums(false)
+		mockServer.CorruptUploads(1)
+
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(input))
+		var mismatch *ChecksumMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		want := sha256.Sum256(input)
+		assert.Equal(t, hex.EncodeToString(want[:]), mismatch.Expected)
+		assert.NotEqual(t, mismatch.Expected, mismatch.Actual)
+	})
+
+	t.Run("disabled per call", func(t *testing.T) {
+		// Without a checksum the corruption goes unnoticed
+		mockServer.CorruptUploads(1)
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(input), WithVerifyChecksum(false))
+		require.NoError(t, err)
+	})
+}
+
+// TestWithDefaultJobType tests job type resolution from the context
+func TestWithDefaultJobType(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Request inspection only supported in mock mode")
+	}
+
+	ctx := WithDefaultJobType(context.Background(), "test/linecount")
+
+	t.Run("empty job type uses context default", func(t *testing.T) {
+		result, err := client.Process(ctx, "", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		assert.Equal(t, "test/linecount", *result.Job.Type)
+	})
+
+	t.Run("explicit job type wins", func(t *testing.T) {
+		job, err := client.CreateAndSubmitJob(ctx, "test/other", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		assert.Equal(t, "test/other", *job.Type)
+	})
+
+	t.Run("no default still fails validation", func(t *testing.T) {
+		_, err := client.Process(context.Background(), "", bytes.NewReader([]byte("data")))
+		assert.ErrorIs(t, err, ErrInvalidJobType)
+	})
+}
@@ -0,0 +1,406 @@
+package bsubio
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsOutputReady reports whether the job output can be downloaded, using a HEAD request
+// so no output is transferred
+func (c *BsubClient) IsOutputReady(ctx context.Context, jobID JobId) (bool, error) {
+	resp, err := c.GetJobOutput(ctx, jobID, withMethod(http.MethodHead))
+	if err != nil {
+		return false, fmt.Errorf("failed to check job output: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, c.apiError("check job output", resp, nil)
+	}
+}
+
+// WaitForOutput polls IsOutputReady until the job output can be downloaded.
+//
+// Prefer it over WaitForJob when the output is what the caller needs and the server
+// may publish it before the job status reaches finished. While the output is missing
+// the status is checked too, so a failed job returns ErrJobFailed, and a cancelled one
+// ErrJobCancelled, instead of waiting until ctx is done.
+func (c *BsubClient) WaitForOutput(ctx context.Context, jobID JobId) error {
+	for {
+		ready, err := c.IsOutputReady(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+
+		status, err := c.GetJobStatus(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case JobStatusFailed:
+			return ErrJobFailed
+		case JobStatusCancelled:
+			return ErrJobCancelled
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// GetJobOutputTo copies the job output to w as it is downloaded and returns the number
+// of bytes written, so large outputs can go to a file or an HTTP response without being
+// held in memory. A response other than 200 OK is returned as *APIError, and nothing is
+// written to w. Output that does not match the checksum sent by the server, see
+// WithVerifyOutputChecksum, has been written in full when the *OutputChecksumError is
+// returned, so discard it.
+func (c *BsubClient) GetJobOutputTo(ctx context.Context, jobID JobId, w io.Writer, opts ...CallOption) (int64, error) {
+	resp, err := c.getJobOutput(ctx, jobID, newCallOptions(opts))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get job output: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.apiError("get job output", resp, nil)
+	}
+
+	n, err := c.copyData(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read output: %w", err)
+	}
+
+	return n, nil
+}
+
+// JobResultStream is a job with readers over its output and logs, as returned by
+// GetJobResultStream. The caller must Close it, or close both readers, when done.
+type JobResultStream struct {
+	Job *Job
+	// Output streams the job output, nil when the job has none yet
+	Output io.ReadCloser
+	// Logs streams the job logs, nil when the server has none for the job
+	Logs io.ReadCloser
+	// OutputContentType is the media type the server returned the output in
+	OutputContentType string
+	// OutputContentEncoding is the encoding of the output when it was requested with
+	// WithRawOutput and the server compressed it, and empty otherwise
+	OutputContentEncoding string
+	// OutputContentLength is the size of the output announced by the server, or -1 when
+	// it sent none or there is no output
+	OutputContentLength int64
+}
+
+// Close closes the output and log readers that are open
+func (r *JobResultStream) Close() error {
+	var errs []error
+	for _, body := range []io.ReadCloser{r.Output, r.Logs} {
+		if body != nil {
+			errs = append(errs, body.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetJobResultStream is GetJobResult without buffering: the output and logs are left
+// unread in the returned JobResultStream, so multi-gigabyte outputs can be streamed to
+// their destination. Like GetJobResult, the output is only fetched for finished jobs
+// and, on a best-effort basis, failed ones, and missing logs are not an error.
+func (c *BsubClient) GetJobResultStream(ctx context.Context, jobID JobId, opts ...CallOption) (*JobResultStream, error) {
+	options := newCallOptions(opts)
+
+	job, err := c.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == nil {
+		return nil, ErrMissingJobStatus
+	}
+
+	result := &JobResultStream{
+		Job:                 job,
+		OutputContentLength: -1,
+	}
+
+	// Like GetJobResult, partial output of failed jobs is fetched on a best-effort basis
+	failed := *job.Status == JobStatusFailed
+	if options.outputReady || failed || *job.Status == JobStatusFinished {
+		resp, err := c.getJobOutput(ctx, jobID, options)
+		switch {
+		case err != nil && !failed:
+			return nil, fmt.Errorf("failed to get job output: %w", err)
+		case err != nil:
+		case resp.StatusCode == http.StatusOK:
+			result.Output = resp.Body
+			result.OutputContentType = resp.Header.Get("Content-Type")
+			result.OutputContentEncoding = resp.Header.Get("Content-Encoding")
+			result.OutputContentLength = resp.ContentLength
+		default:
+			resp.Body.Close()
+		}
+	}
+
+	// Logs might not always be available, so we don't fail here
+	if resp, err := c.getJobLogs(ctx, jobID); err == nil {
+		if resp.StatusCode == http.StatusOK {
+			result.Logs = resp.Body
+		} else {
+			resp.Body.Close()
+		}
+	}
+
+	return result, nil
+}
+
+// ProcessStreaming creates, uploads, submits and waits for a job like Process, then
+// downloads the output and passes it to onChunk piece by piece as it arrives, so large
+// outputs can be handled without holding them in memory. Chunks are at most IOBufferSize
+// bytes and are only valid during the call. Returning an error from onChunk aborts the
+// download and ProcessStreaming returns that error.
+//
+// The finished job is returned; a failed job is returned with an error wrapping ErrJobFailed,
+// a cancelled one with ErrJobCancelled, and when ctx is done first the last job snapshot
+// fetched is returned with the context error.
+func (c *BsubClient) ProcessStreaming(ctx context.Context, jobType string, in io.Reader, onChunk func([]byte) error, opts ...CallOption) (*Job, error) {
+	if onChunk == nil {
+		return nil, ErrNilCallback
+	}
+
+	job, err := c.CreateAndSubmitJob(ctx, jobType, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	finishedJob, err := c.WaitForJob(ctx, *job.Id)
+	if err != nil {
+		return finishedJob, fmt.Errorf("failed waiting for job: %w", err)
+	}
+
+	if err := jobEndError(finishedJob); err != nil {
+		return finishedJob, err
+	}
+
+	resp, err := c.getJobOutput(ctx, *job.Id, newCallOptions(opts))
+	if err != nil {
+		return finishedJob, fmt.Errorf("failed to get job output: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return finishedJob, c.apiError("get job output", resp, nil)
+	}
+
+	buf := make([]byte, c.ioBufferSize)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if err := onChunk(buf[:n]); err != nil {
+				return finishedJob, err
+			}
+		}
+		if readErr == io.EOF {
+			return finishedJob, nil
+		}
+		if readErr != nil {
+			return finishedJob, fmt.Errorf("failed to read output: %w", readErr)
+		}
+	}
+}
+
+// ProcessFileToFile processes inputPath as a job of jobType and writes the output to
+// outputPath. The output is streamed into a temporary file next to outputPath, which is
+// renamed over it only once the download is complete, so a failed job or an interrupted
+// download never leaves a partial file behind. The written file has mode 0644.
+//
+// The finished job is returned; a failed job is returned with an error wrapping ErrJobFailed,
+// a cancelled one with ErrJobCancelled, and when ctx is done first the last job snapshot
+// fetched is returned with the context error.
+func (c *BsubClient) ProcessFileToFile(ctx context.Context, jobType string, inputPath string, outputPath string, opts ...CallOption) (*Job, error) {
+	if outputPath == "" {
+		return nil, ErrEmptyFilePath
+	}
+
+	job, err := c.CreateAndSubmitJobFromFile(ctx, jobType, inputPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	finishedJob, err := c.WaitForJob(ctx, *job.Id)
+	if err != nil {
+		return finishedJob, fmt.Errorf("failed waiting for job: %w", err)
+	}
+
+	if err := jobEndError(finishedJob); err != nil {
+		return finishedJob, err
+	}
+
+	if err := c.writeOutputFile(ctx, *job.Id, outputPath, opts); err != nil {
+		return finishedJob, err
+	}
+
+	return finishedJob, nil
+}
+
+// writeOutputFile downloads the job output to a temporary file and renames it to path
+func (c *BsubClient) writeOutputFile(ctx context.Context, jobID JobId, path string, opts []CallOption) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := c.GetJobOutputTo(ctx, jobID, tmp, opts...); err != nil {
+		return err
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// getJobOutput requests the job output with the Accept header from options. Output the
+// server gzips is decompressed unless WithRawOutput was given, in which case the body is
+// left as sent; decoding errors are reported as *DecompressError.
+func (c *BsubClient) getJobOutput(ctx context.Context, jobID JobId, options *callOptions) (*http.Response, error) {
+	var editors []RequestEditorFn
+	if options.accept != "" {
+		editors = append(editors, withHeader("Accept", options.accept))
+	}
+	// Asking for gzip ourselves keeps net/http from decompressing behind our back,
+	// so raw output stays raw
+	editors = append(editors, withHeader("Accept-Encoding", "gzip"))
+
+	resp, err := c.GetJobOutput(ctx, jobID, editors...)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := resp.Header.Get("Content-Encoding")
+	if options.rawOutput || resp.StatusCode != http.StatusOK || !strings.EqualFold(encoding, "gzip") {
+		if resp.StatusCode == http.StatusOK && encoding == "" {
+			verifyOutputChecksum(resp, options)
+		}
+		return resp, nil
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, &DecompressError{Encoding: encoding, Err: err}
+	}
+
+	// The Content-Length header is kept, as the compressed size is still a useful lower
+	// bound for buffers, see outputSizeHint
+	resp.Body = &decompressBody{gzipBody: gzipBody{Reader: zr, body: resp.Body}, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	verifyOutputChecksum(resp, options)
+	return resp, nil
+}
+
+// verifyOutputChecksum makes the body of an output response hash what is read, and fail
+// with *OutputChecksumError at the end if that does not match the server's ChecksumHeader.
+// Responses without the header, and calls with WithVerifyOutputChecksum(false), are left
+// alone.
+func verifyOutputChecksum(resp *http.Response, options *callOptions) {
+	want := resp.Header.Get(ChecksumHeader)
+	if want == "" || options.skipOutputChecksum {
+		return
+	}
+	resp.Body = &checksumBody{ReadCloser: resp.Body, digest: sha256.New(), want: want}
+}
+
+// checksumBody reports a body whose SHA-256 differs from want when it reaches EOF
+type checksumBody struct {
+	io.ReadCloser
+	digest hash.Hash
+	want   string
+}
+
+func (b *checksumBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.digest.Write(p[:n])
+	if err == io.EOF {
+		if got := hex.EncodeToString(b.digest.Sum(nil)); !strings.EqualFold(got, b.want) {
+			return n, &OutputChecksumError{Expected: b.want, Actual: got}
+		}
+	}
+	return n, err
+}
+
+// outputSizeHint returns how many bytes to reserve for an output response body: its
+// length, or for decompressed output the compressed length
+func outputSizeHint(resp *http.Response) int64 {
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength
+	}
+	n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// decompressBody is a gzipBody that reports corrupt data as *DecompressError
+type decompressBody struct {
+	gzipBody
+	encoding string
+}
+
+func (b *decompressBody) Read(p []byte) (int, error) {
+	n, err := b.gzipBody.Read(p)
+	var corrupt flate.CorruptInputError
+	if errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) || errors.As(err, &corrupt) {
+		err = &DecompressError{Encoding: b.encoding, Err: err}
+	}
+	return n, err
+}
+
+// withMethod returns a request editor that changes the HTTP method of a single call
+func withMethod(method string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Method = method
+		return nil
+	}
+}
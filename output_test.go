@@ -0,0 +1,446 @@
+package bsubio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsOutputReady tests checking output availability without downloading it
+func TestIsOutputReady(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Unsubmitted job test only supported in mock mode")
+	}
+
+	ctx := context.Background()
+
+	job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")))
+	require.NoError(t, err)
+
+	ready, err := client.IsOutputReady(ctx, *job.Id)
+	require.NoError(t, err)
+	assert.True(t, ready)
+	assert.Equal(t, "HEAD", mockServer.LastRequest().Method)
+
+	resp, err := client.CreateJobWithResponse(ctx, CreateJobJSONRequestBody{Type: "test/linecount"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.JSON201)
+
+	ready, err = client.IsOutputReady(ctx, *resp.JSON201.Data.Id)
+	require.NoError(t, err)
+	assert.False(t, ready)
+}
+
+// TestWaitForOutput tests waiting for the output instead of the job status
+func TestWaitForOutput(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Lagging status only supported in mock mode")
+	}
+
+	t.Run("output before status", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		// The status never reaches finished, so WaitForJob would not return
+		mockServer.SetProgression("test/lagging", JobStatusProcessing)
+		mockServer.SetEarlyOutput("test/lagging")
+
+		ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+		defer cancel()
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/lagging", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		require.NoError(t, client.WaitForOutput(ctx, *job.Id))
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/broken", JobStatusProcessing, JobStatusFailed)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+		defer cancel()
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		assert.ErrorIs(t, client.WaitForOutput(ctx, *job.Id), ErrJobFailed)
+	})
+
+	t.Run("process", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/lagging", JobStatusProcessing)
+		mockServer.SetEarlyOutput("test/lagging")
+		mockServer.SetOutputFunc("test/lagging", func(uploadedData []byte) []byte {
+			return bytes.ToUpper(uploadedData)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+		defer cancel()
+
+		result, err := client.Process(ctx, "test/lagging", bytes.NewReader([]byte("data")), WithWaitForOutput())
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusProcessing, *result.Job.Status)
+		assert.Equal(t, "DATA", string(result.Output))
+	})
+
+	t.Run("process failed job", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/broken", JobStatusFailed)
+
+		ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+		defer cancel()
+
+		result, err := client.Process(ctx, "test/broken", bytes.NewReader([]byte("data")), WithWaitForOutput())
+		assert.ErrorIs(t, err, ErrJobFailed)
+		require.NotNil(t, result)
+		assert.Nil(t, result.Output)
+	})
+}
+
+// TestProcessStreaming tests receiving the output through a chunk callback
+func TestProcessStreaming(t *testing.T) {
+	t.Run("chunks add up to the output", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Large output only supported in mock mode")
+		}
+
+		output := bytes.Repeat([]byte("0123456789"), DefaultIOBufferSize/2)
+		mockServer.SetOutputFunc("test/linecount", func([]byte) []byte { return output })
+
+		var received bytes.Buffer
+		chunks := 0
+		job, err := client.ProcessStreaming(context.Background(), "test/linecount", bytes.NewReader([]byte("data")), func(chunk []byte) error {
+			assert.LessOrEqual(t, len(chunk), DefaultIOBufferSize)
+			chunks++
+			received.Write(chunk)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *job.Status)
+		assert.Equal(t, output, received.Bytes())
+		assert.Greater(t, chunks, 1)
+	})
+
+	t.Run("callback error aborts", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		errStop := errors.New("stop")
+		calls := 0
+		_, err := client.ProcessStreaming(context.Background(), "test/linecount", bytes.NewReader([]byte("data")), func([]byte) error {
+			calls++
+			return errStop
+		})
+
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted failure only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+		mockServer.SetProgression("test/broken", JobStatusFailed)
+
+		job, err := client.ProcessStreaming(context.Background(), "test/broken", bytes.NewReader([]byte("data")), func([]byte) error {
+			t.Fatal("no output expected for a failed job")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, ErrJobFailed)
+		require.NotNil(t, job)
+		assert.Equal(t, JobStatusFailed, *job.Status)
+	})
+}
+
+// TestGetJobOutputTo tests streaming the output of a finished job to a writer
+func TestGetJobOutputTo(t *testing.T) {
+	client, _, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	result, err := client.Process(ctx, "test/linecount", bytes.NewReader([]byte("a\nb\nc")))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	n, err := client.GetJobOutputTo(ctx, *result.Job.Id, &out)
+	require.NoError(t, err)
+	assert.Equal(t, int64(out.Len()), n)
+	assert.Equal(t, result.Output, out.Bytes())
+
+	t.Run("unexpected status", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusNotFound, `{"error":"not found"}`)
+
+		var out bytes.Buffer
+		n, err := client.GetJobOutputTo(ctx, uuid.New(), &out)
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr), "error: %v", err)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Zero(t, n)
+		assert.Zero(t, out.Len())
+	})
+}
+
+// TestGetJobResultStream tests getting a job with unread output and logs
+func TestGetJobResultStream(t *testing.T) {
+	client, _, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	result, err := client.Process(ctx, "test/linecount", bytes.NewReader([]byte("a\nb\nc")))
+	require.NoError(t, err)
+
+	t.Run("finished job", func(t *testing.T) {
+		stream, err := client.GetJobResultStream(ctx, *result.Job.Id)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, JobStatusFinished, *stream.Job.Status)
+		require.NotNil(t, stream.Output)
+		output, err := io.ReadAll(stream.Output)
+		require.NoError(t, err)
+		assert.Equal(t, result.Output, output)
+		assert.Equal(t, result.OutputContentType, stream.OutputContentType)
+		assert.Equal(t, int64(len(output)), stream.OutputContentLength)
+
+		require.NotNil(t, stream.Logs)
+		logs, err := io.ReadAll(stream.Logs)
+		require.NoError(t, err)
+		assert.Equal(t, result.Logs, string(logs))
+	})
+
+	t.Run("unfinished job has no output", func(t *testing.T) {
+		job, err := client.CreateJob(ctx, "test/linecount")
+		require.NoError(t, err)
+
+		stream, err := client.GetJobResultStream(ctx, *job.Id)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Nil(t, stream.Output)
+		assert.Equal(t, int64(-1), stream.OutputContentLength)
+	})
+
+	t.Run("missing job", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusNotFound, `{"error":"not found"}`)
+
+		_, err := client.GetJobResultStream(ctx, uuid.New())
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	})
+}
+
+// TestProcessFileToFile tests writing the output to a file, and leaving no partial file on failure
+func TestProcessFileToFile(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Failing job types only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(input, []byte("a\nb\nc"), 0644))
+
+	ctx := context.Background()
+	job, err := client.ProcessFileToFile(ctx, "test/linecount", input, output)
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusFinished, job.CurrentStatus())
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "3", strings.TrimSpace(string(data)))
+
+	t.Run("failed job keeps the previous output", func(t *testing.T) {
+		mockServer.SetProgression("test/broken", JobStatusFailed)
+
+		job, err := client.ProcessFileToFile(ctx, "test/broken", input, output)
+		assert.ErrorIs(t, err, ErrJobFailed)
+		assert.Equal(t, JobStatusFailed, job.CurrentStatus())
+
+		after, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, data, after)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("missing output is not written", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusNotFound, `{"error":"not found"}`)
+
+		err := client.writeOutputFile(ctx, uuid.New(), filepath.Join(dir, "missing.txt"), nil)
+		assert.Error(t, err)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	_, err = client.ProcessFileToFile(ctx, "test/linecount", input, "")
+	assert.ErrorIs(t, err, ErrEmptyFilePath)
+}
+
+// TestOutputDecompression tests decoding gzipped output, keeping it raw on request and
+// reporting corrupt output
+func TestOutputDecompression(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Compressed output only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	output := bytes.Repeat([]byte("compressible output\n"), 1024)
+	mockServer.SetOutput("test/text", output)
+	mockServer.SetJobOutcome("test/text", JobStatusFinished, "", "")
+	mockServer.SetGzipOutput(true)
+
+	ctx := context.Background()
+	result, err := client.Process(ctx, "test/text", strings.NewReader("data"))
+	require.NoError(t, err)
+	assert.Equal(t, output, result.Output)
+	assert.Empty(t, result.OutputContentEncoding)
+	assert.Equal(t, int64(-1), result.OutputContentLength)
+
+	t.Run("raw output", func(t *testing.T) {
+		raw, err := client.GetJobResult(ctx, *result.Job.Id, WithRawOutput())
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", raw.OutputContentEncoding)
+		assert.Equal(t, int64(len(raw.Output)), raw.OutputContentLength)
+
+		zr, err := gzip.NewReader(bytes.NewReader(raw.Output))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, output, decoded)
+	})
+
+	t.Run("streamed", func(t *testing.T) {
+		var out bytes.Buffer
+		n, err := client.GetJobOutputTo(ctx, *result.Job.Id, &out)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(output)), n)
+		assert.Equal(t, output, out.Bytes())
+	})
+
+	t.Run("corrupt output", func(t *testing.T) {
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		_, _ = zw.Write(output)
+		require.NoError(t, zw.Close())
+		corrupt := compressed.Bytes()
+		corrupt[len(corrupt)-5] ^= 0xff // Break the checksum
+
+		for name, body := range map[string][]byte{"bad header": []byte("not gzip"), "bad checksum": corrupt} {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL})
+			require.NoError(t, err)
+
+			_, err = client.GetJobOutputTo(ctx, uuid.New(), io.Discard)
+			var decompressErr *DecompressError
+			require.ErrorAs(t, err, &decompressErr, name)
+			assert.Equal(t, "gzip", decompressErr.Encoding, name)
+		}
+	})
+}
+
+// TestOutputChecksum tests verifying downloaded output against the server's checksum
+func TestOutputChecksum(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Output checksums only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	output := bytes.Repeat([]byte("archived output\n"), 256)
+	mockServer.SetOutput("test/text", output)
+	mockServer.SetJobOutcome("test/text", JobStatusFinished, "", "")
+	mockServer.SetOutputChecksums(true)
+
+	ctx := context.Background()
+	result, err := client.Process(ctx, "test/text", strings.NewReader("data"))
+	require.NoError(t, err)
+	assert.Equal(t, output, result.Output)
+	jobID := *result.Job.Id
+
+	t.Run("corrupt output", func(t *testing.T) {
+		mockServer.CorruptOutputs(1)
+		_, err := client.GetJobResult(ctx, jobID)
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+
+		var checksumErr *OutputChecksumError
+		require.ErrorAs(t, err, &checksumErr)
+		assert.NotEqual(t, checksumErr.Expected, checksumErr.Actual)
+
+		mockServer.CorruptOutputs(1)
+		_, err = client.GetJobOutputTo(ctx, jobID, io.Discard)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("compressed output", func(t *testing.T) {
+		mockServer.SetGzipOutput(true)
+		defer mockServer.SetGzipOutput(false)
+
+		var out bytes.Buffer
+		_, err := client.GetJobOutputTo(ctx, jobID, &out)
+		require.NoError(t, err)
+		assert.Equal(t, output, out.Bytes())
+
+		mockServer.CorruptOutputs(1)
+		_, err = client.GetJobOutputTo(ctx, jobID, io.Discard)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("verification disabled", func(t *testing.T) {
+		mockServer.CorruptOutputs(1)
+		corrupt, err := client.GetJobResult(ctx, jobID, WithVerifyOutputChecksum(false))
+		require.NoError(t, err)
+		assert.NotEqual(t, output, corrupt.Output)
+	})
+}
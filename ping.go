@@ -0,0 +1,44 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors returned by Ping
+var (
+	// ErrInvalidAPIKey is returned when the server rejects the API key
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrServerUnreachable is returned when no response could be had from the server,
+	// e.g. because of a DNS failure or a refused connection
+	ErrServerUnreachable = errors.New("bsub.io server unreachable")
+)
+
+// Ping checks that the server is reachable and accepts the API key, with a single
+// authenticated request for at most one job. Run it before starting a batch to fail
+// fast on configuration problems rather than partway through.
+//
+// It returns nil when all is well, an error wrapping ErrInvalidAPIKey when the server
+// answers 401 Unauthorized, one wrapping ErrServerUnreachable and the network error
+// when there is no response, and an *APIError for any other status.
+func (c *BsubClient) Ping(ctx context.Context) error {
+	limit := 1
+	resp, err := c.ListJobsWithResponse(ctx, &ListJobsParams{Limit: &limit})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %w", ErrServerUnreachable, err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: %w", ErrInvalidAPIKey, c.apiError("ping", resp.HTTPResponse, resp.Body))
+	default:
+		return c.apiError("ping", resp.HTTPResponse, resp.Body)
+	}
+}
@@ -0,0 +1,56 @@
+package bsubio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPing tests the pre-flight check and how it reports each kind of problem
+func TestPing(t *testing.T) {
+	t.Run("reachable", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		require.NoError(t, client.Ping(context.Background()))
+	})
+
+	t.Run("invalid API key", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusUnauthorized, `{"error":"invalid token"}`)
+
+		err := client.Ping(context.Background())
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "invalid token", apiErr.Message)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusInternalServerError, `{"error":"boom"}`)
+
+		err := client.Ping(context.Background())
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.NotErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, client.Ping(context.Background()), ErrServerUnreachable)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		assert.ErrorIs(t, client.Ping(ctx), context.DeadlineExceeded)
+	})
+}
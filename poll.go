@@ -0,0 +1,61 @@
+package bsubio
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// WaitForJobOptions configures how WaitForJobWithOptions spaces its status requests.
+// The zero value polls at the client's fixed interval, like WaitForJob.
+type WaitForJobOptions struct {
+	// InitialInterval is the delay before the second status request
+	// (defaults to the client poll interval, 2s)
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between requests (defaults to no cap)
+	MaxInterval time.Duration
+	// Multiplier grows the delay after every request that sees no status change;
+	// values below 1 keep it fixed
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it, between 0 and 1,
+	// so many waiters do not poll in lockstep
+	Jitter float64
+}
+
+// pollBackoff tracks the delay between status requests of a single wait
+type pollBackoff struct {
+	opts     WaitForJobOptions
+	interval time.Duration
+}
+
+// newPollBackoff fills in the defaults of opts, falling back to interval
+func newPollBackoff(opts WaitForJobOptions, interval time.Duration) *pollBackoff {
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = interval
+	}
+	if opts.MaxInterval > 0 && opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+	opts.Multiplier = max(opts.Multiplier, 1)
+	opts.Jitter = min(max(opts.Jitter, 0), 1)
+
+	return &pollBackoff{opts: opts, interval: opts.InitialInterval}
+}
+
+// next returns how long to sleep before the next request and grows the interval
+func (b *pollBackoff) next() time.Duration {
+	delay := b.interval
+	if b.opts.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * b.opts.Jitter * float64(delay))
+	}
+
+	b.interval = time.Duration(float64(b.interval) * b.opts.Multiplier)
+	if b.opts.MaxInterval > 0 && b.interval > b.opts.MaxInterval {
+		b.interval = b.opts.MaxInterval
+	}
+	return delay
+}
+
+// reset goes back to the initial interval, after the job made progress
+func (b *pollBackoff) reset() {
+	b.interval = b.opts.InitialInterval
+}
@@ -0,0 +1,320 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPollBackoff tests the delays between status requests
+func TestPollBackoff(t *testing.T) {
+	t.Run("defaults to the fixed client interval", func(t *testing.T) {
+		backoff := newPollBackoff(WaitForJobOptions{}, 2*time.Second)
+		for range 3 {
+			assert.Equal(t, 2*time.Second, backoff.next())
+		}
+	})
+
+	t.Run("exponential with cap and reset", func(t *testing.T) {
+		backoff := newPollBackoff(WaitForJobOptions{
+			InitialInterval: 100 * time.Millisecond,
+			MaxInterval:     300 * time.Millisecond,
+			Multiplier:      2,
+		}, 2*time.Second)
+
+		var delays []time.Duration
+		for range 4 {
+			delays = append(delays, backoff.next())
+		}
+		assert.Equal(t, []time.Duration{
+			100 * time.Millisecond,
+			200 * time.Millisecond,
+			300 * time.Millisecond,
+			300 * time.Millisecond,
+		}, delays)
+
+		backoff.reset()
+		assert.Equal(t, 100*time.Millisecond, backoff.next())
+	})
+
+	t.Run("jitter stays within bounds", func(t *testing.T) {
+		backoff := newPollBackoff(WaitForJobOptions{InitialInterval: time.Second, Jitter: 0.25}, 0)
+		for range 100 {
+			delay := backoff.next()
+			assert.GreaterOrEqual(t, delay, 750*time.Millisecond)
+			assert.LessOrEqual(t, delay, 1250*time.Millisecond)
+		}
+	})
+}
+
+// TestWaitForJobWithOptions tests waiting with a custom backoff
+func TestWaitForJobWithOptions(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+
+	mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusProcessing, JobStatusFinished)
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	finished, err := client.WaitForJobWithOptions(ctx, *job.Id, WaitForJobOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+		Multiplier:      2,
+		Jitter:          0.5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusFinished, finished.CurrentStatus())
+
+	t.Run("cancelled during sleep", func(t *testing.T) {
+		mockServer.SetProgression("test/stuck", JobStatusProcessing)
+		stuck, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = client.WaitForJobWithOptions(timeoutCtx, *stuck.Id, WaitForJobOptions{InitialInterval: time.Minute})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
+
+// TestWaitForJobWithCallback tests that every polled snapshot reaches the callback
+func TestWaitForJobWithCallback(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusFinished)
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	var statuses []JobStatus
+	finished, err := client.WaitForJobWithCallback(ctx, *job.Id, func(job *Job) {
+		statuses = append(statuses, job.CurrentStatus())
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []JobStatus{JobStatusClaimed, JobStatusProcessing, JobStatusFinished}, statuses)
+	assert.Equal(t, JobStatusFinished, finished.CurrentStatus())
+
+	_, err = client.WaitForJobWithCallback(ctx, *job.Id, nil)
+	assert.ErrorIs(t, err, ErrNilCallback)
+
+	t.Run("process option", func(t *testing.T) {
+		var polls int
+		result, err := client.Process(ctx, "test/slow", bytes.NewReader([]byte("data")), WithPollCallback(func(job *Job) {
+			polls++
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, result.Job.CurrentStatus())
+		assert.Equal(t, 3, polls)
+	})
+}
+
+// TestWaitForJobStatus tests waiting for an intermediate status
+func TestWaitForJobStatus(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusFinished)
+	mockServer.SetProgression("test/broken", JobStatusClaimed, JobStatusFailed)
+
+	ctx := context.Background()
+
+	t.Run("target reached", func(t *testing.T) {
+		job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		processing, err := client.WaitForJobStatus(ctx, *job.Id, JobStatusProcessing)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusProcessing, processing.CurrentStatus())
+
+		finished, err := client.WaitForJobStatus(ctx, *job.Id, JobStatusFinished)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, finished.CurrentStatus())
+	})
+
+	t.Run("target passed between polls", func(t *testing.T) {
+		mockServer.SetProgression("test/fast", JobStatusClaimed, JobStatusFinished)
+		job, err := client.CreateAndSubmitJob(ctx, "test/fast", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		passed, err := client.WaitForJobStatus(ctx, *job.Id, JobStatusProcessing)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, passed.CurrentStatus())
+	})
+
+	t.Run("job ends first", func(t *testing.T) {
+		job, err := client.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		failed, err := client.WaitForJobStatus(ctx, *job.Id, JobStatusProcessing)
+		require.ErrorIs(t, err, ErrStatusNotReached)
+		assert.Contains(t, err.Error(), "ended as failed")
+		require.NotNil(t, failed)
+		assert.Equal(t, JobStatusFailed, failed.CurrentStatus())
+	})
+}
+
+// TestWaitForJobResult tests waiting for a job and fetching its result in one call
+func TestWaitForJobResult(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/slow", JobStatusProcessing, JobStatusFinished)
+	mockServer.SetOutput("test/slow", []byte("done"))
+	mockServer.SetJobOutcome("test/broken", JobStatusFailed, "invalid_input", "cannot parse input")
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	result, err := client.WaitForJobResult(ctx, *job.Id)
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusFinished, *result.Job.Status)
+	assert.Equal(t, "done", result.OutputString())
+	assert.NotEmpty(t, result.Logs)
+
+	job, err = client.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	result, err = client.WaitForJobResult(ctx, *job.Id)
+	var failed *JobFailedError
+	require.ErrorAs(t, err, &failed)
+	assert.Equal(t, "invalid_input", failed.Code)
+	assert.Equal(t, "cannot parse input", failed.Message)
+	require.NotNil(t, result)
+	assert.Equal(t, JobStatusFailed, *result.Job.Status)
+
+	t.Run("failed job that cannot be fetched again", func(t *testing.T) {
+		// The request for the result right after the wait saw the job fail is refused,
+		// which must not lose the job or its error
+		var sawFailure, refused bool
+		failing, err := NewBsubClient(Config{
+			APIKey:  "test-api-key",
+			BaseURL: mockServer.URL,
+			ResponseInterceptors: []ResponseInterceptor{func(resp *http.Response) error {
+				if operationName(resp.Request) != "GetJob" {
+					return nil
+				}
+				if sawFailure && !refused {
+					refused = true
+					return errors.New("job unavailable")
+				}
+				body, err := io.ReadAll(resp.Body)
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+				sawFailure = err == nil && bytes.Contains(body, []byte(`"status":"failed"`))
+				return nil
+			}},
+		})
+		require.NoError(t, err)
+		failing.pollInterval = testPollInterval
+
+		job, err := failing.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		for name, opts := range map[string][]CallOption{"status": nil, "output": {WithWaitForOutput()}} {
+			sawFailure, refused = false, false
+			result, err := failing.WaitForJobResult(ctx, *job.Id, opts...)
+			assert.True(t, refused, name)
+			var failed *JobFailedError
+			require.ErrorAs(t, err, &failed, name)
+			assert.Equal(t, "invalid_input", failed.Code, name)
+			assert.Equal(t, "cannot parse input", failed.Message, name)
+			require.NotNil(t, result, name)
+			assert.Equal(t, JobStatusFailed, *result.Job.Status, name)
+			assert.Equal(t, int64(-1), result.OutputContentLength, name)
+		}
+	})
+}
+
+// TestWaitDeadlineSnapshot tests that the helpers built on WaitForJob pass on the last
+// job snapshot when ctx ends before the job does
+func TestWaitDeadlineSnapshot(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/stuck", JobStatusProcessing)
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+	withDeadline := func(t *testing.T) context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+		t.Cleanup(cancel)
+		return ctx
+	}
+	assertSnapshot := func(t *testing.T, job *Job, err error) {
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotNil(t, job)
+		assert.Equal(t, JobStatusProcessing, *job.Status)
+	}
+
+	t.Run("Process", func(t *testing.T) {
+		result, err := client.Process(withDeadline(t), "test/stuck", bytes.NewReader([]byte("data")))
+		require.NotNil(t, result)
+		assertSnapshot(t, result.Job, err)
+		assert.Equal(t, int64(-1), result.OutputContentLength)
+	})
+
+	t.Run("ProcessFile", func(t *testing.T) {
+		result, err := client.ProcessFile(withDeadline(t), "test/stuck", path)
+		require.NotNil(t, result)
+		assertSnapshot(t, result.Job, err)
+	})
+
+	t.Run("ProcessStreaming", func(t *testing.T) {
+		job, err := client.ProcessStreaming(withDeadline(t), "test/stuck", bytes.NewReader([]byte("data")),
+			func([]byte) error { return nil })
+		assertSnapshot(t, job, err)
+	})
+
+	t.Run("ProcessFileToFile", func(t *testing.T) {
+		job, err := client.ProcessFileToFile(withDeadline(t), "test/stuck", path, filepath.Join(t.TempDir(), "output.txt"))
+		assertSnapshot(t, job, err)
+	})
+
+	t.Run("ProcessBatch", func(t *testing.T) {
+		results := client.ProcessBatch(withDeadline(t), "test/stuck", []string{path}, BatchOptions{})
+		require.Len(t, results, 1)
+		require.NotNil(t, results[0].Result)
+		assertSnapshot(t, results[0].Result.Job, results[0].Err)
+	})
+}
@@ -0,0 +1,98 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// ErrorCodeRetryPredicate decides whether a job that failed with the given error code
+// is worth running again, e.g. retrying "worker_evicted" but not "invalid_input".
+// IsRetryableErrorCode is one for the known error codes.
+type ErrorCodeRetryPredicate func(code string) bool
+
+// processWithRetry submits a job with submit and waits for its result, submitting it
+// again while it fails and the WithJobRetry settings allow another attempt. Each
+// submission and each wait is bounded by the matching timeout of phases.
+func (c *BsubClient) processWithRetry(ctx context.Context, phases ProcessOptions, opts []CallOption, submit func(context.Context) (*Job, error)) (*JobResult, error) {
+	options := newCallOptions(opts)
+	var codes []string
+
+	for attempt := 1; ; attempt++ {
+		job, err := runPhase(ctx, phases.UploadTimeout, ErrUploadTimeout, submit)
+		if err != nil {
+			return nil, err
+		}
+		c.setSpanJob(ctx, job)
+
+		result, err := runPhase(ctx, phases.WaitTimeout, ErrWaitTimeout, func(ctx context.Context) (*JobResult, error) {
+			return c.WaitForJobResult(ctx, *job.Id, opts...)
+		})
+
+		var failed *JobFailedError
+		if !errors.As(err, &failed) {
+			return result, err
+		}
+
+		if failed.Code != "" && !slices.Contains(codes, failed.Code) {
+			codes = append(codes, failed.Code)
+		}
+		failed.Attempts = attempt
+		failed.Codes = codes
+
+		if attempt >= options.jobAttempts || (options.retryIf != nil && !options.retryIf(failed.Code)) {
+			return result, failed
+		}
+		if ctx.Err() != nil {
+			return result, failed
+		}
+		c.stats.retries.Add(1)
+	}
+}
+
+// runPhase runs fn with ctx limited to timeout, if it is positive. An error caused by
+// the timeout expiring is wrapped in cause, so callers can tell which phase ran out.
+func runPhase[T any](ctx context.Context, timeout time.Duration, cause error, fn func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	// The cause wraps DeadlineExceeded too, since requests interrupted by the timeout
+	// fail with the cause rather than the context error
+	phaseCtx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w: %w", cause, context.DeadlineExceeded))
+	defer cancel()
+
+	v, err := fn(phaseCtx)
+	if err != nil && errors.Is(context.Cause(phaseCtx), cause) {
+		err = fmt.Errorf("%w after %s: %w", cause, timeout, err)
+	}
+	return v, err
+}
+
+// newReplayableReader returns a function that yields the contents of r from the start
+// on every call. Seekable readers are rewound; anything else is read into memory once.
+func newReplayableReader(r io.Reader) (func() (io.Reader, error), error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			return func() (io.Reader, error) {
+				if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+					return nil, fmt.Errorf("failed to rewind input: %w", err)
+				}
+				return seeker, nil
+			}, nil
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	return func() (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	}, nil
+}
@@ -0,0 +1,111 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithJobRetry tests re-running failed jobs depending on their error code
+func TestWithJobRetry(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Scripted failures only supported in mock mode")
+	}
+
+	transient := func(code string) bool { return code == "worker_evicted" }
+
+	t.Run("retries until success", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.FailNextJobs("test/linecount", "worker_evicted", "worker_evicted")
+
+		// A reader that cannot seek is buffered for the re-runs
+		input := strings.NewReader("a\nb\nc")
+		result, err := client.Process(context.Background(), "test/linecount", struct{ io.Reader }{input}, WithJobRetry(3, transient))
+
+		require.NoError(t, err)
+		assert.Equal(t, "3", string(result.Output))
+	})
+
+	t.Run("stops on a code the predicate rejects", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.FailNextJobs("test/linecount", "worker_evicted", "invalid_input")
+
+		result, err := client.Process(context.Background(), "test/linecount", bytes.NewReader([]byte("a")), WithJobRetry(5, transient))
+
+		var failed *JobFailedError
+		require.ErrorAs(t, err, &failed)
+		assert.ErrorIs(t, err, ErrJobFailed)
+		assert.Equal(t, 2, failed.Attempts)
+		assert.Equal(t, "invalid_input", failed.Code)
+		assert.Equal(t, []string{"worker_evicted", "invalid_input"}, failed.Codes)
+		assert.Contains(t, err.Error(), "after 2 attempts, error codes: worker_evicted, invalid_input")
+		require.NotNil(t, result)
+		assert.Equal(t, JobStatusFailed, *result.Job.Status)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.FailNextJobs("test/linecount", "worker_evicted", "worker_evicted", "worker_evicted")
+
+		dir := t.TempDir()
+		path := filepath.Join(dir, "input.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a"), 0644))
+
+		_, err := client.ProcessFile(context.Background(), "test/linecount", path, WithJobRetry(2, nil))
+
+		var failed *JobFailedError
+		require.ErrorAs(t, err, &failed)
+		assert.Equal(t, 2, failed.Attempts)
+		assert.Equal(t, []string{"worker_evicted"}, failed.Codes)
+	})
+
+	t.Run("no retry by default", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.FailNextJobs("test/linecount", "worker_evicted")
+
+		_, err := client.Process(context.Background(), "test/linecount", bytes.NewReader([]byte("a")))
+
+		var failed *JobFailedError
+		require.ErrorAs(t, err, &failed)
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Equal(t, "job failed: mock failure: worker_evicted", err.Error())
+	})
+}
+
+// TestNewReplayableReader tests replaying seekable and plain readers
+func TestNewReplayableReader(t *testing.T) {
+	for name, r := range map[string]io.Reader{
+		"seeker": bytes.NewReader([]byte("xxdata")),
+		"plain":  struct{ io.Reader }{bytes.NewReader([]byte("xxdata"))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Replays start where the reader was when it was handed over
+			skip := make([]byte, 2)
+			_, err := r.Read(skip)
+			require.NoError(t, err)
+
+			replay, err := newReplayableReader(r)
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				input, err := replay()
+				require.NoError(t, err)
+				var buf bytes.Buffer
+				_, err = buf.ReadFrom(input)
+				require.NoError(t, err)
+				assert.Equal(t, "data", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package bsubio
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientStats is a snapshot of the counters a client accumulates over its lifetime
+type ClientStats struct {
+	// Requests counts API requests by operation, e.g. "GetJob" or "UploadJobData"
+	Requests map[string]int64
+	// Retries counts attempts repeated after a failure
+	Retries int64
+	// BytesUploaded and BytesDownloaded count request and response body bytes
+	BytesUploaded   int64
+	BytesDownloaded int64
+	// JobWaitTime is the total time spent waiting for jobs to finish
+	JobWaitTime time.Duration
+}
+
+// TotalRequests returns the number of requests across all operations
+func (s ClientStats) TotalRequests() int64 {
+	var total int64
+	for _, n := range s.Requests {
+		total += n
+	}
+	return total
+}
+
+// clientStats holds the live counters behind Stats. Every counter is updated
+// atomically, so collecting them costs next to nothing and is always on.
+type clientStats struct {
+	requests        sync.Map // operation name -> *atomic.Int64
+	retries         atomic.Int64
+	bytesUploaded   atomic.Int64
+	bytesDownloaded atomic.Int64
+	jobWait         atomic.Int64 // nanoseconds
+}
+
+// countRequest counts one request of the operation
+func (s *clientStats) countRequest(operation string) {
+	counter, ok := s.requests.Load(operation)
+	if !ok {
+		counter, _ = s.requests.LoadOrStore(operation, new(atomic.Int64))
+	}
+	counter.(*atomic.Int64).Add(1)
+}
+
+// addJobWait adds the time since start to the job wait time
+func (s *clientStats) addJobWait(start time.Time) {
+	s.jobWait.Add(int64(time.Since(start)))
+}
+
+// Stats returns the counters accumulated by the client since it was created or
+// ResetStats was last called. Counters are read one by one while requests may be
+// in flight, so a snapshot taken under load is not an exact point in time.
+func (c *BsubClient) Stats() ClientStats {
+	stats := ClientStats{
+		Requests:        make(map[string]int64),
+		Retries:         c.stats.retries.Load(),
+		BytesUploaded:   c.stats.bytesUploaded.Load(),
+		BytesDownloaded: c.stats.bytesDownloaded.Load(),
+		JobWaitTime:     time.Duration(c.stats.jobWait.Load()),
+	}
+
+	c.stats.requests.Range(func(operation, counter any) bool {
+		if n := counter.(*atomic.Int64).Load(); n > 0 {
+			stats.Requests[operation.(string)] = n
+		}
+		return true
+	})
+
+	return stats
+}
+
+// ResetStats sets all counters back to zero, e.g. to report per period on a long-lived client
+func (c *BsubClient) ResetStats() {
+	c.stats.requests.Range(func(_, counter any) bool {
+		counter.(*atomic.Int64).Store(0)
+		return true
+	})
+	c.stats.retries.Store(0)
+	c.stats.bytesUploaded.Store(0)
+	c.stats.bytesDownloaded.Store(0)
+	c.stats.jobWait.Store(0)
+}
+
+// operationName names the API operation a request belongs to, after the generated
+// client methods. Requests to unknown endpoints are named by method and path.
+func operationName(req *http.Request) string {
+	segments := apiPathSegments(req.URL.Path)
+
+	switch {
+	case len(segments) == 2 && segments[1] == "jobs":
+		if req.Method == http.MethodPost {
+			return "CreateJob"
+		}
+		return "ListJobs"
+	case len(segments) == 3 && segments[1] == "jobs":
+		if req.Method == http.MethodDelete {
+			return "DeleteJob"
+		}
+		return "GetJob"
+	case len(segments) == 4 && segments[1] == "jobs":
+		switch segments[3] {
+		case "cancel":
+			return "CancelJob"
+		case "submit":
+			return "SubmitJob"
+		case "logs":
+			return "GetJobLogs"
+		case "output":
+			return "GetJobOutput"
+		}
+	case len(segments) == 3 && segments[1] == "upload":
+		return "UploadJobData"
+	case len(segments) == 2 && segments[1] == "types":
+		return "GetTypes"
+	case len(segments) == 2 && segments[1] == "version":
+		return "GetVersion"
+	}
+
+	return req.Method + " " + req.URL.Path
+}
+
+// apiPathSegments splits an API path into its segments, starting at the API version
+// so any base path in front of it is dropped
+func apiPathSegments(path string) []string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "v1" {
+			return segments[i:]
+		}
+	}
+	return segments
+}
@@ -0,0 +1,102 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStats tests the counters accumulated across requests
+func TestStats(t *testing.T) {
+	t.Run("counts a processed job", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		input := []byte("line1\nline2\nline3")
+		_, err := client.Process(context.Background(), "test/linecount", bytes.NewReader(input))
+		require.NoError(t, err)
+
+		stats := client.Stats()
+		assert.Equal(t, int64(1), stats.Requests["CreateJob"])
+		assert.Equal(t, int64(1), stats.Requests["UploadJobData"])
+		assert.Equal(t, int64(1), stats.Requests["SubmitJob"])
+		assert.Equal(t, int64(1), stats.Requests["GetJobOutput"])
+		assert.Equal(t, int64(1), stats.Requests["GetJobLogs"])
+		assert.GreaterOrEqual(t, stats.Requests["GetJob"], int64(1))
+		assert.Equal(t, stats.TotalRequests(), int64(5)+stats.Requests["GetJob"])
+		assert.Greater(t, stats.BytesUploaded, int64(len(input)))
+		assert.Positive(t, stats.BytesDownloaded)
+		assert.Positive(t, stats.JobWaitTime)
+
+		client.ResetStats()
+		assert.Equal(t, ClientStats{Requests: map[string]int64{}}, client.Stats())
+	})
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		const workers = 8
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.CreateAndSubmitJob(context.Background(), "test/linecount", bytes.NewReader([]byte("data")))
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		stats := client.Stats()
+		assert.Equal(t, int64(workers), stats.Requests["CreateJob"])
+		assert.Equal(t, int64(workers), stats.Requests["SubmitJob"])
+	})
+
+	t.Run("job retries", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted failures only supported in mock mode")
+		}
+		mockServer.FailNextJobs("test/linecount", "worker_evicted")
+
+		_, err := client.Process(context.Background(), "test/linecount", bytes.NewReader([]byte("a")), WithJobRetry(2, nil))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), client.Stats().Retries)
+	})
+}
+
+// TestOperationName tests naming requests after the API operations
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodPost, "/v1/jobs", "CreateJob"},
+		{http.MethodGet, "/v1/jobs", "ListJobs"},
+		{http.MethodGet, "/v1/jobs/0b7d5d4e-0000-0000-0000-000000000000", "GetJob"},
+		{http.MethodDelete, "/v1/jobs/0b7d5d4e-0000-0000-0000-000000000000", "DeleteJob"},
+		{http.MethodPost, "/v1/jobs/0b7d5d4e-0000-0000-0000-000000000000/cancel", "CancelJob"},
+		{http.MethodPost, "/v1/jobs/0b7d5d4e-0000-0000-0000-000000000000/submit", "SubmitJob"},
+		{http.MethodGet, "/v1/jobs/0b7d5d4e-0000-0000-0000-000000000000/logs", "GetJobLogs"},
+		{http.MethodHead, "/v1/jobs/0b7d5d4e-0000-0000-0000-000000000000/output", "GetJobOutput"},
+		{http.MethodPatch, "/v1/upload/0b7d5d4e-0000-0000-0000-000000000000", "UploadJobData"},
+		{http.MethodGet, "/api/v1/types", "GetTypes"},
+		{http.MethodGet, "/v1/version", "GetVersion"},
+		{http.MethodGet, "/healthz", "GET /healthz"},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, "https://app.bsub.io"+tt.path, nil)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, operationName(req), "%s %s", tt.method, tt.path)
+	}
+}
@@ -0,0 +1,145 @@
+package bsubio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// JobStatusCancelled is the terminal status of a job stopped with CancelJob. It is not
+// part of the generated status enum, since the API schema does not list it yet.
+const JobStatusCancelled JobStatus = "cancelled"
+
+// AllJobStatuses returns every job status in the order a job moves through them,
+// followed by the failed and cancelled states. The returned slice is a copy and may
+// be modified.
+func AllJobStatuses() []JobStatus {
+	return []JobStatus{
+		JobStatusCreated,
+		JobStatusLoaded,
+		JobStatusPending,
+		JobStatusClaimed,
+		JobStatusPreparing,
+		JobStatusProcessing,
+		JobStatusFinished,
+		JobStatusFailed,
+		JobStatusCancelled,
+	}
+}
+
+// jobStatusDescriptions holds the human-readable meaning of each status
+var jobStatusDescriptions = map[JobStatus]string{
+	JobStatusCreated:    "Job created, waiting for input data",
+	JobStatusLoaded:     "Input data uploaded, waiting to be submitted",
+	JobStatusPending:    "Submitted and queued for a worker",
+	JobStatusClaimed:    "Picked up by a worker",
+	JobStatusPreparing:  "Worker is preparing to process the job",
+	JobStatusProcessing: "Worker is processing the job",
+	JobStatusFinished:   "Processing finished successfully, output is available",
+	JobStatusFailed:     "Processing failed, see the error code and message",
+	JobStatusCancelled:  "Cancelled before it finished, no output is available",
+}
+
+// Description returns a human-readable explanation of the status,
+// suitable for legends and help text. Unknown statuses return "Unknown status".
+func (s JobStatus) Description() string {
+	if desc, ok := jobStatusDescriptions[s]; ok {
+		return desc
+	}
+	return "Unknown status"
+}
+
+// JobPhase groups job statuses into the coarse stages shown by progress UIs
+type JobPhase string
+
+// Job phases, see JobStatus.Phase
+const (
+	// JobPhaseQueued covers jobs waiting for input, submission or a worker
+	JobPhaseQueued JobPhase = "queued"
+	// JobPhaseActive covers jobs a worker is working on
+	JobPhaseActive JobPhase = "active"
+	// JobPhaseDone covers jobs that finished, failed or were cancelled
+	JobPhaseDone JobPhase = "done"
+	// JobPhaseUnknown is the phase of statuses this SDK does not know
+	JobPhaseUnknown JobPhase = "unknown"
+)
+
+// Phase returns the coarse stage of the status: queued (created, loaded, pending),
+// active (claimed, preparing, processing) or done (finished, failed, cancelled)
+func (s JobStatus) Phase() JobPhase {
+	switch s {
+	case JobStatusCreated, JobStatusLoaded, JobStatusPending:
+		return JobPhaseQueued
+	case JobStatusClaimed, JobStatusPreparing, JobStatusProcessing:
+		return JobPhaseActive
+	case JobStatusFinished, JobStatusFailed, JobStatusCancelled:
+		return JobPhaseDone
+	default:
+		return JobPhaseUnknown
+	}
+}
+
+// jobStatusOrder ranks the statuses of the pipeline a successful job moves through.
+// Failed and cancelled jobs leave the pipeline wherever they were, so those statuses
+// have no rank.
+var jobStatusOrder = map[JobStatus]int{
+	JobStatusCreated:    0,
+	JobStatusLoaded:     1,
+	JobStatusPending:    2,
+	JobStatusClaimed:    3,
+	JobStatusPreparing:  4,
+	JobStatusProcessing: 5,
+	JobStatusFinished:   6,
+}
+
+// AtLeast reports whether a job in status s has reached other, i.e. s is other or comes
+// after it in the pipeline from created to finished. Failed and cancelled are outside
+// the pipeline: they are only at least themselves, and no other status reaches them.
+func (s JobStatus) AtLeast(other JobStatus) bool {
+	if s == other {
+		return true
+	}
+	rank, ok := jobStatusOrder[s]
+	otherRank, otherOK := jobStatusOrder[other]
+	return ok && otherOK && rank >= otherRank
+}
+
+// IsActive reports whether a job in status s has its input and is on its way through
+// the pipeline: loaded, pending, claimed, preparing or processing. Created jobs still
+// wait for their input, and finished, failed and cancelled jobs are done.
+func (s JobStatus) IsActive() bool {
+	rank, ok := jobStatusOrder[s]
+	return ok && rank > jobStatusOrder[JobStatusCreated] && rank < jobStatusOrder[JobStatusFinished]
+}
+
+// CurrentStatus returns the job status, or an empty status when the server response
+// did not include one, so callers can read it without checking the pointer
+func (j *Job) CurrentStatus() JobStatus {
+	if j == nil || j.Status == nil {
+		return ""
+	}
+	return *j.Status
+}
+
+// GetJobStatus returns only the current status of the job, for callers that poll
+// and have no use for the rest of the job record
+func (c *BsubClient) GetJobStatus(ctx context.Context, jobID JobId) (JobStatus, error) {
+	resp, err := c.GetJobWithResponse(ctx, jobID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get job status: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return "", c.apiError("get job status", resp.HTTPResponse, resp.Body)
+	}
+
+	if resp.JSON200 == nil || resp.JSON200.Data == nil {
+		return "", fmt.Errorf("unexpected response format")
+	}
+
+	if resp.JSON200.Data.Status == nil {
+		return "", ErrMissingJobStatus
+	}
+
+	return *resp.JSON200.Data.Status, nil
+}
@@ -0,0 +1,173 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAllJobStatuses tests that the status list is complete and described
+func TestAllJobStatuses(t *testing.T) {
+	statuses := AllJobStatuses()
+	assert.Len(t, statuses, len(jobStatusDescriptions))
+
+	seen := make(map[JobStatus]bool)
+	for _, status := range statuses {
+		assert.False(t, seen[status], "duplicate status %s", status)
+		seen[status] = true
+
+		assert.NotEqual(t, "Unknown status", status.Description(), "status %s has no description", status)
+	}
+
+	// Callers get their own copy
+	statuses[0] = "modified"
+	assert.Equal(t, JobStatusCreated, AllJobStatuses()[0])
+}
+
+// TestJobStatusDescription tests the fallback for unknown statuses
+func TestJobStatusDescription(t *testing.T) {
+	assert.Equal(t, "Processing failed, see the error code and message", JobStatusFailed.Description())
+	assert.Equal(t, "Unknown status", JobStatus("bogus").Description())
+}
+
+// TestJobStatusPhase tests that every status maps to its phase
+func TestJobStatusPhase(t *testing.T) {
+	want := map[JobStatus]JobPhase{
+		JobStatusCreated:    JobPhaseQueued,
+		JobStatusLoaded:     JobPhaseQueued,
+		JobStatusPending:    JobPhaseQueued,
+		JobStatusClaimed:    JobPhaseActive,
+		JobStatusPreparing:  JobPhaseActive,
+		JobStatusProcessing: JobPhaseActive,
+		JobStatusFinished:   JobPhaseDone,
+		JobStatusFailed:     JobPhaseDone,
+		JobStatusCancelled:  JobPhaseDone,
+	}
+
+	for _, status := range AllJobStatuses() {
+		phase, ok := want[status]
+		require.True(t, ok, "status %s has no expected phase", status)
+		assert.Equal(t, phase, status.Phase(), "status %s", status)
+	}
+
+	assert.Equal(t, JobPhaseUnknown, JobStatus("bogus").Phase())
+}
+
+// TestJobStatusAtLeast tests comparing statuses along the job pipeline
+func TestJobStatusAtLeast(t *testing.T) {
+	tests := []struct {
+		status, other JobStatus
+		want          bool
+	}{
+		{JobStatusProcessing, JobStatusProcessing, true},
+		{JobStatusProcessing, JobStatusClaimed, true},
+		{JobStatusFinished, JobStatusCreated, true},
+		{JobStatusClaimed, JobStatusProcessing, false},
+		{JobStatusCreated, JobStatusLoaded, false},
+		{JobStatusFailed, JobStatusFailed, true},
+		{JobStatusFailed, JobStatusCreated, false},
+		{JobStatusFinished, JobStatusFailed, false},
+		{JobStatusCancelled, JobStatusPending, false},
+		{JobStatusProcessing, JobStatusCancelled, false},
+		{JobStatus("bogus"), JobStatusCreated, false},
+		{JobStatusFinished, JobStatus("bogus"), false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.status.AtLeast(tt.other), "%s.AtLeast(%s)", tt.status, tt.other)
+	}
+}
+
+// TestJobStatusIsActive tests telling in-flight jobs apart from new and ended ones
+func TestJobStatusIsActive(t *testing.T) {
+	active := []JobStatus{JobStatusLoaded, JobStatusPending, JobStatusClaimed, JobStatusPreparing, JobStatusProcessing}
+	for _, status := range AllJobStatuses() {
+		assert.Equal(t, slices.Contains(active, status), status.IsActive(), "status %s", status)
+	}
+	assert.False(t, JobStatus("bogus").IsActive())
+}
+
+// TestGetJobStatus tests fetching just the status of a job
+func TestGetJobStatus(t *testing.T) {
+	t.Run("returns the current status", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")))
+		require.NoError(t, err)
+
+		finished, err := client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+
+		status, err := client.GetJobStatus(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, *finished.Status, status)
+	})
+
+	t.Run("missing status", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusOK, `{"data": {"id": "00000000-0000-0000-0000-000000000000"}}`)
+
+		_, err := client.GetJobStatus(context.Background(), JobId{})
+		assert.ErrorIs(t, err, ErrMissingJobStatus)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		client := newCannedClient(t, http.StatusNotFound, `{"error": "not found"}`)
+
+		_, err := client.GetJobStatus(context.Background(), JobId{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 404")
+	})
+}
+
+// TestMissingJobStatus tests that jobs served without a status never cause a panic
+func TestMissingJobStatus(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Missing status only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	ctx := context.Background()
+
+	t.Run("waiting keeps polling", func(t *testing.T) {
+		mockServer.SetProgression("test/flaky", "", JobStatusProcessing, "", JobStatusFinished)
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/flaky", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		finished, err := client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, finished.CurrentStatus())
+	})
+
+	t.Run("results report an error", func(t *testing.T) {
+		mockServer.SetProgression("test/statusless", "")
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/statusless", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		_, err = client.GetJobResult(ctx, *job.Id)
+		assert.ErrorIs(t, err, ErrMissingJobStatus)
+
+		_, err = client.GetJobStatus(ctx, *job.Id)
+		assert.ErrorIs(t, err, ErrMissingJobStatus)
+
+		var buf bytes.Buffer
+		require.NoError(t, client.StreamJobArtifact(ctx, *job.Id, &buf))
+		assert.NotContains(t, readTarEntries(t, &buf), ArtifactOutputEntry)
+	})
+
+	t.Run("current status of a job without one", func(t *testing.T) {
+		assert.Equal(t, JobStatus(""), (&Job{}).CurrentStatus())
+		assert.Equal(t, JobStatus(""), (*Job)(nil).CurrentStatus())
+	})
+}
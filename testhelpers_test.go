@@ -2,6 +2,8 @@ package bsubio
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -100,3 +102,26 @@ func SetupTestClient(t *testing.T) (*BsubClient, *MockServer, func()) {
 		return client, mockServer, func() { mockServer.Close() }
 	}
 }
+
+// newCannedClient creates a client against a server that answers every request
+// with the given status code and JSON body, for testing unexpected responses
+func newCannedClient(t *testing.T, statusCode int, body string) *BsubClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewBsubClient(Config{
+		APIKey:  "test-api-key",
+		BaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create canned client: %v", err)
+	}
+
+	return client
+}
@@ -2,10 +2,20 @@ package bsubio
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,24 +27,321 @@ import (
 // MockServer provides a mock bsub.io server for testing
 type MockServer struct {
 	*httptest.Server
-	jobs        map[uuid.UUID]*Job
-	uploadedData map[uuid.UUID][]byte // Store uploaded data for calculating results
-	mu          sync.RWMutex
-	delays      map[string]time.Duration // Optional delays for specific operations
+	jobs           map[uuid.UUID]*Job
+	uploadedData   map[uuid.UUID][]byte // Store uploaded data for calculating results
+	mu             sync.RWMutex
+	delays         map[string]time.Duration             // Optional delays for specific operations
+	lastRequest    *http.Request                        // Most recent request, without body
+	progressions   map[string][]JobStatus               // Scripted statuses per job type, see SetProgression
+	pending        map[uuid.UUID][]JobStatus            // Statuses a submitted job has yet to go through
+	types          []ProcessingType                     // Served by the types endpoint, see SetTypes
+	createRequests map[uuid.UUID]map[string]interface{} // Decoded create request bodies
+	longPoll       bool                                 // Honor the wait parameter on GetJob
+	outputFuncs    map[string]func([]byte) []byte       // Output generators per job type, see SetOutputFunc
+	outputFormats  map[string][]string                  // Output media types per job type, see SetOutputFormats
+	submitStatus   int                                  // Status code of successful submits, see SetSubmitStatus
+	earlyOutput    map[string]bool                      // Job types whose output precedes their status, see SetEarlyOutput
+	gzipLogs       bool                                 // Compress logs for clients accepting gzip
+	gzipOutput     bool                                 // Compress output for clients accepting gzip
+	failures       map[string][]string                  // Error codes of upcoming failures per job type, see FailNextJobs
+	resumable      bool                                 // Accept resumable uploads, see SetResumableUploads
+	chunkFailures  int                                  // Resumable chunks left to cut short, see FailUploadChunks
+	corruptUploads int                                  // Uploads left to corrupt in transit, see CorruptUploads
+	throttled      int                                  // Uploads left to answer with 429, see ThrottleUploads
+	skipChecksums  bool                                 // Accept uploads whatever their checksum, see SetSkipChecksums
+	inlineData     bool                                 // Accept input in create requests, see SetInlineData
+	outputSums     bool                                 // Send the output checksum, see SetOutputChecksums
+	corruptOutputs int                                  // Outputs left to corrupt in transit, see CorruptOutputs
+	extraLogs      map[uuid.UUID]string                 // Log output appended per job, see AppendLogs
+	uploadParts    map[uuid.UUID]textproto.MIMEHeader   // Headers of multipart upload parts
+	uploadEncoding map[uuid.UUID]string                 // Content-Encoding of uploads
+	uploadLengths  map[uuid.UUID]int64                  // Content-Length of uploads, -1 if chunked
+	outcomes       map[string]mockOutcome               // Final state per job type, see SetJobOutcome
+	idempotency    map[string]uuid.UUID                 // Jobs created per idempotency key
+}
+
+// mockOutcome is the state submitted jobs of a type are put in, see SetJobOutcome
+type mockOutcome struct {
+	status       JobStatus
+	errorCode    string
+	errorMessage string
 }
 
 // NewMockServer creates a new mock bsub.io server
 func NewMockServer() *MockServer {
 	ms := &MockServer{
-		jobs:         make(map[uuid.UUID]*Job),
-		uploadedData: make(map[uuid.UUID][]byte),
-		delays:       make(map[string]time.Duration),
+		jobs:           make(map[uuid.UUID]*Job),
+		uploadedData:   make(map[uuid.UUID][]byte),
+		delays:         make(map[string]time.Duration),
+		progressions:   make(map[string][]JobStatus),
+		pending:        make(map[uuid.UUID][]JobStatus),
+		types:          defaultMockTypes(),
+		createRequests: make(map[uuid.UUID]map[string]interface{}),
+		outputFuncs:    make(map[string]func([]byte) []byte),
+		outputFormats:  make(map[string][]string),
+		submitStatus:   http.StatusOK,
+		earlyOutput:    make(map[string]bool),
+		failures:       make(map[string][]string),
+		extraLogs:      make(map[uuid.UUID]string),
+		uploadParts:    make(map[uuid.UUID]textproto.MIMEHeader),
+		uploadEncoding: make(map[uuid.UUID]string),
+		uploadLengths:  make(map[uuid.UUID]int64),
+		outcomes:       make(map[string]mockOutcome),
+		idempotency:    make(map[string]uuid.UUID),
 	}
 
 	ms.Server = httptest.NewServer(http.HandlerFunc(ms.handler))
 	return ms
 }
 
+// LastRequest returns the most recent request received by the server (for testing inspection)
+func (ms *MockServer) LastRequest() *http.Request {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.lastRequest
+}
+
+// SetProgression scripts the statuses that submitted jobs of jobType go through.
+// Such jobs are pending after submit and move one step forward on every GetJob request,
+// staying in the last status once the list is exhausted. An empty status makes the job
+// be served without a status field.
+func (ms *MockServer) SetProgression(jobType string, statuses ...JobStatus) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.progressions[jobType] = statuses
+}
+
+// SetLongPoll makes GetJob honor the wait query parameter: requests whose job did not
+// change are held for the requested duration before responding
+func (ms *MockServer) SetLongPoll(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.longPoll = enabled
+}
+
+// SetSubmitStatus sets the status code successful submits answer with,
+// e.g. http.StatusAccepted to mimic servers that queue submissions asynchronously
+func (ms *MockServer) SetSubmitStatus(code int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.submitStatus = code
+}
+
+// SetEarlyOutput makes the output of submitted jobs of jobType available before their
+// status reaches finished, like servers whose status updates lag behind the artifact
+func (ms *MockServer) SetEarlyOutput(jobType string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.earlyOutput[jobType] = true
+}
+
+// AppendLogs adds text to the end of the job's logs, like a processor writing more output
+func (ms *MockServer) AppendLogs(jobID uuid.UUID, text string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.extraLogs[jobID] += text
+}
+
+// SetGzipLogs makes the logs endpoint gzip its response for clients that accept it
+func (ms *MockServer) SetGzipLogs(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.gzipLogs = enabled
+}
+
+// SetGzipOutput makes the output endpoint gzip its response for clients that accept it
+func (ms *MockServer) SetGzipOutput(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.gzipOutput = enabled
+}
+
+// FailNextJobs makes the next submitted jobs of jobType fail right away, one per code,
+// with that error code. Jobs submitted after that run normally.
+func (ms *MockServer) FailNextJobs(jobType string, codes ...string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.failures[jobType] = append(ms.failures[jobType], codes...)
+}
+
+// SetDelay makes the server wait d before handling requests whose path contains
+// operation, such as "/v1/upload/" for uploads, "/submit" for submits, "/output" for
+// output downloads or "/v1/jobs/" for every job endpoint. When several operations match,
+// an arbitrary one applies, so prefer keys that don't overlap. A zero d removes the delay.
+func (ms *MockServer) SetDelay(operation string, d time.Duration) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if d == 0 {
+		delete(ms.delays, operation)
+		return
+	}
+	ms.delays[operation] = d
+}
+
+// SetJobOutcome makes every submitted job of jobType go straight to status, with
+// errorCode and errorMessage as its error when they are not empty, replacing any
+// progression set for the type. A non-terminal status leaves the job running forever,
+// which is handy to exercise timeouts. FailNextJobs still takes precedence.
+func (ms *MockServer) SetJobOutcome(jobType string, status JobStatus, errorCode, errorMessage string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.outcomes[jobType] = mockOutcome{status: status, errorCode: errorCode, errorMessage: errorMessage}
+}
+
+// SetOutput makes finished jobs of jobType return output regardless of their input.
+// It is shorthand for a SetOutputFunc returning a copy of output.
+func (ms *MockServer) SetOutput(jobType string, output []byte) {
+	output = append([]byte(nil), output...)
+	ms.SetOutputFunc(jobType, func([]byte) []byte { return output })
+}
+
+// SetResumableUploads makes the upload endpoint speak the resumable upload protocol:
+// HEAD reports the stored offset and PATCH appends a chunk at that offset
+func (ms *MockServer) SetResumableUploads(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.resumable = enabled
+}
+
+// FailUploadChunks makes the next n resumable chunks fail halfway, with only
+// the first half of their bytes stored, like a connection dropped mid-request
+func (ms *MockServer) FailUploadChunks(n int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.chunkFailures = n
+}
+
+// CorruptUploads flips a byte of the next n single-request uploads, as if they were
+// damaged in transit
+func (ms *MockServer) CorruptUploads(n int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.corruptUploads = n
+}
+
+// ThrottleUploads answers the next n single-request uploads with 429 Too Many Requests
+// and a Retry-After of 0, without reading them
+func (ms *MockServer) ThrottleUploads(n int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.throttled = n
+}
+
+// SetSkipChecksums makes the server accept uploads without checking the checksum the
+// client sent; it still reports the checksum of what it received
+func (ms *MockServer) SetSkipChecksums(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.skipChecksums = enabled
+}
+
+// SetInlineData makes the server accept the input of a job in its create request and
+// submit the job right away. Otherwise the input is ignored there, as by servers
+// that do not support it.
+func (ms *MockServer) SetInlineData(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.inlineData = enabled
+}
+
+// SetOutputChecksums makes output responses carry the SHA-256 of the output in ChecksumHeader
+func (ms *MockServer) SetOutputChecksums(enabled bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.outputSums = enabled
+}
+
+// CorruptOutputs flips a byte of the next n outputs after their checksum is computed,
+// as if they were damaged in transit
+func (ms *MockServer) CorruptOutputs(n int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.corruptOutputs = n
+}
+
+// SetTypes replaces the processing types served by the types endpoint
+func (ms *MockServer) SetTypes(types []ProcessingType) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.types = types
+}
+
+// SetOutputFunc makes finished jobs of jobType return fn applied to their uploaded data
+// as output, taking precedence over the built-in outputs. Passing nil removes it.
+func (ms *MockServer) SetOutputFunc(jobType string, fn func(uploadedData []byte) []byte) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if fn == nil {
+		delete(ms.outputFuncs, jobType)
+		return
+	}
+	ms.outputFuncs[jobType] = fn
+}
+
+// SetOutputFormats declares the media types jobs of jobType can return their output in.
+// The output endpoint answers in the type named by the Accept header when it is one of
+// them, and in the first one otherwise.
+func (ms *MockServer) SetOutputFormats(jobType string, contentTypes ...string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.outputFormats[jobType] = contentTypes
+}
+
+// defaultMockTypes returns the processing types the mock knows how to run
+func defaultMockTypes() []ProcessingType {
+	typeName := "test/linecount"
+	name := "Line counter"
+	description := "Counts the lines of the input"
+	return []ProcessingType{
+		{Type: &typeName, Name: &name, Description: &description},
+	}
+}
+
+// CreateRequest returns the decoded body the job was created with (for testing inspection)
+func (ms *MockServer) CreateRequest(jobID uuid.UUID) map[string]interface{} {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.createRequests[jobID]
+}
+
+// UploadPart returns the header of the multipart part a job's input was uploaded in
+// (for testing inspection)
+func (ms *MockServer) UploadPart(jobID uuid.UUID) textproto.MIMEHeader {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.uploadParts[jobID]
+}
+
+// UploadEncoding returns the Content-Encoding a job's input was uploaded with
+// (for testing inspection)
+func (ms *MockServer) UploadEncoding(jobID uuid.UUID) string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.uploadEncoding[jobID]
+}
+
+// UploadContentLength returns the Content-Length a job's input was uploaded with, or -1
+// when it was sent with chunked encoding (for testing inspection)
+func (ms *MockServer) UploadContentLength(jobID uuid.UUID) int64 {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.uploadLengths[jobID]
+}
+
+// UploadedData returns a copy of the input stored for a job (for testing inspection)
+func (ms *MockServer) UploadedData(jobID uuid.UUID) []byte {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return append([]byte(nil), ms.uploadedData[jobID]...)
+}
+
+// ExpireInput drops the stored input of a job, as the server does after a while
+func (ms *MockServer) ExpireInput(jobID uuid.UUID) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.uploadedData, jobID)
+}
+
 // GetJob returns a job by ID (for testing inspection)
 func (ms *MockServer) GetJob(jobID uuid.UUID) *Job {
 	ms.mu.RLock()
@@ -45,27 +352,46 @@ func (ms *MockServer) GetJob(jobID uuid.UUID) *Job {
 func (ms *MockServer) handler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Check for delays
+	ms.mu.Lock()
+	ms.lastRequest = r.Clone(context.Background())
+	ms.lastRequest.Body = nil
+	ms.mu.Unlock()
+
+	// Check for delays, sleeping without the lock so other requests go on
+	var delay time.Duration
 	ms.mu.RLock()
-	for op, delay := range ms.delays {
+	for op, d := range ms.delays {
 		if strings.Contains(r.URL.Path, op) {
-			time.Sleep(delay)
+			delay = d
 			break
 		}
 	}
 	ms.mu.RUnlock()
+	time.Sleep(delay)
 
 	switch {
 	case r.Method == "POST" && r.URL.Path == "/v1/jobs":
 		ms.handleCreateJob(w, r)
 
+	case r.Method == "GET" && r.URL.Path == "/v1/jobs":
+		ms.handleListJobs(w, r)
+
+	case r.Method == "GET" && r.URL.Path == "/v1/types":
+		ms.handleGetTypes(w, r)
+
 	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/v1/upload/"):
 		ms.handleUpload(w, r)
 
+	case (r.Method == "HEAD" || r.Method == "PATCH") && strings.HasPrefix(r.URL.Path, "/v1/upload/"):
+		ms.handleResumableUpload(w, r)
+
 	case r.Method == "POST" && strings.Contains(r.URL.Path, "/submit"):
 		ms.handleSubmit(w, r)
 
-	case r.Method == "GET" && strings.Contains(r.URL.Path, "/v1/jobs/") && strings.Contains(r.URL.Path, "/output"):
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/v1/jobs/") && strings.HasSuffix(r.URL.Path, "/cancel"):
+		ms.handleCancelJob(w, r)
+
+	case (r.Method == "GET" || r.Method == "HEAD") && strings.Contains(r.URL.Path, "/v1/jobs/") && strings.Contains(r.URL.Path, "/output"):
 		ms.handleGetOutput(w, r)
 
 	case r.Method == "GET" && strings.Contains(r.URL.Path, "/v1/jobs/") && strings.Contains(r.URL.Path, "/logs"):
@@ -74,18 +400,135 @@ func (ms *MockServer) handler(w http.ResponseWriter, r *http.Request) {
 	case r.Method == "GET" && strings.Contains(r.URL.Path, "/v1/jobs/"):
 		ms.handleGetJob(w, r)
 
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/jobs/"):
+		ms.handleDeleteJob(w, r)
+
 	default:
 		http.Error(w, "Not found", http.StatusNotFound)
 	}
 }
 
+func (ms *MockServer) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = len(ms.jobs)
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	jobs := make([]*Job, 0, len(ms.jobs))
+	for _, job := range ms.jobs {
+		if status != "" && (job.Status == nil || string(*job.Status) != status) {
+			continue
+		}
+		if !ms.metadataMatches(*job.Id, r.URL.Query()) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	// Newest first, with the ID as a tie breaker so pages are stable
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(*jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(*jobs[j].CreatedAt)
+		}
+		return jobs[i].Id.String() < jobs[j].Id.String()
+	})
+
+	total := len(jobs)
+	jobs = jobs[min(max(offset, 0), len(jobs)):]
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+
+	// Jobs are listed with their metadata, which the Job type does not model
+	type taggedJob struct {
+		*Job
+		Metadata interface{} `json:"metadata,omitempty"`
+	}
+	tagged := make([]taggedJob, len(jobs))
+	for i, job := range jobs {
+		tagged[i] = taggedJob{Job: job, Metadata: ms.createRequests[*job.Id]["metadata"]}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"jobs":  tagged,
+			"total": total,
+		},
+		"success": true,
+	})
+}
+
+// metadataMatches reports whether a job was created with the metadata named by the
+// metadata.<key> query parameters. The caller must hold the lock.
+func (ms *MockServer) metadataMatches(jobID uuid.UUID, query url.Values) bool {
+	metadata, _ := ms.createRequests[jobID]["metadata"].(map[string]interface{})
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, "metadata.")
+		if ok && metadata[key] != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func (ms *MockServer) handleGetTypes(w http.ResponseWriter, r *http.Request) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	types := ms.types
+	if types == nil {
+		types = []ProcessingType{}
+	}
+
+	// The ETag is a hash of the list, so clients holding the current one get a 304
+	body, _ := json.Marshal(map[string]interface{}{
+		"types": types,
+	})
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
 func (ms *MockServer) handleCreateJob(w http.ResponseWriter, r *http.Request) {
-	var req CreateJobJSONRequestBody
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var raw map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
+	var req CreateJobJSONRequestBody
+	if jobType, ok := raw["type"].(string); ok {
+		req.Type = jobType
+	}
+
+	// A repeated idempotency key gets the job it created, as long as it exists
+	key := r.Header.Get(IdempotencyKeyHeader)
+	ms.mu.RLock()
+	existing, replayed := ms.jobs[ms.idempotency[key]]
+	ms.mu.RUnlock()
+	if key != "" && replayed {
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":    existing,
+			"success": true,
+		})
+		return
+	}
+
 	jobID := uuid.New()
 	status := JobStatusCreated
 	uploadToken := uuid.New().String()
@@ -106,11 +549,35 @@ func (ms *MockServer) handleCreateJob(w http.ResponseWriter, r *http.Request) {
 
 	ms.mu.Lock()
 	ms.jobs[jobID] = job
+	ms.createRequests[jobID] = raw
+	if encoded, ok := raw["data"].(string); ok && ms.inlineData {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			delete(ms.jobs, jobID)
+			ms.mu.Unlock()
+			http.Error(w, "Invalid inline data", http.StatusBadRequest)
+			return
+		}
+		size := int64(len(data))
+		job.DataSize = &size
+		ms.uploadedData[jobID] = data
+		ms.startJob(jobID, job)
+	}
+	if key != "" {
+		ms.idempotency[key] = jobID
+	}
 	ms.mu.Unlock()
 
+	// The job is echoed with its priority and metadata, which the Job type does not model
+	type createdJob struct {
+		*Job
+		Priority interface{} `json:"priority,omitempty"`
+		Metadata interface{} `json:"metadata,omitempty"`
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"data":    job,
+		"data":    createdJob{Job: job, Priority: raw["priority"], Metadata: raw["metadata"]},
 		"success": true,
 	})
 }
@@ -136,17 +603,69 @@ func (ms *MockServer) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Read the uploaded data
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
+	ms.mu.Lock()
+	throttle := ms.throttled > 0
+	if throttle {
+		ms.throttled--
+	}
+	ms.mu.Unlock()
+	if throttle {
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "Too many uploads", http.StatusTooManyRequests)
+		return
+	}
+
+	// Read the uploaded data, sized by Content-Length so benchmarks measure the client.
+	// Compressed bodies are decompressed, and multipart uploads are unwrapped to the
+	// contents of their "file" part.
+	source := io.Reader(r.Body)
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		source = zr
+	}
+	var partHeader textproto.MIMEHeader
+	if mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "multipart/form-data" {
+		part, err := multipart.NewReader(source, params["boundary"]).NextPart()
+		if err != nil || part.FormName() != "file" {
+			http.Error(w, "Missing file part", http.StatusBadRequest)
+			return
+		}
+		source = part
+		partHeader = part.Header
+	}
+
+	var body bytes.Buffer
+	if r.ContentLength > 0 {
+		body.Grow(int(r.ContentLength) + bytes.MinRead)
+	}
+	if _, err := body.ReadFrom(source); err != nil {
 		http.Error(w, "Failed to read upload", http.StatusBadRequest)
 		return
 	}
+	data := body.Bytes()
 
-	// Verify job exists and token matches
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	if ms.corruptUploads > 0 && len(data) > 0 {
+		ms.corruptUploads--
+		data[len(data)/2] ^= 0xff
+	}
+
+	// Verify the checksum when the client sent one
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if want := r.Header.Get(ChecksumHeader); want != "" && !ms.skipChecksums && checksum != want {
+		http.Error(w, "Checksum mismatch", http.StatusBadRequest)
+		return
+	}
+
+	// Verify job exists and token matches
+
 	job, exists := ms.jobs[jobID]
 	if !exists {
 		http.Error(w, "Job not found", http.StatusNotFound)
@@ -164,7 +683,13 @@ func (ms *MockServer) handleUpload(w http.ResponseWriter, r *http.Request) {
 	dataSize := int64(len(data))
 	job.DataSize = &dataSize
 	ms.uploadedData[jobID] = data
+	if partHeader != nil {
+		ms.uploadParts[jobID] = partHeader
+	}
+	ms.uploadEncoding[jobID] = r.Header.Get("Content-Encoding")
+	ms.uploadLengths[jobID] = r.ContentLength
 
+	w.Header().Set(ChecksumHeader, checksum)
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"data_size": len(data),
@@ -172,6 +697,70 @@ func (ms *MockServer) handleUpload(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (ms *MockServer) handleResumableUpload(w http.ResponseWriter, r *http.Request) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if !ms.resumable {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract job ID from path: /v1/upload/{jobId}
+	jobID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/v1/upload/"))
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, exists := ms.jobs[jobID]
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if job.UploadToken == nil || *job.UploadToken != r.URL.Query().Get("token") {
+		http.Error(w, "Invalid upload token", http.StatusUnauthorized)
+		return
+	}
+
+	data := ms.uploadedData[jobID]
+	if r.Method == "PATCH" {
+		if r.Header.Get(UploadOffsetHeader) != strconv.Itoa(len(data)) {
+			w.Header().Set(UploadOffsetHeader, strconv.Itoa(len(data)))
+			http.Error(w, "Offset mismatch", http.StatusConflict)
+			return
+		}
+
+		chunk, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read chunk", http.StatusBadRequest)
+			return
+		}
+
+		failed := ms.chunkFailures > 0
+		if failed {
+			ms.chunkFailures--
+			chunk = chunk[:len(chunk)/2]
+		}
+
+		data = append(data, chunk...)
+		ms.uploadedData[jobID] = data
+		status := JobStatusLoaded
+		job.Status = &status
+		dataSize := int64(len(data))
+		job.DataSize = &dataSize
+
+		if failed {
+			http.Error(w, "Connection lost", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set(UploadOffsetHeader, strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (ms *MockServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
 	// Extract job ID from path: /v1/jobs/{jobId}/submit
 	parts := strings.Split(r.URL.Path, "/")
@@ -194,6 +783,35 @@ func (ms *MockServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Failed jobs are run again on their stored input, as long as it is kept
+	if job.Status != nil && *job.Status == JobStatusFailed {
+		if _, ok := ms.uploadedData[jobID]; !ok {
+			ms.mu.Unlock()
+			w.WriteHeader(http.StatusGone)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   map[string]string{"code": ErrorCodeInputExpired, "message": "job input has expired"},
+			})
+			return
+		}
+		job.ErrorCode, job.ErrorMessage = nil, nil
+	}
+
+	ms.startJob(jobID, job)
+	submitStatus := ms.submitStatus
+	ms.mu.Unlock()
+
+	// Return simple success response (matching real API)
+	w.WriteHeader(submitStatus)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Job submitted successfully",
+	})
+}
+
+// startJob puts a submitted job in its first status, following the scripted
+// progressions and outcomes of its type. The caller holds ms.mu.
+func (ms *MockServer) startJob(jobID uuid.UUID, job *Job) {
 	// Simulate job processing - for test job types, mark as finished immediately
 	// For other types, mark as pending and will need to be polled
 	status := JobStatusFinished
@@ -205,17 +823,50 @@ func (ms *MockServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
 			status = JobStatusPending
 		}
 	}
+	if job.Type != nil {
+		if steps, ok := ms.progressions[*job.Type]; ok {
+			status = JobStatusPending
+			ms.pending[jobID] = append([]JobStatus(nil), steps...)
+		}
+		if outcome, ok := ms.outcomes[*job.Type]; ok {
+			status = outcome.status
+			if outcome.errorCode != "" {
+				job.ErrorCode = &outcome.errorCode
+			}
+			if outcome.errorMessage != "" {
+				job.ErrorMessage = &outcome.errorMessage
+			}
+			delete(ms.pending, jobID)
+		}
+		if codes := ms.failures[*job.Type]; len(codes) > 0 {
+			status = JobStatusFailed
+			code, message := codes[0], "mock failure: "+codes[0]
+			job.ErrorCode, job.ErrorMessage = &code, &message
+			ms.failures[*job.Type] = codes[1:]
+			delete(ms.pending, jobID)
+		}
+	}
 	job.Status = &status
 	now := time.Now()
 	job.UpdatedAt = &now
-	ms.mu.Unlock()
+	ms.notifyCallback(jobID, job)
+}
 
-	// Return simple success response (matching real API)
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Job submitted successfully",
-	})
+// notifyCallback posts a job that has ended to the callback URL it was created with,
+// as a server would. The caller must hold the lock.
+func (ms *MockServer) notifyCallback(jobID uuid.UUID, job *Job) {
+	callbackURL, _ := ms.createRequests[jobID]["callback_url"].(string)
+	if callbackURL == "" || !isTerminal(job.Status) {
+		return
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"data": job})
+	go func() {
+		resp, err := http.Post(callbackURL, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
 }
 
 func (ms *MockServer) handleGetJob(w http.ResponseWriter, r *http.Request) {
@@ -234,20 +885,111 @@ func (ms *MockServer) handleGetJob(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	ms.mu.RLock()
+	ms.mu.Lock()
 	job, exists := ms.jobs[jobID]
-	ms.mu.RUnlock()
-
 	if !exists {
+		ms.mu.Unlock()
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	// Advance scripted jobs by one step per poll
+	changed := false
+	if steps := ms.pending[jobID]; len(steps) > 0 {
+		changed = job.Status == nil || *job.Status != steps[0]
+		status := steps[0]
+		job.Status = &status
+		if status == "" {
+			job.Status = nil
+		}
+		now := time.Now()
+		job.UpdatedAt = &now
+		ms.pending[jobID] = steps[1:]
+		if changed {
+			ms.notifyCallback(jobID, job)
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
 		"data":    job,
 		"success": true,
 	})
+	longPoll := ms.longPoll
+	ms.mu.Unlock()
+
+	// Hold long-poll requests for the requested time when nothing changed
+	if wait, err := time.ParseDuration(r.URL.Query().Get("wait")); longPoll && err == nil && !changed {
+		select {
+		case <-time.After(wait):
+		case <-r.Context().Done():
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (ms *MockServer) handleDeleteJob(w http.ResponseWriter, r *http.Request) {
+	// Extract job ID from path: /v1/jobs/{jobId}
+	jobID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"))
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	job, exists := ms.jobs[jobID]
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if job.Status != nil && job.Status.Phase() == JobPhaseActive {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "job is being processed"})
+		return
+	}
+
+	delete(ms.jobs, jobID)
+	delete(ms.uploadedData, jobID)
+	delete(ms.pending, jobID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ms *MockServer) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	// Extract job ID from path: /v1/jobs/{jobId}/cancel
+	jobID, err := uuid.Parse(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), "/cancel"))
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	job, exists := ms.jobs[jobID]
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if isTerminal(job.Status) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "job already ended"})
+		return
+	}
+
+	status := JobStatusCancelled
+	now := time.Now()
+	job.Status = &status
+	job.FinishedAt = &now
+	delete(ms.pending, jobID)
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "job cancelled"})
 }
 
 func (ms *MockServer) handleGetOutput(w http.ResponseWriter, r *http.Request) {
@@ -264,19 +1006,48 @@ func (ms *MockServer) handleGetOutput(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	ms.mu.RLock()
+	ms.mu.Lock()
 	job, exists := ms.jobs[jobID]
 	uploadedData := ms.uploadedData[jobID]
-	ms.mu.RUnlock()
+	var outputFunc func([]byte) []byte
+	var formats []string
+	gzipOutput := ms.gzipOutput
+	outputSums := ms.outputSums
+	corrupt := ms.corruptOutputs > 0 && r.Method == http.MethodGet
+	if corrupt {
+		ms.corruptOutputs--
+	}
+	available := exists && job.Status != nil && *job.Status == JobStatusFinished
+	if exists && job.Type != nil {
+		outputFunc = ms.outputFuncs[*job.Type]
+		formats = ms.outputFormats[*job.Type]
+
+		// Failed jobs of types with an output function keep partial output
+		if outputFunc != nil && job.Status != nil && *job.Status == JobStatusFailed {
+			available = true
+		}
 
-	if !exists || job.Status == nil || *job.Status != JobStatusFinished {
+		// Submitted jobs of early output types have output unless they failed
+		if ms.earlyOutput[*job.Type] && job.Status != nil {
+			switch *job.Status {
+			case JobStatusCreated, JobStatusLoaded, JobStatusFailed:
+			default:
+				available = true
+			}
+		}
+	}
+	ms.mu.Unlock()
+
+	if !available {
 		http.Error(w, "Output not available", http.StatusNotFound)
 		return
 	}
 
 	// Generate output based on job type
 	var output string
-	if job.Type != nil {
+	if outputFunc != nil {
+		output = string(outputFunc(uploadedData))
+	} else if job.Type != nil {
 		switch *job.Type {
 		case "test/linecount":
 			// Calculate actual line count from uploaded data
@@ -298,7 +1069,38 @@ func (ms *MockServer) handleGetOutput(w http.ResponseWriter, r *http.Request) {
 		output = "mock output"
 	}
 
-	w.Header().Set("Content-Type", "application/octet-stream")
+	contentType := "application/octet-stream"
+	if len(formats) > 0 {
+		contentType = formats[0]
+		for _, format := range formats {
+			if format == r.Header.Get("Accept") {
+				contentType = format
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if outputSums {
+		sum := sha256.Sum256([]byte(output))
+		w.Header().Set(ChecksumHeader, hex.EncodeToString(sum[:]))
+	}
+	if corrupt && output != "" {
+		damaged := []byte(output)
+		damaged[len(damaged)/2] ^= 0xff
+		output = string(damaged)
+	}
+	if gzipOutput && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		_, _ = zw.Write([]byte(output))
+		_ = zw.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(compressed.Bytes())
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(output))
 }
@@ -318,6 +1120,8 @@ func (ms *MockServer) handleGetLogs(w http.ResponseWriter, r *http.Request) {
 
 	ms.mu.RLock()
 	job, exists := ms.jobs[jobID]
+	gzipLogs := ms.gzipLogs
+	extra := ms.extraLogs[jobID]
 	ms.mu.RUnlock()
 
 	if !exists {
@@ -329,8 +1133,18 @@ func (ms *MockServer) handleGetLogs(w http.ResponseWriter, r *http.Request) {
 	if job.Type != nil {
 		logs = "Processing " + *job.Type + " job\nCompleted successfully"
 	}
+	logs += extra
 
 	w.Header().Set("Content-Type", "text/plain")
+	if gzipLogs && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		zw := gzip.NewWriter(w)
+		_, _ = zw.Write([]byte(logs))
+		_ = zw.Close()
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(logs))
 }
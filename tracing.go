@@ -0,0 +1,101 @@
+package bsubio
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the spans the client creates
+const tracerName = "github.com/bsubio/bsubio-go"
+
+// Attributes set on the spans the client creates
+const (
+	attrJobID          = attribute.Key("job.id")
+	attrJobType        = attribute.Key("job.type")
+	attrHTTPMethod     = attribute.Key("http.method")
+	attrHTTPStatusCode = attribute.Key("http.status_code")
+	attrURLPath        = attribute.Key("url.path")
+)
+
+// noopSpan is returned by startSpan when tracing is disabled
+var noopSpan trace.Span = noop.Span{}
+
+// spanJobTypeKey is the context key of the job type recorded on request spans
+type spanJobTypeKey struct{}
+
+// tracingDoer records a client span for every API call, named after the operation and
+// carrying the job ID and type when they are known. Retries of a call happen within its
+// span. The query string is left out, since upload URLs carry the upload token.
+func (c *BsubClient) tracingDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		attrs := []attribute.KeyValue{
+			attrHTTPMethod.String(req.Method),
+			attrURLPath.String(req.URL.Path),
+		}
+		if segments := apiPathSegments(req.URL.Path); len(segments) >= 3 && (segments[1] == "jobs" || segments[1] == "upload") {
+			attrs = append(attrs, attrJobID.String(segments[2]))
+		}
+		if jobType, ok := req.Context().Value(spanJobTypeKey{}).(string); ok {
+			attrs = append(attrs, attrJobType.String(jobType))
+		}
+
+		ctx, span := c.tracer.Start(req.Context(), operationName(req),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		resp, err := next.Do(req.WithContext(ctx))
+		if err != nil {
+			recordSpanError(span, err)
+			return nil, err
+		}
+
+		span.SetAttributes(attrHTTPStatusCode.Int(resp.StatusCode))
+		if resp.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+		return resp, nil
+	})
+}
+
+// startSpan starts a span covering a whole helper call, such as Process, with the job
+// type recorded on it and on the request spans within. Without a tracer provider it
+// returns ctx unchanged and a no-op span.
+func (c *BsubClient) startSpan(ctx context.Context, name string, jobType string) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan
+	}
+
+	ctx = context.WithValue(ctx, spanJobTypeKey{}, jobType)
+	return c.tracer.Start(ctx, name, trace.WithAttributes(attrJobType.String(jobType)))
+}
+
+// endSpan ends a span started by startSpan, marking it failed when err is not nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	span.End()
+}
+
+// recordSpanError marks span failed with err, without the upload token that transport
+// errors quote in the request URL, see redactError
+func recordSpanError(span trace.Span, err error) {
+	err = redactError(err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// setSpanJob records the job a helper call is working on, once it has been created
+func (c *BsubClient) setSpanJob(ctx context.Context, job *Job) {
+	if c.tracer == nil || job == nil || job.Id == nil {
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrJobID.String(job.Id.String()))
+}
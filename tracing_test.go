@@ -0,0 +1,136 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttr returns the value of an attribute of a recorded span
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// TestTracing tests the spans recorded for API requests and Process calls
+func TestTracing(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	client, err := NewBsubClientWithOptions(
+		WithAPIKey("test-api-key"),
+		WithAPIBaseURL(mockServer.URL),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+	)
+	require.NoError(t, err)
+	client.pollInterval = testPollInterval
+
+	t.Run("process span covers the job", func(t *testing.T) {
+		recorder.Reset()
+
+		result, err := client.Process(context.Background(), "test/linecount", bytes.NewReader([]byte("a\nb")))
+		require.NoError(t, err)
+		jobID := result.Job.Id.String()
+
+		spans := recorder.Ended()
+		require.NotEmpty(t, spans)
+		root := spans[len(spans)-1]
+		assert.Equal(t, "bsubio.Process", root.Name())
+		assert.Equal(t, codes.Unset, root.Status().Code)
+		value, _ := spanAttr(root, attrJobType)
+		assert.Equal(t, "test/linecount", value.AsString())
+		value, _ = spanAttr(root, attrJobID)
+		assert.Equal(t, jobID, value.AsString())
+
+		operations := make(map[string]sdktrace.ReadOnlySpan)
+		for _, span := range spans[:len(spans)-1] {
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(), "span %s", span.Name())
+			operations[span.Name()] = span
+		}
+		for _, name := range []string{"CreateJob", "UploadJobData", "SubmitJob", "GetJob", "GetJobOutput"} {
+			require.Contains(t, operations, name)
+		}
+
+		upload := operations["UploadJobData"]
+		value, _ = spanAttr(upload, attrJobID)
+		assert.Equal(t, jobID, value.AsString())
+		value, _ = spanAttr(upload, attrJobType)
+		assert.Equal(t, "test/linecount", value.AsString())
+		value, _ = spanAttr(upload, attrHTTPStatusCode)
+		assert.Equal(t, int64(200), value.AsInt64())
+		value, _ = spanAttr(upload, attrURLPath)
+		assert.NotContains(t, value.AsString(), "token")
+	})
+
+	t.Run("error responses mark the span", func(t *testing.T) {
+		recorder.Reset()
+
+		_, err := client.GetJobStatus(context.Background(), uuid.New())
+		require.Error(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GetJob", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		value, _ := spanAttr(spans[0], attrHTTPStatusCode)
+		assert.Equal(t, int64(404), value.AsInt64())
+		_, hasType := spanAttr(spans[0], attrJobType)
+		assert.False(t, hasType)
+	})
+
+	t.Run("failed upload hides the token", func(t *testing.T) {
+		const token = "secret-upload-token"
+		server := hangUpUploadServer(t, token)
+		client, err := NewBsubClientWithOptions(
+			WithAPIKey("test-api-key"),
+			WithAPIBaseURL(server.URL),
+			WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		)
+		require.NoError(t, err)
+		recorder.Reset()
+
+		_, err = client.Process(context.Background(), "test/linecount", bytes.NewReader([]byte("data")), WithCleanupOnError(false))
+		require.Error(t, err)
+
+		spans := recorder.Ended()
+		require.NotEmpty(t, spans)
+		var failed int
+		for _, span := range spans {
+			if span.Status().Code != codes.Error {
+				continue
+			}
+			failed++
+			assert.NotContains(t, span.Status().Description, token, "span %s", span.Name())
+			for _, event := range span.Events() {
+				for _, attr := range event.Attributes {
+					assert.NotContains(t, attr.Value.Emit(), token, "span %s", span.Name())
+				}
+			}
+		}
+		assert.Equal(t, 2, failed)
+	})
+
+	t.Run("disabled without a provider", func(t *testing.T) {
+		plain, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: mockServer.URL})
+		require.NoError(t, err)
+		assert.Nil(t, plain.tracer)
+
+		ctx := context.Background()
+		spanCtx, span := plain.startSpan(ctx, "bsubio.Process", "test/linecount")
+		assert.Equal(t, ctx, spanCtx)
+		assert.False(t, span.IsRecording())
+	})
+}
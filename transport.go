@@ -0,0 +1,468 @@
+package bsubio
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultRetryBackoff is the delay before the first retry of a failed request
+const defaultRetryBackoff = 500 * time.Millisecond
+
+// defaultRateLimitRetries is how many times a throttled request is repeated by default
+const defaultRateLimitRetries = 3
+
+// DefaultRetryStatusCodes are the response status codes retried when
+// Config.RetryStatusCodes is empty
+var DefaultRetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// doerFunc adapts an ordinary function to the HttpRequestDoer interface
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// doer wraps the HTTP client in the layers every API request goes through
+func (c *BsubClient) doer(next HttpRequestDoer) HttpRequestDoer {
+	if c.requestTimeout > 0 {
+		next = c.timeoutDoer(next)
+	}
+	if c.limiter != nil {
+		next = c.throttleDoer(next)
+	}
+	if c.captureLimit > 0 {
+		next = c.captureDoer(next)
+	}
+	next = c.loggingDoer(next)
+	next = c.statsDoer(next)
+	if c.rateLimitRetries > 0 {
+		next = c.rateLimitDoer(next)
+	}
+	if c.maxRetries > 0 {
+		next = c.retryDoer(next)
+	}
+	if len(c.requestInterceptors) > 0 || len(c.responseInterceptors) > 0 {
+		next = c.interceptorDoer(next)
+	}
+	if c.tracer != nil {
+		next = c.tracingDoer(next)
+	}
+	return next
+}
+
+// RequestInterceptor inspects or changes an API request before it is sent. Returning an
+// error aborts the call, and the helper that made it returns the error.
+type RequestInterceptor func(req *http.Request) error
+
+// ResponseInterceptor inspects an API response before the helpers read it. Returning an
+// error aborts the call, and the helper that made it returns the error.
+type ResponseInterceptor func(resp *http.Response) error
+
+// interceptorDoer runs the configured interceptors around every API call
+func (c *BsubClient) interceptorDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		for _, intercept := range c.requestInterceptors {
+			if err := intercept(req); err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, fmt.Errorf("request interceptor: %w", err)
+			}
+		}
+
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, intercept := range c.responseInterceptors {
+			if err := intercept(resp); err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("response interceptor: %w", err)
+			}
+		}
+		return resp, nil
+	})
+}
+
+// timeoutDoer bounds each request by requestTimeout, from sending it until its response
+// body is closed. Uploads, output and log downloads and long polls run for as long as
+// their input, output or wait needs, so they are left to their context. A timeout is
+// reported as ErrRequestTimeout rather than context.DeadlineExceeded, so that it is not
+// mistaken for the caller's own deadline and is retried like other transient errors.
+func (c *BsubClient) timeoutDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		switch operationName(req) {
+		case "UploadJobData", "GetJobOutput", "GetJobLogs":
+			return next.Do(req)
+		}
+		if req.URL.Query().Has("wait") {
+			return next.Do(req)
+		}
+
+		ctx, cancel := context.WithTimeoutCause(req.Context(), c.requestTimeout, ErrRequestTimeout)
+		resp, err := next.Do(req.WithContext(ctx))
+		if err != nil {
+			cancel()
+			if errors.Is(context.Cause(ctx), ErrRequestTimeout) && req.Context().Err() == nil {
+				return nil, fmt.Errorf("%s %s: %w after %s", req.Method, req.URL.Path, ErrRequestTimeout, c.requestTimeout)
+			}
+			return nil, err
+		}
+		resp.Body = &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	})
+}
+
+// cancelReadCloser releases the context of a request once its response body is closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// throttleDoer holds every request until the rate limiter allows it. A request whose
+// context ends first fails with the context error.
+func (c *BsubClient) throttleDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if err := c.limiter.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// The wait would outlast the deadline, so the request could not be sent in time
+			return nil, context.DeadlineExceeded
+		}
+		return next.Do(req)
+	})
+}
+
+// retryDoer repeats requests that failed with a transient network error or a retryable
+// status code, up to maxRetries times with exponential backoff. Only GET and HEAD
+// requests and job creation are repeated, and only when their body can be replayed.
+// A retry that would not start before the context deadline is not attempted.
+func (c *BsubClient) retryDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.Do(req)
+		if !retryableRequest(req) {
+			return resp, err
+		}
+
+		ctx := req.Context()
+		delay := c.retryBackoff
+		for attempt := 0; attempt < c.maxRetries; attempt++ {
+			if err != nil && !isTransientError(err) {
+				return resp, err
+			}
+			if err == nil && !c.retryStatusCodes[resp.StatusCode] {
+				return resp, nil
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return resp, err
+			}
+
+			retry := req.Clone(ctx)
+			if req.GetBody != nil {
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return resp, err
+				}
+				retry.Body = body
+			}
+
+			select {
+			case <-ctx.Done():
+				return resp, err
+			case <-time.After(delay):
+			}
+
+			if resp != nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+
+			c.stats.retries.Add(1)
+			c.logger.Info("retrying request", "operation", operationName(req), "attempt", attempt+1, "reason", retryReason(resp, err), "delay", delay)
+			resp, err = next.Do(retry)
+			delay *= 2
+		}
+		return resp, err
+	})
+}
+
+// rateLimitDoer repeats requests the server throttled with 429 Too Many Requests, up to
+// rateLimitRetries times. It waits as long as the Retry-After header asks, or the retry
+// backoff when there is none. A throttled request was not processed, so any request
+// whose body can be replayed is retried, unless the wait would pass the context deadline.
+func (c *BsubClient) rateLimitDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		backoff := c.retryBackoff
+
+		resp, err := next.Do(req)
+		for attempt := 0; attempt < c.rateLimitRetries; attempt++ {
+			if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+				return resp, err
+			}
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+
+			delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if !ok {
+				delay = backoff
+				backoff *= 2
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return resp, nil
+			}
+
+			retry := req.Clone(ctx)
+			if req.GetBody != nil {
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return resp, nil
+				}
+				retry.Body = body
+			}
+
+			select {
+			case <-ctx.Done():
+				return resp, nil
+			case <-time.After(delay):
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			c.stats.retries.Add(1)
+			c.logger.Info("retrying request", "operation", operationName(req), "attempt", attempt+1, "reason", resp.Status, "delay", delay)
+			resp, err = next.Do(retry)
+		}
+		return resp, err
+	})
+}
+
+// retryReason describes why a request is retried, for the log
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return redactError(err).Error()
+	}
+	return resp.Status
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an HTTP date,
+// into the delay it asks for
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// parseErrorBody fills in the details of apiErr from a JSON error body, or keeps the
+// start of the body as RawBody when it is not JSON
+func parseErrorBody(apiErr *APIError, body []byte) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return
+	}
+
+	var parsed apiErrorBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		apiErr.RawBody = bytes.Clone(body[:min(len(body), errorBodyLimit)])
+		return
+	}
+
+	apiErr.Code = parsed.Code
+	apiErr.Message = parsed.Message
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = parsed.RequestID
+	}
+
+	var message string
+	var detail struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	switch {
+	case json.Unmarshal(parsed.Error, &message) == nil && message != "":
+		apiErr.Message = message
+	case json.Unmarshal(parsed.Error, &detail) == nil:
+		apiErr.Code = cmp.Or(detail.Code, apiErr.Code)
+		apiErr.Message = cmp.Or(detail.Message, apiErr.Message)
+	}
+}
+
+// retryableRequest reports whether repeating req cannot have unwanted side effects
+// beyond a duplicate job, which the idempotency key of create requests guards against,
+// and its body can be sent again
+func retryableRequest(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	return req.Method == http.MethodGet || req.Method == http.MethodHead || operationName(req) == "CreateJob"
+}
+
+// isTransientError reports whether a request error is likely to go away on its own,
+// such as a reset connection or a timeout, as opposed to a cancelled context
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRequestTimeout) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// statsDoer counts requests by operation and the bytes sent and received
+func (c *BsubClient) statsDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		c.stats.countRequest(operationName(req))
+
+		if req.Body != nil && req.Body != http.NoBody {
+			req = req.WithContext(req.Context())
+			req.Body = &countingReadCloser{ReadCloser: req.Body, count: c.stats.bytesUploaded.Add}
+		}
+
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Body = &countingReadCloser{ReadCloser: resp.Body, count: c.stats.bytesDownloaded.Add}
+		return resp, nil
+	})
+}
+
+// countingReadCloser reports the number of bytes read through it to count
+type countingReadCloser struct {
+	io.ReadCloser
+	count func(int64) int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.count(int64(n))
+	}
+	return n, err
+}
+
+// captureKey is the context key of the requestCapture of a request
+type captureKey struct{}
+
+// requestCapture keeps what apiError reports about a request: its redacted headers and
+// the first bytes of its body
+type requestCapture struct {
+	mu     sync.Mutex
+	header http.Header
+	body   bytes.Buffer
+	limit  int
+}
+
+func (rc *requestCapture) Write(p []byte) (int, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if room := rc.limit - rc.body.Len(); room > 0 {
+		rc.body.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (rc *requestCapture) bytes() []byte {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return bytes.Clone(rc.body.Bytes())
+}
+
+// captureDoer records the headers and the start of the body of each request, so
+// apiError can attach them when the response is rejected
+func (c *BsubClient) captureDoer(next HttpRequestDoer) HttpRequestDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		rc := &requestCapture{header: req.Header.Clone(), limit: c.captureLimit}
+		if rc.header.Get("Authorization") != "" {
+			rc.header.Set("Authorization", "REDACTED")
+		}
+
+		req = req.WithContext(context.WithValue(req.Context(), captureKey{}, rc))
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(req.Body, rc), req.Body}
+		}
+
+		return next.Do(req)
+	})
+}
+
+// errorBodyLimit is how much of an error response is read to describe the error
+const errorBodyLimit = 4 << 10
+
+// apiErrorBody is the JSON error body of the API. The documented form is
+// {"error": "message"}; error may also be an object with a code and a message.
+type apiErrorBody struct {
+	Error     json.RawMessage `json:"error"`
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	RequestID string          `json:"request_id"`
+}
+
+// apiError builds the APIError for an unexpected response. body is the response body
+// when it was already read; otherwise the start of it is read from resp. Captured bodies
+// and headers are only attached when Config.CaptureBodiesOnError is set.
+func (c *BsubClient) apiError(op string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{Op: op, StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-Id")}
+
+	if body == nil && resp.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, int64(max(errorBodyLimit, c.captureLimit))))
+	}
+	parseErrorBody(apiErr, body)
+
+	if c.captureLimit <= 0 {
+		return apiErr
+	}
+	if len(body) > 0 {
+		apiErr.ResponseBody = bytes.Clone(body[:min(len(body), c.captureLimit)])
+	}
+
+	if resp.Request != nil {
+		if rc, ok := resp.Request.Context().Value(captureKey{}).(*requestCapture); ok {
+			apiErr.RequestHeader = rc.header
+			apiErr.RequestBody = rc.bytes()
+		}
+	}
+	return apiErr
+}
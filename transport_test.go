@@ -0,0 +1,436 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer answers the first failures requests with status, or by dropping the
+// connection when status is 0, and then succeeds
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			if status == 0 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				conn.Close()
+				return
+			}
+			w.WriteHeader(status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"data":{"id":"` + uuid.NewString() + `","status":"created","upload_token":"t"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":{"id":"` + uuid.NewString() + `","status":"finished"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newRetryClient creates a client with fast retries against server
+func newRetryClient(t *testing.T, server *httptest.Server, maxRetries int) *BsubClient {
+	t.Helper()
+
+	client, err := NewBsubClient(Config{
+		APIKey:       "test-api-key",
+		BaseURL:      server.URL,
+		MaxRetries:   maxRetries,
+		RetryBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+// TestRetryTransientErrors tests the retry layer for transient HTTP failures
+func TestRetryTransientErrors(t *testing.T) {
+	t.Run("server errors", func(t *testing.T) {
+		server, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+		client := newRetryClient(t, server, 3)
+
+		status, err := client.GetJobStatus(context.Background(), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, status)
+		assert.Equal(t, int32(3), calls.Load())
+
+		stats := client.Stats()
+		assert.Equal(t, int64(2), stats.Retries)
+		assert.Equal(t, int64(3), stats.Requests["GetJob"])
+	})
+
+	t.Run("connection reset", func(t *testing.T) {
+		server, calls := flakyServer(t, 1, 0)
+		client := newRetryClient(t, server, 3)
+
+		_, err := client.GetJobStatus(context.Background(), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("job creation", func(t *testing.T) {
+		server, calls := flakyServer(t, 1, http.StatusBadGateway)
+		client := newRetryClient(t, server, 3)
+
+		job, err := client.CreateJob(context.Background(), "test/linecount", WithParams(map[string]any{"k": "v"}))
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCreated, job.CurrentStatus())
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusGatewayTimeout)
+		client := newRetryClient(t, server, 2)
+
+		_, err := client.GetJobStatus(context.Background(), uuid.New())
+		assert.ErrorContains(t, err, "status 504")
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("client errors and submissions are not retried", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusNotFound)
+		client := newRetryClient(t, server, 3)
+
+		_, err := client.GetJobStatus(context.Background(), uuid.New())
+		assert.ErrorContains(t, err, "status 404")
+		assert.Equal(t, int32(1), calls.Load())
+
+		server, calls = flakyServer(t, 10, http.StatusServiceUnavailable)
+		client = newRetryClient(t, server, 3)
+
+		_, err = client.SubmitJobWithResponse(context.Background(), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("respects the context deadline", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusServiceUnavailable)
+		client, err := NewBsubClient(Config{
+			APIKey:       "test-api-key",
+			BaseURL:      server.URL,
+			MaxRetries:   5,
+			RetryBackoff: time.Minute,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err = client.GetJobStatus(ctx, uuid.New())
+		assert.ErrorContains(t, err, "status 503")
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("configuration", func(t *testing.T) {
+		_, err := NewBsubClient(Config{APIKey: "test-api-key", MaxRetries: -1})
+		assert.Error(t, err)
+
+		_, err = NewBsubClient(Config{APIKey: "test-api-key", MaxRetries: 1, RetryStatusCodes: []int{http.StatusBadRequest}})
+		assert.Error(t, err)
+
+		server, calls := flakyServer(t, 1, http.StatusInternalServerError)
+		client, err := NewBsubClient(Config{
+			APIKey:           "test-api-key",
+			BaseURL:          server.URL,
+			MaxRetries:       1,
+			RetryBackoff:     time.Millisecond,
+			RetryStatusCodes: []int{http.StatusInternalServerError},
+		})
+		require.NoError(t, err)
+
+		_, err = client.GetJobStatus(context.Background(), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+// TestRateLimitRetry tests that throttled requests wait for Retry-After and are repeated
+func TestRateLimitRetry(t *testing.T) {
+	t.Run("job created after two 429s", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"data":{"id":"` + uuid.NewString() + `","status":"created","upload_token":"t"}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL})
+		require.NoError(t, err)
+
+		job, err := client.CreateJob(context.Background(), "test/linecount")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCreated, job.CurrentStatus())
+		assert.Equal(t, int32(3), calls.Load())
+		assert.Equal(t, int64(2), client.Stats().Retries)
+	})
+
+	t.Run("falls back to backoff and gives up", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusTooManyRequests)
+		client, err := NewBsubClient(Config{
+			APIKey:              "test-api-key",
+			BaseURL:             server.URL,
+			RetryBackoff:        time.Millisecond,
+			MaxRateLimitRetries: 2,
+		})
+		require.NoError(t, err)
+
+		_, err = client.GetJobStatus(context.Background(), uuid.New())
+		assert.ErrorContains(t, err, "status 429")
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusTooManyRequests)
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL, MaxRateLimitRetries: -1})
+		require.NoError(t, err)
+
+		_, err = client.GetJobStatus(context.Background(), uuid.New())
+		assert.ErrorContains(t, err, "status 429")
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+// TestRetryAfter tests parsing both forms of the Retry-After header
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	delay, ok := retryAfter("7", now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, delay)
+
+	delay, ok = retryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, delay)
+
+	delay, ok = retryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Zero(t, delay)
+
+	_, ok = retryAfter("", now)
+	assert.False(t, ok)
+	_, ok = retryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+// TestInterceptors tests request and response interceptors around API calls
+func TestInterceptors(t *testing.T) {
+	t.Run("run on every call", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+
+		var authSeen []string
+		var statuses []int
+		client, err := NewBsubClientWithOptions(
+			WithAPIKey("test-api-key"),
+			WithAPIBaseURL(mockServer.URL),
+			WithRequestInterceptor(func(req *http.Request) error {
+				authSeen = append(authSeen, req.Header.Get("Authorization"))
+				req.Header.Set("X-Tenant", "acme")
+				return nil
+			}),
+			WithResponseInterceptor(func(resp *http.Response) error {
+				statuses = append(statuses, resp.StatusCode)
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+		client.pollInterval = testPollInterval
+
+		_, err = client.Process(context.Background(), "test/linecount", strings.NewReader("a\nb"))
+		require.NoError(t, err)
+
+		assert.Equal(t, "acme", mockServer.LastRequest().Header.Get("X-Tenant"))
+		assert.Equal(t, "Bearer test-api-key", mockServer.LastRequest().Header.Get("Authorization"))
+		require.NotEmpty(t, authSeen)
+		for _, auth := range authSeen {
+			assert.Equal(t, "Bearer test-api-key", auth)
+		}
+		assert.Len(t, statuses, len(authSeen))
+		assert.Equal(t, http.StatusCreated, statuses[0])
+	})
+
+	t.Run("request interceptor error aborts the call", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+
+		errDenied := errors.New("denied by policy")
+		client, err := NewBsubClient(Config{
+			APIKey:  "test-api-key",
+			BaseURL: mockServer.URL,
+			RequestInterceptors: []RequestInterceptor{func(req *http.Request) error {
+				return errDenied
+			}},
+		})
+		require.NoError(t, err)
+
+		_, err = client.Process(context.Background(), "test/linecount", strings.NewReader("a\nb"))
+		assert.ErrorIs(t, err, errDenied)
+		assert.Nil(t, mockServer.LastRequest())
+	})
+
+	t.Run("response interceptor error aborts the call", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+
+		errAudit := errors.New("audit log unavailable")
+		client, err := NewBsubClient(Config{
+			APIKey:  "test-api-key",
+			BaseURL: mockServer.URL,
+			ResponseInterceptors: []ResponseInterceptor{func(resp *http.Response) error {
+				return errAudit
+			}},
+		})
+		require.NoError(t, err)
+
+		_, err = client.GetJobStatus(context.Background(), uuid.New())
+		assert.ErrorIs(t, err, errAudit)
+		assert.ErrorContains(t, err, "failed to get job status: response interceptor")
+	})
+}
+
+// TestRequestRateLimit tests pacing outgoing requests with the client-side rate limiter
+func TestRequestRateLimit(t *testing.T) {
+	t.Run("requests are paced", func(t *testing.T) {
+		server, calls := flakyServer(t, 0, 0)
+		client, err := NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithAPIBaseURL(server.URL), WithRateLimit(50, 1))
+		require.NoError(t, err)
+
+		start := time.Now()
+		for range 6 {
+			_, err := client.GetJobStatus(context.Background(), uuid.New())
+			require.NoError(t, err)
+		}
+
+		// The first request is sent at once, the other five 20ms apart
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, int32(6), calls.Load())
+	})
+
+	t.Run("shared across goroutines", func(t *testing.T) {
+		server, _ := flakyServer(t, 0, 0)
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL, RequestsPerSecond: 100, Burst: 2})
+		require.NoError(t, err)
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for range 12 {
+			wg.Go(func() {
+				_, err := client.GetJobStatus(context.Background(), uuid.New())
+				assert.NoError(t, err)
+			})
+		}
+		wg.Wait()
+
+		// Two requests fit in the burst, the other ten wait 10ms each
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		server, calls := flakyServer(t, 0, 0)
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", BaseURL: server.URL, RequestsPerSecond: 0.1})
+		require.NoError(t, err)
+
+		_, err = client.GetJobStatus(context.Background(), uuid.New())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		_, err = client.GetJobStatus(ctx, uuid.New())
+		assert.ErrorIs(t, err, context.Canceled)
+
+		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = client.GetJobStatus(ctx, uuid.New())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("negative rate", func(t *testing.T) {
+		_, err := NewBsubClient(Config{APIKey: "test-api-key", RequestsPerSecond: -1})
+		assert.ErrorContains(t, err, "invalid rate limit")
+	})
+}
+
+// TestDefaultTimeout tests that slow requests are cut off while long polls and downloads are not
+func TestDefaultTimeout(t *testing.T) {
+	t.Run("slow request", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+		client, err := NewBsubClientWithOptions(WithAPIKey("test-api-key"), WithAPIBaseURL(mockServer.URL), WithDefaultTimeout(20*time.Millisecond))
+		require.NoError(t, err)
+
+		job, err := client.CreateJob(context.Background(), "test/linecount")
+		require.NoError(t, err)
+
+		mockServer.SetDelay("/v1/jobs/", 200*time.Millisecond)
+		_, err = client.GetJobStatus(context.Background(), *job.Id)
+		assert.ErrorIs(t, err, ErrRequestTimeout)
+		assert.NotErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("long polls and downloads are exempt", func(t *testing.T) {
+		mockServer := NewMockServer()
+		defer mockServer.Close()
+		mockServer.SetLongPoll(true)
+		mockServer.SetProgression("test/slow", JobStatusProcessing, JobStatusProcessing, JobStatusFinished)
+
+		client, err := NewBsubClient(Config{
+			APIKey:         "test-api-key",
+			BaseURL:        mockServer.URL,
+			UseLongPoll:    true,
+			DefaultTimeout: 50 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		client.longPollWait = 200 * time.Millisecond
+		client.pollInterval = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/slow", strings.NewReader("data"))
+		require.NoError(t, err)
+		finalJob, err := client.WaitForJob(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *finalJob.Status)
+
+		mockServer.SetDelay("/output", 100*time.Millisecond)
+		result, err := client.GetJobResult(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Output)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		client, err := NewBsubClient(Config{APIKey: "test-api-key", DefaultTimeout: -1})
+		require.NoError(t, err)
+		assert.Zero(t, client.requestTimeout)
+
+		client, err = NewBsubClient(Config{APIKey: "test-api-key"})
+		require.NoError(t, err)
+		assert.Equal(t, DefaultRequestTimeout, client.requestTimeout)
+	})
+}
@@ -0,0 +1,153 @@
+package bsubio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ListTypes returns the processing types the server supports.
+// A server that supports no types yields an empty slice and a nil error,
+// while a response without a types list is reported as an error.
+func (c *BsubClient) ListTypes(ctx context.Context) ([]ProcessingType, error) {
+	resp, err := c.GetTypesWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get types: %w", err)
+	}
+
+	return c.typesFromResponse(resp)
+}
+
+// typesFromResponse reads the type list from a types endpoint response
+func (c *BsubClient) typesFromResponse(resp *GetTypesResponse) ([]ProcessingType, error) {
+	if resp.StatusCode() != http.StatusOK {
+		return nil, c.apiError("get types", resp.HTTPResponse, resp.Body)
+	}
+
+	if resp.JSON200 == nil || resp.JSON200.Types == nil {
+		return nil, fmt.Errorf("types endpoint returned no data")
+	}
+
+	return *resp.JSON200.Types, nil
+}
+
+// defaultTypeCacheTTL is how long the type list is reused by default
+const defaultTypeCacheTTL = 5 * time.Minute
+
+// typeCache holds the type list last fetched from the types endpoint, along with its
+// sorted names and the ETag to revalidate it with
+type typeCache struct {
+	mu        sync.Mutex
+	types     []ProcessingType
+	names     []string
+	etag      string
+	fetchedAt time.Time
+}
+
+// GetTypesCached returns the processing types the server supports, like ListTypes, but
+// reuses the list for Config.TypeCacheTTL. Once that has passed the list is revalidated
+// with a conditional request carrying its ETag, so an unchanged list costs a 304 Not
+// Modified response rather than a download. The cache is shared with ValidateJobType.
+func (c *BsubClient) GetTypesCached(ctx context.Context) ([]ProcessingType, error) {
+	c.types.mu.Lock()
+	defer c.types.mu.Unlock()
+
+	if err := c.loadTypes(ctx, false); err != nil {
+		return nil, err
+	}
+	return slices.Clone(c.types.types), nil
+}
+
+// RefreshTypes downloads the type list unconditionally, replacing the one cached for
+// GetTypesCached and ValidateJobType, and returns it
+func (c *BsubClient) RefreshTypes(ctx context.Context) ([]ProcessingType, error) {
+	c.types.mu.Lock()
+	defer c.types.mu.Unlock()
+
+	if err := c.loadTypes(ctx, true); err != nil {
+		return nil, err
+	}
+	return slices.Clone(c.types.types), nil
+}
+
+// ValidateJobType reports whether the server supports jobType. The type list is fetched
+// from the types endpoint and reused for Config.TypeCacheTTL, so validating before every
+// submission costs one request per TTL rather than one per job.
+func (c *BsubClient) ValidateJobType(ctx context.Context, jobType string) (bool, error) {
+	names, err := c.jobTypeNames(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, found := slices.BinarySearch(names, jobType)
+	return found, nil
+}
+
+// checkJobType returns an *UnknownJobTypeError when the server does not support jobType
+func (c *BsubClient) checkJobType(ctx context.Context, jobType string) error {
+	names, err := c.jobTypeNames(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to validate job type: %w", err)
+	}
+	if _, found := slices.BinarySearch(names, jobType); !found {
+		return &UnknownJobTypeError{JobType: jobType, ValidTypes: slices.Clone(names)}
+	}
+	return nil
+}
+
+// jobTypeNames returns the sorted job type names, from the cache while it is fresh.
+// The cache lock is held during the fetch, so concurrent callers share one request.
+func (c *BsubClient) jobTypeNames(ctx context.Context) ([]string, error) {
+	c.types.mu.Lock()
+	defer c.types.mu.Unlock()
+
+	if err := c.loadTypes(ctx, false); err != nil {
+		return nil, err
+	}
+	return c.types.names, nil
+}
+
+// loadTypes fills the type cache, unless it is still fresh and refresh is false. A stale
+// list is revalidated with its ETag. The caller must hold c.types.mu.
+func (c *BsubClient) loadTypes(ctx context.Context, refresh bool) error {
+	cached := c.types.names != nil
+	if cached && !refresh && time.Since(c.types.fetchedAt) < c.typeCacheTTL {
+		return nil
+	}
+
+	var editors []RequestEditorFn
+	if cached && !refresh && c.types.etag != "" {
+		editors = append(editors, withHeader("If-None-Match", c.types.etag))
+	}
+
+	resp, err := c.GetTypesWithResponse(ctx, editors...)
+	if err != nil {
+		return fmt.Errorf("failed to get types: %w", err)
+	}
+
+	if resp.StatusCode() == http.StatusNotModified && len(editors) > 0 {
+		c.types.fetchedAt = time.Now()
+		return nil
+	}
+
+	types, err := c.typesFromResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(types))
+	for _, procType := range types {
+		if procType.Type != nil {
+			names = append(names, *procType.Type)
+		}
+	}
+	slices.Sort(names)
+
+	c.types.types = types
+	c.types.names = names
+	c.types.etag = resp.HTTPResponse.Header.Get("ETag")
+	c.types.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,171 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListTypes tests the typed wrapper around the types endpoint
+func TestListTypes(t *testing.T) {
+	t.Run("returns server types", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		types, err := client.ListTypes(context.Background())
+		require.NoError(t, err)
+		require.NotEmpty(t, types)
+
+		found := false
+		for _, procType := range types {
+			if procType.Type != nil && *procType.Type == "test/linecount" {
+				found = true
+			}
+		}
+		assert.True(t, found, "test/linecount should be listed")
+	})
+
+	t.Run("empty list is not an error", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{"types": []}`)
+
+		types, err := client.ListTypes(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, types)
+		assert.Empty(t, types)
+	})
+
+	t.Run("missing types list", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{}`)
+
+		types, err := client.ListTypes(context.Background())
+		require.Error(t, err)
+		assert.Nil(t, types)
+		assert.Contains(t, err.Error(), "types endpoint returned no data")
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		client := newCannedClient(t, 200, `{"types": "nope"}`)
+
+		_, err := client.ListTypes(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get types")
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		client := newCannedClient(t, 500, `{"error": "boom"}`)
+
+		_, err := client.ListTypes(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 500")
+	})
+}
+
+// TestValidateJobType tests checking job types against the cached type list
+func TestValidateJobType(t *testing.T) {
+	t.Run("known and unknown types", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		ctx := context.Background()
+		valid, err := client.ValidateJobType(ctx, "test/linecount")
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = client.ValidateJobType(ctx, "test/linecont")
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		assert.Equal(t, int64(1), client.Stats().Requests["GetTypes"], "type list should be cached")
+	})
+
+	t.Run("negative TTL disables the cache", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.typeCacheTTL = -1
+
+		for range 2 {
+			_, err := client.ValidateJobType(context.Background(), "test/linecount")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(2), client.Stats().Requests["GetTypes"])
+	})
+
+	t.Run("process rejects unknown type before creating a job", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		_, err := client.Process(context.Background(), "test/linecont", bytes.NewReader([]byte("a\nb")), WithJobTypeValidation())
+		require.ErrorIs(t, err, ErrUnknownJobType)
+
+		var typeErr *UnknownJobTypeError
+		require.ErrorAs(t, err, &typeErr)
+		assert.Equal(t, "test/linecont", typeErr.JobType)
+		assert.Contains(t, typeErr.ValidTypes, "test/linecount")
+		assert.Contains(t, err.Error(), "test/linecount")
+		assert.Zero(t, client.Stats().Requests["CreateJob"])
+	})
+
+	t.Run("process accepts known type", func(t *testing.T) {
+		client, _, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.pollInterval = testPollInterval
+
+		result, err := client.Process(context.Background(), "test/linecount", bytes.NewReader([]byte("a\nb")), WithJobTypeValidation())
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFinished, *result.Job.Status)
+	})
+
+	t.Run("types endpoint failure", func(t *testing.T) {
+		client := newCannedClient(t, 500, `{"error": "boom"}`)
+
+		_, err := client.CreateJob(context.Background(), "test/linecount", WithJobTypeValidation())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to validate job type")
+		assert.NotErrorIs(t, err, ErrUnknownJobType)
+	})
+}
+
+// TestGetTypesCached tests reusing the type list and revalidating it with its ETag
+func TestGetTypesCached(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted types only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	types, err := client.GetTypesCached(ctx)
+	require.NoError(t, err)
+	require.Len(t, types, 1)
+
+	// Within the TTL no request is made
+	_, err = client.GetTypesCached(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), client.Stats().Requests["GetTypes"])
+
+	// Once stale, an unchanged list is revalidated without a download
+	client.typeCacheTTL = -1
+	downloaded := client.Stats().BytesDownloaded
+	cached, err := client.GetTypesCached(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types, cached)
+	assert.NotEmpty(t, mockServer.LastRequest().Header.Get("If-None-Match"))
+	assert.Equal(t, downloaded, client.Stats().BytesDownloaded)
+
+	// A changed list is downloaded again, and validation sees it
+	typeName := "test/new"
+	mockServer.SetTypes(append(types, ProcessingType{Type: &typeName}))
+	valid, err := client.ValidateJobType(ctx, "test/new")
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// Refreshing skips the ETag
+	refreshed, err := client.RefreshTypes(ctx)
+	require.NoError(t, err)
+	assert.Len(t, refreshed, 2)
+	assert.Empty(t, mockServer.LastRequest().Header.Get("If-None-Match"))
+}
@@ -0,0 +1,445 @@
+package bsubio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Headers of the resumable upload protocol. A HEAD request on the upload URL reports how
+// many bytes the server holds in UploadOffsetHeader; each PATCH request appends the bytes
+// starting at that offset and answers with the new offset.
+const (
+	UploadOffsetHeader = "Upload-Offset"
+	UploadLengthHeader = "Upload-Length"
+)
+
+// resumableContentType is the content type of resumable upload chunks
+const resumableContentType = "application/offset+octet-stream"
+
+// defaultUploadChunkSize is how much ResumableUpload sends per request
+const defaultUploadChunkSize = 8 << 20
+
+// maxUploadChunkFailures is how many times in a row ResumableUpload retries a chunk
+const maxUploadChunkFailures = 3
+
+// ErrResumableUploadUnsupported is returned by StartUpload when the server only accepts
+// single-shot uploads
+var ErrResumableUploadUnsupported = errors.New("server does not support resumable uploads")
+
+// UploadSession tracks a resumable upload of a job's input. It is not safe for concurrent use.
+type UploadSession struct {
+	client *BsubClient
+	token  string
+
+	// JobID is the job the input belongs to
+	JobID JobId
+	// Size is the total input size in bytes
+	Size int64
+	// Offset is how many bytes the server has received
+	Offset int64
+}
+
+// StartUpload opens a resumable upload of size bytes for the job, authorized by its upload
+// token. The session starts at the offset reported by the server, so calling StartUpload
+// again after a crash resumes where the previous upload stopped.
+func (c *BsubClient) StartUpload(ctx context.Context, jobID JobId, token string, size int64) (*UploadSession, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid input: negative size %d", size)
+	}
+
+	session := &UploadSession{client: c, token: token, JobID: jobID, Size: size}
+	if err := session.Resume(ctx); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Resume asks the server how many bytes it holds and moves Offset there. Call it after
+// a failed UploadChunk, whose bytes may have been partially received.
+func (s *UploadSession) Resume(ctx context.Context) error {
+	resp, err := s.client.UploadJobDataWithBody(ctx, s.JobID, &UploadJobDataParams{Token: s.token}, resumableContentType, nil,
+		withMethod(http.MethodHead),
+		withHeader(UploadLengthHeader, strconv.FormatInt(s.Size, 10)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get upload offset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return ErrResumableUploadUnsupported
+	default:
+		return s.client.apiError("get upload offset", resp, nil)
+	}
+
+	offset, err := uploadOffset(resp)
+	if err != nil {
+		if resp.Header.Get(UploadOffsetHeader) == "" {
+			return ErrResumableUploadUnsupported
+		}
+		return err
+	}
+
+	s.Offset = offset
+	return nil
+}
+
+// UploadChunk sends up to n bytes of r, starting at Offset, and advances Offset to what
+// the server acknowledged
+func (s *UploadSession) UploadChunk(ctx context.Context, r io.ReaderAt, n int64) error {
+	n = min(n, s.Size-s.Offset)
+	if n <= 0 {
+		return nil
+	}
+
+	resp, err := s.client.UploadJobDataWithBody(ctx, s.JobID, &UploadJobDataParams{Token: s.token}, resumableContentType,
+		io.NewSectionReader(r, s.Offset, n),
+		withMethod(http.MethodPatch),
+		withHeader(UploadOffsetHeader, strconv.FormatInt(s.Offset, 10)),
+		withContentLength(n),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upload chunk: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return s.client.apiError(fmt.Sprintf("upload chunk at offset %d", s.Offset), resp, nil)
+	}
+
+	offset, err := uploadOffset(resp)
+	if err != nil {
+		return err
+	}
+
+	s.Offset = offset
+	return nil
+}
+
+// Done reports whether the server has received the whole input
+func (s *UploadSession) Done() bool {
+	return s.Offset >= s.Size
+}
+
+// ResumableUpload uploads the first size bytes of r as the input of a created job, in
+// chunks that are retried from the server-reported offset when a request fails. Servers
+// without resumable upload support get a single-shot upload instead, which starts over
+// when it fails. The job still has to be submitted afterwards.
+func (c *BsubClient) ResumableUpload(ctx context.Context, jobID JobId, r io.ReaderAt, size int64) error {
+	if r == nil {
+		return ErrNilInput
+	}
+
+	jobResp, err := c.GetJobWithResponse(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if jobResp.StatusCode() != http.StatusOK {
+		return c.apiError("get job", jobResp.HTTPResponse, jobResp.Body)
+	}
+
+	if jobResp.JSON200 == nil || jobResp.JSON200.Data == nil || jobResp.JSON200.Data.UploadToken == nil {
+		return fmt.Errorf("no upload token in response")
+	}
+	return c.resumableUpload(ctx, jobID, *jobResp.JSON200.Data.UploadToken, r, size, newCallOptions(nil))
+}
+
+// resumableUpload is ResumableUpload for a job whose upload token is known. The upload
+// progress is reported after every chunk; the single-shot fallback honors all options.
+func (c *BsubClient) resumableUpload(ctx context.Context, jobID JobId, token string, r io.ReaderAt, size int64, options *callOptions) error {
+	session, err := c.StartUpload(ctx, jobID, token, size)
+	if errors.Is(err, ErrResumableUploadUnsupported) {
+		return c.singleShotUpload(ctx, jobID, token, r, size, options)
+	}
+	if err != nil {
+		return err
+	}
+
+	failures := 0
+	for !session.Done() {
+		err := session.UploadChunk(ctx, r, c.uploadChunkSize)
+		if err == nil {
+			failures = 0
+			if options.onUploadProgress != nil {
+				options.onUploadProgress(session.Offset, size)
+			}
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		failures++
+		if failures >= maxUploadChunkFailures {
+			return fmt.Errorf("failed to upload data: %w", err)
+		}
+
+		if err := session.Resume(ctx); err != nil {
+			return fmt.Errorf("failed to resume upload: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CreateAndSubmitJobFromFileResumable is CreateAndSubmitJobFromFile for very large files:
+// the file is sent with ResumableUpload, so a dropped connection only costs the chunk in
+// flight. With WithUploadState the job is recorded in a state file once it is created,
+// and a later call for the same, unchanged file, e.g. after a process restart, continues
+// that job's upload from the offset the server holds instead of creating a new job. The
+// state file is removed once the job is submitted.
+//
+// On servers without resumable upload support the file is uploaded in a single request
+// that is retried from the start, so only the job, not the bytes sent, survives a restart.
+//
+// Chunks carry the raw bytes of the file, so WithUploadChecksum, WithVerifyChecksum,
+// WithUploadCompression and WithUploadFilename are rejected with ErrUnsupportedOption.
+// WithUploadProgress is reported after every chunk.
+func (c *BsubClient) CreateAndSubmitJobFromFileResumable(ctx context.Context, jobType string, filePath string, opts ...CallOption) (*Job, error) {
+	jobType = jobTypeFromContext(ctx, jobType)
+	if err := validateJobType(jobType); err != nil {
+		return nil, err
+	}
+	if filePath == "" {
+		return nil, ErrEmptyFilePath
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	options := newCallOptions(opts)
+	if err := checkResumableOptions(options); err != nil {
+		return nil, err
+	}
+	state := uploadState{FilePath: filePath, Size: info.Size(), ModTime: info.ModTime()}
+
+	job, err := c.resumeUploadState(ctx, options.uploadState, state)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		if job, err = c.createJob(ctx, jobType, nil, options); err != nil {
+			return nil, err
+		}
+		if options.uploadState != "" {
+			state.JobID, state.Token = *job.Id, *job.UploadToken
+			if err := writeUploadState(options.uploadState, state); err != nil {
+				return nil, c.abandonJob(ctx, *job.Id, err, options.cleanupOnError)
+			}
+		}
+	}
+
+	// A resumed job, or one returned for a repeated idempotency key, may be submitted already
+	if !isTerminal(job.Status) && !job.CurrentStatus().AtLeast(JobStatusPending) {
+		err := c.resumableUpload(ctx, *job.Id, *job.UploadToken, file, info.Size(), options)
+		if err == nil {
+			err = c.submitJob(ctx, *job.Id)
+		}
+		if err != nil {
+			// A job recorded in a state file is kept for the next call to resume
+			return nil, c.abandonJob(ctx, *job.Id, err, options.cleanupOnError && options.uploadState == "")
+		}
+	}
+
+	if options.uploadState != "" {
+		// A leftover state file is harmless: its job is found submitted next time
+		os.Remove(options.uploadState)
+	}
+	return job, nil
+}
+
+// uploadState is the content of a WithUploadState file. The file is identified by its
+// path, size and modification time, so a file changed since does not resume the job.
+type uploadState struct {
+	JobID    JobId     `json:"job_id"`
+	Token    string    `json:"upload_token"`
+	FilePath string    `json:"file_path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+}
+
+// resumeUploadState returns the job recorded in the state file at path when it is for
+// the file described by want and still exists, and nil when there is none to resume
+func (c *BsubClient) resumeUploadState(ctx context.Context, path string, want uploadState) (*Job, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload state: %w", err)
+	}
+
+	var state uploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse upload state %s: %w", path, err)
+	}
+	if state.FilePath != want.FilePath || state.Size != want.Size || !state.ModTime.Equal(want.ModTime) {
+		return nil, nil
+	}
+
+	job, err := c.getJob(ctx, state.JobID)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Id == nil {
+		job.Id = &state.JobID
+	}
+	if job.UploadToken == nil {
+		job.UploadToken = &state.Token
+	}
+	return job, nil
+}
+
+// writeUploadState saves state to path, readable only by the owner as it holds the
+// upload token
+func writeUploadState(path string, state uploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	return nil
+}
+
+// singleShotUpload uploads the whole input in one request for servers without resumable
+// upload support. Without an offset to resume from, a failed request is retried from the
+// start, up to maxUploadChunkFailures times; client errors other than 408 and 429 are not
+// retried, as repeating the same request cannot fix them.
+func (c *BsubClient) singleShotUpload(ctx context.Context, jobID JobId, token string, r io.ReaderAt, size int64, options *callOptions) error {
+	var err error
+	for range maxUploadChunkFailures {
+		err = c.upload(ctx, jobID, token, io.NewSectionReader(r, 0, size), options)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError &&
+			apiErr.StatusCode != http.StatusRequestTimeout && apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+	}
+	return err
+}
+
+// checkResumableOptions rejects the upload options resumable uploads cannot honor: chunks
+// are sent as raw bytes, without the multipart form, checksum header or compression of
+// single-request uploads
+func checkResumableOptions(options *callOptions) error {
+	var option string
+	switch {
+	case options.checksum:
+		option = "WithUploadChecksum"
+	case options.verifyChecksum != nil && *options.verifyChecksum:
+		option = "WithVerifyChecksum"
+	case options.compressUpload != nil && *options.compressUpload:
+		option = "WithUploadCompression"
+	case options.uploadFilename != "":
+		option = "WithUploadFilename"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: %s with resumable uploads", ErrUnsupportedOption, option)
+}
+
+// uploadOffset reads the offset the server reported in a resumable upload response
+func uploadOffset(resp *http.Response) (int64, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get(UploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid %s header %q", UploadOffsetHeader, resp.Header.Get(UploadOffsetHeader))
+	}
+	return offset, nil
+}
+
+// withContentLength returns a request editor that declares the body length of a single call,
+// for bodies net/http cannot measure by itself
+func withContentLength(n int64) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.ContentLength = n
+		return nil
+	}
+}
+
+// withGetBody returns a request editor that lets a single call's body be replayed, for
+// bodies net/http cannot copy by itself
+func withGetBody(getBody func() (io.ReadCloser, error)) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.GetBody = getBody
+		return nil
+	}
+}
+
+// progressReader reports how much of the input embedded in an upload body has been read.
+// The input occupies size bytes starting at start; the bytes around it, such as the
+// multipart headers, are not counted.
+type progressReader struct {
+	r          io.Reader
+	start      int64
+	size       int64
+	read       int64
+	onProgress func(bytesSent, totalBytes int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		before := min(max(p.read-p.start, 0), p.size)
+		p.read += int64(n)
+		if sent := min(max(p.read-p.start, 0), p.size); sent != before {
+			p.onProgress(sent, p.size)
+		}
+	}
+	return n, err
+}
+
+// uploadProgressWriter reports how much of the input has been written to an upload form. When
+// the form is written into a pipe, a write returns once the request has read it.
+type uploadProgressWriter struct {
+	w          io.Writer
+	size       int64
+	written    int64
+	onProgress func(bytesSent, totalBytes int64)
+}
+
+func (p *uploadProgressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if n > 0 {
+		p.written += int64(n)
+		p.onProgress(p.written, p.size)
+	}
+	return n, err
+}
@@ -0,0 +1,460 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestJob creates a job without uploading anything
+func createTestJob(t *testing.T, client *BsubClient) *Job {
+	t.Helper()
+
+	job, err := client.CreateJob(context.Background(), "test/linecount")
+	require.NoError(t, err)
+	return job
+}
+
+// TestResumableUpload tests chunked uploads that recover from failed requests
+func TestResumableUpload(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Resumable uploads only supported in mock mode")
+	}
+
+	input := bytes.Repeat([]byte("0123456789\n"), 500)
+
+	t.Run("resumes after failed chunks", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.uploadChunkSize = 1000
+		mockServer.SetResumableUploads(true)
+		mockServer.FailUploadChunks(2)
+
+		job := createTestJob(t, client)
+		ctx := context.Background()
+		require.NoError(t, client.ResumableUpload(ctx, *job.Id, bytes.NewReader(input), int64(len(input))))
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		assert.Equal(t, "PATCH", mockServer.LastRequest().Method)
+
+		// The uploaded job can be submitted and processed as usual
+		submitResp, err := client.SubmitJobWithResponse(ctx, *job.Id)
+		require.NoError(t, err)
+		require.Equal(t, 200, submitResp.StatusCode())
+
+		result, err := client.GetJobResult(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "500", string(result.Output))
+	})
+
+	t.Run("gives up after repeated failures", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.uploadChunkSize = 1000
+		mockServer.SetResumableUploads(true)
+		mockServer.FailUploadChunks(maxUploadChunkFailures)
+
+		job := createTestJob(t, client)
+		err := client.ResumableUpload(context.Background(), *job.Id, bytes.NewReader(input), int64(len(input)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to upload data")
+	})
+
+	t.Run("session resumes from the server offset", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.SetResumableUploads(true)
+
+		job := createTestJob(t, client)
+		ctx := context.Background()
+		r := bytes.NewReader(input)
+
+		session, err := client.StartUpload(ctx, *job.Id, *job.UploadToken, int64(len(input)))
+		require.NoError(t, err)
+		assert.Zero(t, session.Offset)
+		require.NoError(t, session.UploadChunk(ctx, r, 1234))
+		assert.Equal(t, int64(1234), session.Offset)
+
+		// A new session, e.g. after a crash, picks up where the first one stopped
+		session, err = client.StartUpload(ctx, *job.Id, *job.UploadToken, int64(len(input)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), session.Offset)
+		for !session.Done() {
+			require.NoError(t, session.UploadChunk(ctx, r, 4096))
+		}
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+	})
+
+	t.Run("falls back to a single-shot upload", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		job := createTestJob(t, client)
+		ctx := context.Background()
+
+		_, err := client.StartUpload(ctx, *job.Id, *job.UploadToken, int64(len(input)))
+		assert.ErrorIs(t, err, ErrResumableUploadUnsupported)
+
+		require.NoError(t, client.ResumableUpload(ctx, *job.Id, bytes.NewReader(input), int64(len(input))))
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		assert.Equal(t, "POST", mockServer.LastRequest().Method)
+	})
+}
+
+// TestCreateAndSubmitJobFromFileResumable tests continuing an interrupted upload from its state file
+func TestCreateAndSubmitJobFromFileResumable(t *testing.T) {
+	if GetTestMode() == TestModeProduction {
+		t.Skip("Resumable uploads only supported in mock mode")
+	}
+
+	dir := t.TempDir()
+	input := bytes.Repeat([]byte("0123456789\n"), 500)
+	path := filepath.Join(dir, "input.txt")
+	require.NoError(t, os.WriteFile(path, input, 0644))
+
+	t.Run("resumes after a restart", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.uploadChunkSize = 1000
+		mockServer.SetResumableUploads(true)
+		mockServer.FailUploadChunks(maxUploadChunkFailures)
+
+		ctx := context.Background()
+		statePath := filepath.Join(dir, "upload.json")
+
+		_, err := client.CreateAndSubmitJobFromFileResumable(ctx, "test/linecount", path, WithUploadState(statePath))
+		var jobErr *IncompleteJobError
+		require.ErrorAs(t, err, &jobErr)
+		assert.False(t, jobErr.Deleted)
+		assert.Less(t, len(mockServer.UploadedData(jobErr.JobID)), len(input))
+
+		info, err := os.Stat(statePath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		// The next call, as after a restart, continues the same job
+		job, err := client.CreateAndSubmitJobFromFileResumable(ctx, "test/linecount", path, WithUploadState(statePath))
+		require.NoError(t, err)
+		assert.Equal(t, jobErr.JobID, *job.Id)
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		assert.NoFileExists(t, statePath)
+
+		client.pollInterval = testPollInterval
+		result, err := client.WaitForJobResult(ctx, *job.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "500", result.OutputString())
+	})
+
+	t.Run("changed file starts a new job", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		mockServer.SetResumableUploads(true)
+
+		ctx := context.Background()
+		statePath := filepath.Join(dir, "stale.json")
+		stale, err := client.CreateJob(ctx, "test/linecount")
+		require.NoError(t, err)
+		require.NoError(t, writeUploadState(statePath, uploadState{
+			JobID:    *stale.Id,
+			Token:    *stale.UploadToken,
+			FilePath: path,
+			Size:     int64(len(input)) - 1,
+		}))
+
+		job, err := client.CreateAndSubmitJobFromFileResumable(ctx, "test/linecount", path, WithUploadState(statePath))
+		require.NoError(t, err)
+		assert.NotEqual(t, *stale.Id, *job.Id)
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		assert.NoFileExists(t, statePath)
+	})
+
+	t.Run("falls back to a single-shot upload", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		var sent int64
+		job, err := client.CreateAndSubmitJobFromFileResumable(context.Background(), "test/linecount", path,
+			WithUploadProgress(func(bytesSent, totalBytes int64) { sent = bytesSent }))
+		require.NoError(t, err)
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		assert.Equal(t, int64(len(input)), sent)
+	})
+
+	t.Run("progress per chunk", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+		client.uploadChunkSize = 2000
+		mockServer.SetResumableUploads(true)
+
+		var sent []int64
+		_, err := client.CreateAndSubmitJobFromFileResumable(context.Background(), "test/linecount", path,
+			WithUploadProgress(func(bytesSent, totalBytes int64) {
+				assert.Equal(t, int64(len(input)), totalBytes)
+				sent = append(sent, bytesSent)
+			}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2000, 4000, int64(len(input))}, sent)
+	})
+
+	t.Run("unsupported options", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		for _, opt := range []CallOption{WithUploadChecksum(nil), WithVerifyChecksum(true), WithUploadCompression(true), WithUploadFilename("input.txt")} {
+			_, err := client.CreateAndSubmitJobFromFileResumable(context.Background(), "test/linecount", path, opt)
+			assert.ErrorIs(t, err, ErrUnsupportedOption)
+		}
+		if mockServer != nil {
+			assert.Zero(t, client.Stats().Requests["CreateJob"])
+		}
+	})
+}
+
+// TestUploadProgress tests the upload progress callback
+func TestUploadProgress(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+	path := filepath.Join(t.TempDir(), "input.bin")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	var sent []int64
+	job, err := client.CreateAndSubmitJobFromFileWithProgress(context.Background(), "test/linecount", path, func(bytesSent, totalBytes int64) {
+		assert.Equal(t, int64(len(data)), totalBytes)
+		sent = append(sent, bytesSent)
+	})
+	require.NoError(t, err)
+
+	require.Greater(t, len(sent), 1)
+	assert.True(t, slices.IsSorted(sent))
+	assert.Equal(t, int64(len(data)), sent[len(sent)-1])
+
+	if mockServer != nil {
+		assert.Equal(t, data, mockServer.UploadedData(*job.Id))
+	}
+
+	_, err = client.CreateAndSubmitJobFromFileWithProgress(context.Background(), "test/linecount", path, nil)
+	assert.ErrorIs(t, err, ErrNilCallback)
+}
+
+// countingReader generates size bytes of input, counting how many have been read
+type countingReader struct {
+	size int64
+	read atomic.Int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	left := r.size - r.read.Load()
+	if left <= 0 {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(b)), left))
+	for i := range n {
+		b[i] = 'a' + byte(i%26)
+	}
+	r.read.Add(int64(n))
+	return n, nil
+}
+
+// TestStreamedUpload tests that uploads are sent while the input is read rather than
+// buffered in memory first
+func TestStreamedUpload(t *testing.T) {
+	mockServer := NewMockServer()
+	defer mockServer.Close()
+
+	const size = 16 << 20
+	input := &countingReader{size: size}
+	readAtUpload := int64(-1)
+	client, err := NewBsubClient(Config{
+		APIKey:  "test-api-key",
+		BaseURL: mockServer.URL,
+		RequestInterceptors: []RequestInterceptor{func(req *http.Request) error {
+			if operationName(req) == "UploadJobData" {
+				readAtUpload = input.read.Load()
+			}
+			return nil
+		}},
+	})
+	require.NoError(t, err)
+
+	var sent []int64
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/linecount", input,
+		WithUploadProgress(func(bytesSent, totalBytes int64) {
+			assert.Equal(t, int64(-1), totalBytes)
+			sent = append(sent, bytesSent)
+		}))
+	require.NoError(t, err)
+
+	// Buffering the form would have read all of the input before sending the request
+	require.GreaterOrEqual(t, readAtUpload, int64(0))
+	assert.LessOrEqual(t, readAtUpload, int64(client.ioBufferSize))
+	assert.Equal(t, int64(size), input.read.Load())
+	assert.Len(t, mockServer.UploadedData(*job.Id), size)
+	require.NotEmpty(t, sent)
+	assert.Equal(t, int64(size), sent[len(sent)-1])
+
+	t.Run("input error", func(t *testing.T) {
+		errBroken := errors.New("disk on fire")
+		broken := io.MultiReader(strings.NewReader("a\nb\n"), iotest.ErrReader(errBroken))
+
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", broken)
+		require.ErrorIs(t, err, errBroken)
+		assert.Contains(t, err.Error(), "failed to copy data")
+
+		var jobErr *IncompleteJobError
+		require.ErrorAs(t, err, &jobErr)
+		assert.True(t, jobErr.Deleted)
+	})
+}
+
+// TestThrottledUpload tests that a streamed upload throttled with 429 is sent again
+// when its input can be rewound
+func TestThrottledUpload(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Throttling only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	input := []byte("a\nb\nc\n")
+
+	t.Run("seekable input is sent again", func(t *testing.T) {
+		mockServer.ThrottleUploads(2)
+		retries := client.Stats().Retries
+
+		// Start past a header, which the retries must not send
+		data := strings.NewReader("skip" + string(input))
+		_, err := data.Seek(4, io.SeekStart)
+		require.NoError(t, err)
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", data)
+		require.NoError(t, err)
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		assert.Equal(t, retries+2, client.Stats().Retries)
+	})
+
+	t.Run("other readers fail", func(t *testing.T) {
+		mockServer.ThrottleUploads(1)
+
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", io.MultiReader(bytes.NewReader(input)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 429")
+	})
+}
+
+// TestUploadContentLength tests sending uploads of known length with a Content-Length
+// and falling back to chunked encoding otherwise
+func TestUploadContentLength(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Upload inspection only supported in mock mode")
+	}
+
+	ctx := context.Background()
+	input := []byte("a\nb\nc\n")
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, input, 0644))
+
+	upload := func(t *testing.T, data io.Reader, opts ...CallOption) *Job {
+		t.Helper()
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", data, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, input, mockServer.UploadedData(*job.Id))
+		return job
+	}
+
+	t.Run("known length", func(t *testing.T) {
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		for name, data := range map[string]io.Reader{
+			"bytes":  bytes.NewReader(input),
+			"file":   file,
+			"seeker": struct{ io.ReadSeeker }{bytes.NewReader(input)},
+		} {
+			job := upload(t, data)
+			assert.Greater(t, mockServer.UploadContentLength(*job.Id), int64(len(input)), name)
+		}
+	})
+
+	t.Run("unknown length", func(t *testing.T) {
+		job := upload(t, iotest.OneByteReader(bytes.NewReader(input)))
+		assert.Equal(t, int64(-1), mockServer.UploadContentLength(*job.Id))
+	})
+
+	t.Run("declared length", func(t *testing.T) {
+		job := upload(t, iotest.OneByteReader(bytes.NewReader(input)), WithUploadSize(int64(len(input))))
+		assert.Greater(t, mockServer.UploadContentLength(*job.Id), int64(len(input)))
+
+		_, err := client.CreateAndSubmitJob(ctx, "test/linecount", iotest.OneByteReader(bytes.NewReader(input)), WithUploadSize(int64(len(input))+1))
+		assert.Error(t, err)
+	})
+}
+
+// TestUploadCompression tests gzipping uploads, and skipping it when it does not pay off
+func TestUploadCompression(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Upload inspection only supported in mock mode")
+	}
+	client.compressUploads = true
+
+	ctx := context.Background()
+	text := bytes.Repeat([]byte("a line of text\n"), 4096)
+
+	t.Run("compressible input", func(t *testing.T) {
+		var sum string
+		var sent, total int64
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(text),
+			WithUploadChecksum(&sum),
+			WithUploadProgress(func(bytesSent, totalBytes int64) { sent, total = bytesSent, totalBytes }))
+		require.NoError(t, err)
+
+		assert.Equal(t, "gzip", mockServer.UploadEncoding(*job.Id))
+		assert.Equal(t, text, mockServer.UploadedData(*job.Id))
+		assert.NotEmpty(t, sum)
+		assert.Equal(t, total, sent)
+		assert.Less(t, total, int64(len(text)))
+	})
+
+	t.Run("disabled per call", func(t *testing.T) {
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(text), WithUploadCompression(false))
+		require.NoError(t, err)
+
+		assert.Empty(t, mockServer.UploadEncoding(*job.Id))
+		assert.Equal(t, text, mockServer.UploadedData(*job.Id))
+	})
+
+	t.Run("incompressible input", func(t *testing.T) {
+		data := make([]byte, 4096)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+
+		job, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		assert.Empty(t, mockServer.UploadEncoding(*job.Id))
+		assert.Equal(t, data, mockServer.UploadedData(*job.Id))
+	})
+}
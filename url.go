@@ -0,0 +1,53 @@
+package bsubio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ProcessURL runs a job like Process on the content of sourceURL. The source is fetched
+// with the client's HTTP client and its body piped into the upload, which streams it to
+// the server, so remote files never touch the disk. Options that need the whole input
+// before sending, WithUploadCompression, WithUploadChecksum and WithJobRetry, hold it in
+// memory instead.
+//
+// Failures of the source itself, a failed request, an unexpected status code or a
+// broken download, are reported as *SourceError, distinct from the API errors of the
+// job. ctx bounds both the fetch and the job.
+func (c *BsubClient) ProcessURL(ctx context.Context, jobType string, sourceURL string, opts ...CallOption) (*JobResult, error) {
+	jobType = jobTypeFromContext(ctx, jobType)
+	if err := validateJobType(jobType); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, &SourceError{URL: sourceURL, Err: err}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &SourceError{URL: sourceURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &SourceError{URL: sourceURL, StatusCode: resp.StatusCode}
+	}
+
+	// Read errors of the source reach the upload through the pipe as SourceError
+	pr, pw := io.Pipe()
+	go func() {
+		if _, err := c.copyData(pw, resp.Body); err != nil {
+			pw.CloseWithError(&SourceError{URL: sourceURL, StatusCode: resp.StatusCode, Err: err})
+			return
+		}
+		pw.Close()
+	}()
+	// Unblock the copy if the job ends without reading the whole source
+	defer pr.CloseWithError(fmt.Errorf("upload of %s stopped", sourceURL))
+
+	return c.Process(ctx, jobType, pr, opts...)
+}
@@ -0,0 +1,74 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProcessURL tests processing a remote source and telling source failures apart
+func TestProcessURL(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/input.txt":
+			_, _ = io.WriteString(w, "a\nb\nc")
+		case "/truncated.txt":
+			w.Header().Set("Content-Length", "1000")
+			_, _ = io.WriteString(w, "a\nb\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer source.Close()
+
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Local source server only reachable in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	t.Run("streams the source", func(t *testing.T) {
+		result, err := client.ProcessURL(context.Background(), "test/linecount", source.URL+"/input.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "3", strings.TrimSpace(string(result.Output)))
+	})
+
+	t.Run("default job type", func(t *testing.T) {
+		ctx := WithDefaultJobType(context.Background(), "test/linecount")
+		result, err := client.ProcessURL(ctx, "", source.URL+"/input.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "test/linecount", *result.Job.Type)
+	})
+
+	t.Run("source status", func(t *testing.T) {
+		_, err := client.ProcessURL(context.Background(), "test/linecount", source.URL+"/missing.txt")
+		var sourceErr *SourceError
+		require.True(t, errors.As(err, &sourceErr), "error: %v", err)
+		assert.Equal(t, http.StatusNotFound, sourceErr.StatusCode)
+
+		var apiErr *APIError
+		assert.False(t, errors.As(err, &apiErr))
+	})
+
+	t.Run("broken download", func(t *testing.T) {
+		_, err := client.ProcessURL(context.Background(), "test/linecount", source.URL+"/truncated.txt")
+		var sourceErr *SourceError
+		require.True(t, errors.As(err, &sourceErr), "error: %v", err)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		_, err := client.ProcessURL(context.Background(), "test/linecount", "://nope")
+		var sourceErr *SourceError
+		assert.True(t, errors.As(err, &sourceErr))
+	})
+}
@@ -0,0 +1,376 @@
+package bsubio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// waitConcurrency is how many status requests WaitForAll has in flight at once
+const waitConcurrency = 8
+
+// JobEvent describes a change observed while waiting for a job
+type JobEvent struct {
+	// JobID identifies the job the event is about
+	JobID JobId
+	// Status is the job status at the time of the event
+	Status JobStatus
+	// Worker is the ID of the worker that claimed the job, empty until it is claimed
+	Worker string
+	// Job is the full snapshot the event was derived from
+	Job *Job
+}
+
+// WaitForJobEvents polls the job like WaitForJob, but reports every change of status
+// or claiming worker on the returned event channel instead of only returning the final job.
+//
+// Repeated identical observations are sent once. When the job reaches a terminal state,
+// or polling fails or ctx is done, the outcome (nil on success) is sent on the error
+// channel and both channels are closed.
+func (c *BsubClient) WaitForJobEvents(ctx context.Context, jobID JobId) (<-chan JobEvent, <-chan error) {
+	events := make(chan JobEvent)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(events)
+
+		var last JobEvent
+		var sendErr error
+		_, err := c.pollJob(ctx, jobID, func(job *Job) bool {
+			if job.Status == nil {
+				return false
+			}
+
+			worker, _ := job.Worker()
+			if *job.Status == last.Status && worker == last.Worker {
+				return false
+			}
+
+			event := JobEvent{
+				JobID:  jobID,
+				Status: *job.Status,
+				Worker: worker,
+				Job:    job,
+			}
+
+			select {
+			case events <- event:
+				last = event
+			case <-ctx.Done():
+				sendErr = ctx.Err()
+				return true
+			}
+			return isTerminal(job.Status)
+		})
+		if err == nil {
+			err = sendErr
+		}
+		errs <- err
+	}()
+
+	return events, errs
+}
+
+// ProcessEventKind names the step of processing a ProcessEvent reports
+type ProcessEventKind string
+
+// Kinds of ProcessEvent, in the order they are sent
+const (
+	// ProcessEventUploading is sent when the job is created and its input upload starts
+	ProcessEventUploading ProcessEventKind = "uploading"
+	// ProcessEventUploaded is sent when the whole input was uploaded
+	ProcessEventUploaded ProcessEventKind = "uploaded"
+	// ProcessEventSubmitted is sent when the job was submitted for processing
+	ProcessEventSubmitted ProcessEventKind = "submitted"
+	// ProcessEventStatusChanged is sent for every new job status seen while waiting
+	ProcessEventStatusChanged ProcessEventKind = "status_changed"
+	// ProcessEventFinished is the last event when the job finished and its result was
+	// retrieved
+	ProcessEventFinished ProcessEventKind = "finished"
+	// ProcessEventFailed is the last event when processing failed at any step
+	ProcessEventFailed ProcessEventKind = "failed"
+)
+
+// ProcessEvent describes a step of ProcessFileEvents
+type ProcessEvent struct {
+	Kind ProcessEventKind
+	// Time is when the step happened
+	Time time.Time
+	// JobID identifies the job, and is zero when it failed before the job was created
+	JobID JobId
+	// Status is the job status, set for status changes and the last event
+	Status JobStatus
+	// Result and Err are the outcome of processing, set on the last event like the return
+	// values of ProcessFile: a failed job has both
+	Result *JobResult
+	Err    error
+}
+
+// ProcessFileEvents processes a file like ProcessFile in the background and reports its
+// progress as events on the returned channel: the upload and submit steps, each change
+// of job status, and last ProcessEventFinished or ProcessEventFailed carrying the
+// outcome, after which the channel is closed. With WithJobRetry the steps repeat for
+// every attempt.
+//
+// Events are sent from the processing goroutine, which waits for the caller to take
+// each one, so the channel must be read until it is closed. If ctx is done, the
+// remaining events are skipped but the last one is still sent.
+func (c *BsubClient) ProcessFileEvents(ctx context.Context, jobType string, filePath string, opts ...CallOption) <-chan ProcessEvent {
+	events := make(chan ProcessEvent)
+
+	send := func(event ProcessEvent) {
+		event.Time = time.Now()
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	var lastStatus JobStatus
+	onPoll := newCallOptions(opts).onPoll
+	opts = append(opts,
+		withStepCallback(func(kind ProcessEventKind, job *Job) {
+			// Every attempt of WithJobRetry reports its statuses afresh
+			if kind == ProcessEventUploading {
+				lastStatus = ""
+			}
+			send(ProcessEvent{Kind: kind, JobID: *job.Id})
+		}),
+		WithPollCallback(func(job *Job) {
+			if onPoll != nil {
+				onPoll(job)
+			}
+			if status := job.CurrentStatus(); status != "" && status != lastStatus {
+				lastStatus = status
+				send(ProcessEvent{Kind: ProcessEventStatusChanged, JobID: *job.Id, Status: status})
+			}
+		}))
+
+	go func() {
+		defer close(events)
+
+		result, err := c.ProcessFile(ctx, jobType, filePath, opts...)
+		last := ProcessEvent{Kind: ProcessEventFinished, Time: time.Now(), Result: result, Err: err}
+		if err != nil {
+			last.Kind = ProcessEventFailed
+		}
+		var jobErr *IncompleteJobError
+		if result != nil && result.Job != nil && result.Job.Id != nil {
+			last.JobID = *result.Job.Id
+			last.Status = result.Job.CurrentStatus()
+		} else if errors.As(err, &jobErr) {
+			last.JobID = jobErr.JobID
+		}
+		events <- last
+	}()
+
+	return events
+}
+
+// WatchJobs follows several jobs at once and reports every change of status or claiming
+// worker on the event channel, like WaitForJobEvents does for a single job.
+//
+// Watching stops when every job is in a terminal state or ctx is done. Either way the
+// last observed state of each job is then sent on the snapshot channel and both channels
+// are closed, so a UI interrupted by cancellation can still show where each job stood.
+// Jobs that were never fetched successfully are missing from the snapshot. Failed status
+// requests are retried on the next round.
+func (c *BsubClient) WatchJobs(ctx context.Context, ids []JobId) (<-chan JobEvent, <-chan map[JobId]*Job) {
+	events := make(chan JobEvent)
+	snapshots := make(chan map[JobId]*Job, 1)
+
+	go func() {
+		defer close(snapshots)
+		defer close(events)
+
+		latest := make(map[JobId]*Job, len(ids))
+		defer func() { snapshots <- latest }()
+
+		last := make(map[JobId]JobEvent, len(ids))
+		for {
+			done := true
+			for _, id := range ids {
+				if job := latest[id]; job != nil && isTerminal(job.Status) {
+					continue
+				}
+
+				job, ok := c.fetchJob(ctx, id)
+				if ctx.Err() != nil {
+					return
+				}
+				if !ok {
+					done = false
+					continue
+				}
+				latest[id] = job
+				if !isTerminal(job.Status) {
+					done = false
+				}
+
+				if job.Status == nil {
+					continue
+				}
+				worker, _ := job.Worker()
+				if prev, seen := last[id]; seen && prev.Status == *job.Status && prev.Worker == worker {
+					continue
+				}
+
+				event := JobEvent{JobID: id, Status: *job.Status, Worker: worker, Job: job}
+				select {
+				case events <- event:
+					last[id] = event
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if done {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.pollInterval):
+			}
+		}
+	}()
+
+	return events, snapshots
+}
+
+// WaitForAll waits until every job in ids is in a terminal state and returns the final
+// jobs by ID. Jobs are polled in rounds that share one backoff, with at most a few
+// status requests in flight at once, so it scales to jobs submitted out-of-band in bulk.
+// A job whose status request fails with a retryable error, see IsRetryable, is polled
+// again in the next round; other errors, such as an unknown ID, end its wait.
+//
+// The error joins one entry per job that failed or could not be fetched, each prefixed
+// with the job ID; failed and cancelled jobs are in the map and their errors wrap
+// ErrJobFailed or ErrJobCancelled. When ctx is done the jobs seen so far are returned
+// with the context error.
+func (c *BsubClient) WaitForAll(ctx context.Context, ids []JobId) (map[JobId]*Job, error) {
+	defer c.stats.addJobWait(time.Now())
+
+	jobs := make(map[JobId]*Job, len(ids))
+	failures := make(map[JobId]error)
+
+	var unique []JobId
+	seen := make(map[JobId]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	backoff := newPollBackoff(WaitForJobOptions{}, c.pollInterval)
+	pending := append([]JobId(nil), unique...)
+	for len(pending) > 0 {
+		changed := false
+		var (
+			mu  sync.Mutex
+			wg  sync.WaitGroup
+			sem = make(chan struct{}, waitConcurrency)
+		)
+		for _, id := range pending {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(id JobId) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				job, err := c.getJob(ctx, id)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failures[id] = err
+					return
+				}
+				delete(failures, id)
+				if jobs[id].CurrentStatus() != job.CurrentStatus() {
+					changed = true
+				}
+				jobs[id] = job
+			}(id)
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return jobs, ctx.Err()
+		}
+
+		var next []JobId
+		for _, id := range pending {
+			if err, failed := failures[id]; failed && !IsRetryable(err) {
+				continue
+			}
+			if job := jobs[id]; job == nil || !isTerminal(job.Status) {
+				next = append(next, id)
+			}
+		}
+		pending = next
+		if len(pending) == 0 {
+			break
+		}
+
+		if changed {
+			backoff.reset()
+		}
+		select {
+		case <-ctx.Done():
+			return jobs, ctx.Err()
+		case <-time.After(backoff.next()):
+		}
+	}
+
+	var errs []error
+	for _, id := range unique {
+		err := failures[id]
+		if err == nil {
+			err = jobEndError(jobs[id])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", id, err))
+		}
+	}
+	return jobs, errors.Join(errs...)
+}
+
+// getJob gets the current state of a job
+func (c *BsubClient) getJob(ctx context.Context, jobID JobId) (*Job, error) {
+	resp, err := c.GetJobWithResponse(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, c.apiError("get job", resp.HTTPResponse, resp.Body)
+	}
+
+	if resp.JSON200 == nil || resp.JSON200.Data == nil {
+		return nil, fmt.Errorf("unexpected response format")
+	}
+
+	return resp.JSON200.Data, nil
+}
+
+// fetchJob gets the current state of a job, reporting false if that failed
+func (c *BsubClient) fetchJob(ctx context.Context, jobID JobId) (*Job, bool) {
+	job, err := c.getJob(ctx, jobID)
+	return job, err == nil
+}
+
+// Worker returns the ID of the worker that claimed the job,
+// and false if no worker has claimed it yet
+func (j *Job) Worker() (string, bool) {
+	if j.ClaimedBy == nil || *j.ClaimedBy == "" {
+		return "", false
+	}
+	return *j.ClaimedBy, true
+}
@@ -0,0 +1,356 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPollInterval keeps polling tests fast against the mock server
+const testPollInterval = 10 * time.Millisecond
+
+// TestWaitForJobEvents tests status transition reporting
+func TestWaitForJobEvents(t *testing.T) {
+	t.Run("reports each distinct status once", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/slow",
+			JobStatusClaimed, JobStatusClaimed, JobStatusPreparing,
+			JobStatusProcessing, JobStatusProcessing, JobStatusFinished,
+		)
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		events, errs := client.WaitForJobEvents(ctx, *job.Id)
+
+		var seen []JobStatus
+		for event := range events {
+			assert.Equal(t, *job.Id, event.JobID)
+			seen = append(seen, event.Status)
+		}
+		require.NoError(t, <-errs)
+
+		assert.Equal(t, []JobStatus{
+			JobStatusClaimed,
+			JobStatusPreparing,
+			JobStatusProcessing,
+			JobStatusFinished,
+		}, seen)
+	})
+
+	t.Run("context cancellation closes channels with error", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Context cancellation test only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		ctx := context.Background()
+		job, err := client.CreateAndSubmitJob(ctx, "test/never-finishes", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, testContextTimeout)
+		defer cancel()
+
+		events, errs := client.WaitForJobEvents(ctxWithTimeout, *job.Id)
+
+		var seen []JobStatus
+		for event := range events {
+			seen = append(seen, event.Status)
+		}
+		err = <-errs
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, []JobStatus{JobStatusPending}, seen)
+	})
+}
+
+// TestWaitForJobEvents_Worker tests that the claiming worker is reported
+func TestWaitForJobEvents_Worker(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusFinished)
+
+	ctx := context.Background()
+	job, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	worker := "worker-7"
+	mockServer.GetJob(*job.Id).ClaimedBy = &worker
+
+	events, errs := client.WaitForJobEvents(ctx, *job.Id)
+
+	var workers []string
+	for event := range events {
+		workers = append(workers, event.Worker)
+	}
+	require.NoError(t, <-errs)
+
+	assert.Equal(t, []string{"worker-7", "worker-7"}, workers)
+}
+
+// TestJobWorker tests the nil-safe ClaimedBy accessor
+func TestJobWorker(t *testing.T) {
+	var job Job
+	worker, ok := job.Worker()
+	assert.False(t, ok)
+	assert.Empty(t, worker)
+
+	empty := ""
+	job.ClaimedBy = &empty
+	_, ok = job.Worker()
+	assert.False(t, ok)
+
+	claimedBy := "worker-1"
+	job.ClaimedBy = &claimedBy
+	worker, ok = job.Worker()
+	assert.True(t, ok)
+	assert.Equal(t, "worker-1", worker)
+}
+
+// TestProcessFileEvents tests the event stream of processing a file
+func TestProcessFileEvents(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb"), 0644))
+
+	collect := func(events <-chan ProcessEvent) []ProcessEvent {
+		var all []ProcessEvent
+		for event := range events {
+			all = append(all, event)
+		}
+		return all
+	}
+
+	t.Run("finished", func(t *testing.T) {
+		mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusProcessing, JobStatusFinished)
+
+		events := collect(client.ProcessFileEvents(context.Background(), "test/slow", path))
+
+		var kinds []ProcessEventKind
+		var statuses []JobStatus
+		for i, event := range events {
+			kinds = append(kinds, event.Kind)
+			if event.Kind == ProcessEventStatusChanged {
+				statuses = append(statuses, event.Status)
+			}
+			assert.NotEqual(t, uuid.Nil, event.JobID)
+			assert.Equal(t, events[0].JobID, event.JobID)
+			if i > 0 {
+				assert.False(t, event.Time.Before(events[i-1].Time))
+			}
+		}
+		assert.Equal(t, []ProcessEventKind{ProcessEventUploading, ProcessEventUploaded, ProcessEventSubmitted}, kinds[:3])
+		assert.Equal(t, []JobStatus{JobStatusClaimed, JobStatusProcessing, JobStatusFinished}, statuses)
+
+		last := events[len(events)-1]
+		assert.Equal(t, ProcessEventFinished, last.Kind)
+		assert.Equal(t, JobStatusFinished, last.Status)
+		require.NoError(t, last.Err)
+		assert.Equal(t, "mock output", last.Result.OutputString())
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		mockServer.SetJobOutcome("test/broken", JobStatusFailed, ErrorCodeInvalidInput, "bad input")
+
+		events := collect(client.ProcessFileEvents(context.Background(), "test/broken", path))
+
+		last := events[len(events)-1]
+		assert.Equal(t, ProcessEventFailed, last.Kind)
+		assert.Equal(t, JobStatusFailed, last.Status)
+		assert.ErrorIs(t, last.Err, ErrJobFailed)
+		require.NotNil(t, last.Result)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		events := collect(client.ProcessFileEvents(context.Background(), "test/linecount", filepath.Join(t.TempDir(), "missing.txt")))
+
+		require.Len(t, events, 1)
+		assert.Equal(t, ProcessEventFailed, events[0].Kind)
+		assert.Error(t, events[0].Err)
+		assert.Equal(t, uuid.Nil, events[0].JobID)
+	})
+}
+
+// TestWatchJobs tests following several jobs and the final snapshot
+func TestWatchJobs(t *testing.T) {
+	t.Run("stops when all jobs are terminal", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/slow", JobStatusProcessing, JobStatusFinished)
+		mockServer.SetProgression("test/broken", JobStatusFailed)
+
+		ctx := context.Background()
+		slow, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		broken, err := client.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		events, snapshots := client.WatchJobs(ctx, []JobId{*slow.Id, *broken.Id})
+
+		seen := make(map[JobId][]JobStatus)
+		for event := range events {
+			seen[event.JobID] = append(seen[event.JobID], event.Status)
+		}
+		assert.Equal(t, []JobStatus{JobStatusProcessing, JobStatusFinished}, seen[*slow.Id])
+		assert.Equal(t, []JobStatus{JobStatusFailed}, seen[*broken.Id])
+
+		snapshot := <-snapshots
+		require.Len(t, snapshot, 2)
+		assert.Equal(t, JobStatusFinished, snapshot[*slow.Id].CurrentStatus())
+		assert.Equal(t, JobStatusFailed, snapshot[*broken.Id].CurrentStatus())
+	})
+
+	t.Run("cancellation sends the last observed state", func(t *testing.T) {
+		client, mockServer, cleanup := SetupTestClient(t)
+		defer cleanup()
+
+		if mockServer == nil {
+			t.Skip("Scripted progression only supported in mock mode")
+		}
+		client.pollInterval = testPollInterval
+
+		mockServer.SetProgression("test/stuck", JobStatusClaimed, JobStatusProcessing)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		finished, err := client.CreateAndSubmitJob(ctx, "test/linecount", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		stuck, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		events, snapshots := client.WatchJobs(ctx, []JobId{*finished.Id, *stuck.Id})
+		for event := range events {
+			if event.JobID == *stuck.Id && event.Status == JobStatusProcessing {
+				cancel()
+			}
+		}
+
+		snapshot := <-snapshots
+		require.Len(t, snapshot, 2)
+		assert.Equal(t, JobStatusFinished, snapshot[*finished.Id].CurrentStatus())
+		assert.Equal(t, JobStatusProcessing, snapshot[*stuck.Id].CurrentStatus())
+
+		_, open := <-snapshots
+		assert.False(t, open)
+	})
+}
+
+// TestWaitForAll tests joining on several jobs, including failed and unknown ones
+func TestWaitForAll(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Scripted progression only supported in mock mode")
+	}
+	client.pollInterval = testPollInterval
+
+	mockServer.SetProgression("test/slow", JobStatusClaimed, JobStatusProcessing, JobStatusFinished)
+	mockServer.SetProgression("test/broken", JobStatusProcessing, JobStatusFailed)
+
+	ctx := context.Background()
+	slow, err := client.CreateAndSubmitJob(ctx, "test/slow", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+	broken, err := client.CreateAndSubmitJob(ctx, "test/broken", bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+
+	t.Run("all succeed", func(t *testing.T) {
+		jobs, err := client.WaitForAll(ctx, []JobId{*slow.Id, *slow.Id})
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, JobStatusFinished, jobs[*slow.Id].CurrentStatus())
+	})
+
+	t.Run("combined error", func(t *testing.T) {
+		unknown := uuid.New()
+		jobs, err := client.WaitForAll(ctx, []JobId{*slow.Id, *broken.Id, unknown})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrJobFailed)
+		assert.Contains(t, err.Error(), "job "+broken.Id.String()+": job failed")
+		assert.Contains(t, err.Error(), "job "+unknown.String()+": failed to get job: status 404")
+		assert.NotContains(t, err.Error(), slow.Id.String())
+
+		require.Len(t, jobs, 2)
+		assert.Equal(t, JobStatusFinished, jobs[*slow.Id].CurrentStatus())
+		assert.Equal(t, JobStatusFailed, jobs[*broken.Id].CurrentStatus())
+	})
+
+	t.Run("transient errors are polled again", func(t *testing.T) {
+		// The first status request of the job is answered with a 503
+		var unavailable atomic.Bool
+		flaky, err := NewBsubClient(Config{
+			APIKey:  "test-api-key",
+			BaseURL: mockServer.URL,
+			ResponseInterceptors: []ResponseInterceptor{func(resp *http.Response) error {
+				if operationName(resp.Request) == "GetJob" && unavailable.CompareAndSwap(false, true) {
+					resp.StatusCode = http.StatusServiceUnavailable
+					resp.Body = io.NopCloser(strings.NewReader(`{"error":"unavailable"}`))
+				}
+				return nil
+			}},
+		})
+		require.NoError(t, err)
+		flaky.pollInterval = testPollInterval
+
+		jobs, err := flaky.WaitForAll(ctx, []JobId{*slow.Id})
+		require.NoError(t, err)
+		assert.True(t, unavailable.Load())
+		assert.Equal(t, JobStatusFinished, jobs[*slow.Id].CurrentStatus())
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		mockServer.SetProgression("test/stuck", JobStatusProcessing)
+		stuck, err := client.CreateAndSubmitJob(ctx, "test/stuck", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		jobs, err := client.WaitForAll(timeoutCtx, []JobId{*slow.Id, *stuck.Id})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, JobStatusProcessing, jobs[*stuck.Id].CurrentStatus())
+	})
+}
@@ -0,0 +1,58 @@
+package bsubio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxWebhookSize bounds the webhook payloads ParseWebhook reads
+const maxWebhookSize = 1 << 20
+
+// ParseWebhook decodes the job the server posted to the callback URL of a job created
+// with CreateAndSubmitJobWithCallback or WithCallbackURL. It reads the request body but
+// leaves the response to the caller, which should answer 2xx once the job is handled.
+//
+// Anyone who knows the callback URL can post to it, so confirm the job with GetJob
+// before acting on its status, or embed a secret in the URL and check it.
+func ParseWebhook(r *http.Request) (*Job, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("%w: unexpected method %s", ErrInvalidWebhook, r.Method)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook: %w", err)
+	}
+	if len(body) > maxWebhookSize {
+		return nil, fmt.Errorf("%w: payload exceeds %d bytes", ErrInvalidWebhook, maxWebhookSize)
+	}
+
+	return ParseWebhookPayload(body)
+}
+
+// ParseWebhookPayload is ParseWebhook for a payload that was already read, e.g. by a
+// framework other than net/http. It accepts the job wrapped in the API's response
+// envelope, {"data": {...}}, as well as the bare job.
+func ParseWebhookPayload(body []byte) (*Job, error) {
+	var envelope struct {
+		Data *Job `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+
+	job := envelope.Data
+	if job == nil {
+		job = new(Job)
+		if err := json.Unmarshal(body, job); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+		}
+	}
+	if job.Id == nil {
+		return nil, fmt.Errorf("%w: no job ID", ErrInvalidWebhook)
+	}
+
+	return job, nil
+}
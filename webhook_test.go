@@ -0,0 +1,96 @@
+package bsubio
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateAndSubmitJobWithCallback tests that the server notifies the callback URL
+// when the job ends, without the client polling
+func TestCreateAndSubmitJobWithCallback(t *testing.T) {
+	client, mockServer, cleanup := SetupTestClient(t)
+	defer cleanup()
+
+	if mockServer == nil {
+		t.Skip("Callbacks only supported in mock mode")
+	}
+
+	notified := make(chan *Job, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		job, err := ParseWebhook(r)
+		if !assert.NoError(t, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		notified <- job
+	}))
+	defer receiver.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+	defer cancel()
+
+	job, err := client.CreateAndSubmitJobWithCallback(ctx, "test/linecount", bytes.NewReader([]byte("a\nb")), receiver.URL+"/hooks/bsub")
+	require.NoError(t, err)
+	assert.Equal(t, receiver.URL+"/hooks/bsub", mockServer.CreateRequest(*job.Id)["callback_url"])
+
+	select {
+	case finished := <-notified:
+		assert.Equal(t, *job.Id, *finished.Id)
+		assert.Equal(t, JobStatusFinished, *finished.Status)
+	case <-ctx.Done():
+		t.Fatal("callback URL was not notified")
+	}
+
+	t.Run("invalid callback URL", func(t *testing.T) {
+		for _, callbackURL := range []string{"", "/hooks/bsub", "ftp://example.com/hook", "https://"} {
+			_, err := client.CreateAndSubmitJobWithCallback(ctx, "test/linecount", bytes.NewReader([]byte("a")), callbackURL)
+			assert.ErrorIs(t, err, ErrInvalidCallbackURL, callbackURL)
+		}
+
+		_, err := client.CreateJob(ctx, "test/linecount", WithCallbackURL("not a url"))
+		assert.ErrorIs(t, err, ErrInvalidCallbackURL)
+	})
+}
+
+// TestParseWebhook tests decoding webhook requests into jobs
+func TestParseWebhook(t *testing.T) {
+	id := uuid.New()
+	post := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/hooks/bsub", strings.NewReader(body))
+	}
+
+	t.Run("envelope", func(t *testing.T) {
+		job, err := ParseWebhook(post(`{"data":{"id":"` + id.String() + `","status":"failed","error_code":"timeout"},"success":true}`))
+		require.NoError(t, err)
+		assert.Equal(t, id, *job.Id)
+		assert.Equal(t, JobStatusFailed, *job.Status)
+		assert.Equal(t, "timeout", *job.ErrorCode)
+	})
+
+	t.Run("bare job", func(t *testing.T) {
+		job, err := ParseWebhook(post(`{"id":"` + id.String() + `","status":"finished","finished_at":"` + time.Now().Format(time.RFC3339) + `"}`))
+		require.NoError(t, err)
+		assert.Equal(t, id, *job.Id)
+		assert.Equal(t, JobStatusFinished, *job.Status)
+		assert.NotNil(t, job.FinishedAt)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{"", "not json", `{"data":{"status":"finished"}}`, `{"status":"finished"}`, "{" + strings.Repeat(" ", maxWebhookSize) + "}"} {
+			_, err := ParseWebhook(post(body))
+			assert.ErrorIs(t, err, ErrInvalidWebhook)
+		}
+
+		_, err := ParseWebhook(httptest.NewRequest(http.MethodGet, "/hooks/bsub", nil))
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+}